@@ -2,19 +2,37 @@ package main
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
+	"starter/config"
 	"starter/internal/adapters/api/http/handler"
+	"starter/internal/adapters/api/http/middleware"
 	"starter/internal/adapters/api/http/route"
 	"starter/internal/adapters/auth"
 	"starter/internal/adapters/database"
+	"starter/internal/adapters/memory"
+	"starter/internal/adapters/metadata"
+	"starter/internal/adapters/metrics"
 	"starter/internal/adapters/storage"
+	"starter/internal/adapters/tracing"
 	"starter/internal/adapters/validator"
+	"starter/internal/core/audit"
 	"starter/internal/core/auth"
 	"starter/internal/core/author"
 	"starter/internal/core/book"
 	"starter/internal/core/category"
+	"starter/internal/core/health"
+	"starter/internal/core/idempotency"
+	"starter/internal/core/merge"
 	"starter/internal/core/publisher"
+	"starter/internal/core/ratelimit"
+	"starter/internal/core/reading"
+	"starter/internal/core/recommendation"
+	"starter/internal/core/review"
+	"starter/internal/core/shelf"
 	istorage "starter/internal/core/storage"
+	"starter/internal/core/tag"
+	"starter/internal/core/trash"
 	"starter/internal/core/user"
 	"starter/pkg/hasher"
 )
@@ -23,136 +41,337 @@ type App struct {
 	Handlers   Handlers
 	Usecase    Usecase
 	Repository Repository
+	Store      Store
+	Middleware Middleware
 	Route      Route
 }
 
 func (app *App) Bootstrap(fiber *fiber.App, db *gorm.DB) {
 	app.Repository = *app.NewRepositories()
+	app.Store = *app.NewStores(db)
 	app.Usecase = *app.NewUsecases(db)
 	app.Handlers = *app.NewHandlers(app.Usecase)
+	app.Middleware = *app.NewMiddleware()
 	app.Route = *app.NewRoutes(fiber)
 }
 
 type Handlers struct {
-	AuthHandler      handler.AuthHandler
-	UserHandler      handler.UserHandler
-	AuthorHandler    handler.AuthorHandler
-	CategoryHandler  handler.CategoryHandler
-	BookHandler      handler.BookHandler
-	PublisherHandler handler.PublisherHandler
-	StorageHandler   handler.StorageHandler
+	AuthHandler           handler.AuthHandler
+	UserHandler           handler.UserHandler
+	AuthorHandler         handler.AuthorHandler
+	CategoryHandler       handler.CategoryHandler
+	BookHandler           handler.BookHandler
+	PublisherHandler      handler.PublisherHandler
+	StorageHandler        handler.StorageHandler
+	HealthHandler         handler.HealthHandler
+	AuditHandler          handler.AuditHandler
+	TrashHandler          handler.TrashHandler
+	ReviewHandler         handler.ReviewHandler
+	ShelfHandler          handler.ShelfHandler
+	ReadingHandler        handler.ReadingHandler
+	RecommendationHandler handler.RecommendationHandler
+	TagHandler            handler.TagHandler
 }
 
 func (app *App) NewHandlers(usecase Usecase) *Handlers {
 	return &Handlers{
-		AuthHandler:      *handler.NewAuthHandler(usecase.AuthUsecase),
-		UserHandler:      *handler.NewUserHandler(usecase.UserUsecase),
-		AuthorHandler:    *handler.NewAuthorHandler(usecase.AuthorUsecase),
-		CategoryHandler:  *handler.NewCategoryHandler(usecase.CategoryUsecase),
-		PublisherHandler: *handler.NewPublisherHandler(usecase.PublisherUsecase),
-		BookHandler:      *handler.NewBookHandler(usecase.BookUsecase),
-		StorageHandler:   *handler.NewStorageHandler(usecase.Storage),
+		AuthHandler:           *handler.NewAuthHandler(usecase.AuthUsecase),
+		UserHandler:           *handler.NewUserHandler(usecase.UserUsecase),
+		AuthorHandler:         *handler.NewAuthorHandler(usecase.AuthorUsecase),
+		CategoryHandler:       *handler.NewCategoryHandler(usecase.CategoryUsecase),
+		PublisherHandler:      *handler.NewPublisherHandler(usecase.PublisherUsecase),
+		BookHandler:           *handler.NewBookHandler(usecase.BookUsecase),
+		StorageHandler:        *handler.NewStorageHandler(usecase.Storage),
+		HealthHandler:         *handler.NewHealthHandler(usecase.HealthUsecase),
+		AuditHandler:          *handler.NewAuditHandler(usecase.AuditUsecase),
+		TrashHandler:          *handler.NewTrashHandler(usecase.TrashUsecase),
+		ReviewHandler:         *handler.NewReviewHandler(usecase.ReviewUsecase),
+		ShelfHandler:          *handler.NewShelfHandler(usecase.ShelfUsecase),
+		ReadingHandler:        *handler.NewReadingHandler(usecase.ReadingUsecase),
+		RecommendationHandler: *handler.NewRecommendationHandler(usecase.RecommendationUsecase),
+		TagHandler:            *handler.NewTagHandler(usecase.TagUsecase),
 	}
 }
 
 type Usecase struct {
-	UserUsecase      user.Usecase
-	AuthUsecase      auth.Usecase
-	AuthorUsecase    author.Usecase
-	CategoryUsecase  category.Usecase
-	PublisherUsecase publisher.Usecase
-	BookUsecase      book.Usecase
-	Storage          istorage.Storage
+	UserUsecase           user.Usecase
+	AuthUsecase           auth.Usecase
+	AuthorUsecase         author.Usecase
+	CategoryUsecase       category.Usecase
+	PublisherUsecase      publisher.Usecase
+	BookUsecase           book.Usecase
+	HealthUsecase         health.Usecase
+	AuditUsecase          audit.Usecase
+	TrashUsecase          trash.Usecase
+	ReviewUsecase         review.Usecase
+	ShelfUsecase          shelf.Usecase
+	ReadingUsecase        reading.Usecase
+	RecommendationUsecase recommendation.Usecase
+	TagUsecase            tag.Usecase
+	Storage               istorage.Storage
 }
 
 func (app *App) NewUsecases(db *gorm.DB) *Usecase {
 	hasher := hasher.NewBcryptHasher()
 	validator := validator.NewValidator()
-	storage := storage.NewStorage()
+	storage := metrics.NewStorage(tracing.NewStorage(storage.NewStorage()))
+	recorder := metrics.NewRecorder()
 	tokenGenerator := jwt.NewTokenGenerator()
+	metadataProvider := memory.NewMetadataCache(
+		metadata.NewOpenLibrary(config.AppConfig.MetadataBaseURL, config.AppConfig.MetadataTimeout),
+		config.AppConfig.MetadataCacheTTL,
+		config.AppConfig.MetadataCacheSize,
+	)
+	auditUsecase := audit.NewUsecase(audit.UsecaseDependency{
+		DB:              db,
+		Validator:       validator,
+		AuditRepository: app.Repository.AuditRepository,
+	})
 
 	userDependency := user.UsecaseDependency{
 		DB:             db,
 		Hasher:         hasher,
 		Validator:      validator,
+		Audit:          auditUsecase,
 		UserRepository: app.Repository.UserRepository,
 	}
 	authDependency := auth.UsecaseDependency{
 		DB:             db,
 		Hasher:         hasher,
 		Validator:      validator,
+		Audit:          auditUsecase,
 		UserRepository: app.Repository.UserRepository,
 		TokenGenerator: tokenGenerator,
+		Metrics:        recorder,
+		RateLimitStore: app.Store.RateLimitStore,
+		Backoff: ratelimit.Backoff{
+			Threshold: config.AppConfig.LockoutThreshold,
+			Base:      config.AppConfig.LockoutBase,
+			Max:       config.AppConfig.LockoutMax,
+			Forget:    config.AppConfig.LockoutForget,
+		},
 	}
 	authorDependency := author.UsecaseDependency{
-		DB:               db,
-		Validator:        validator,
-		AuthorRepository: app.Repository.AuthorRepository,
+		DB:                 db,
+		Validator:          validator,
+		Audit:              auditUsecase,
+		AuthorRepository:   app.Repository.AuthorRepository,
+		RedirectRepository: app.Repository.RedirectRepository,
+		BookRule:           config.AppConfig.AuthorBookRule,
 	}
 	categoryDependency := category.UsecaseDependency{
 		DB:                 db,
 		Validator:          validator,
+		Audit:              auditUsecase,
 		CategoryRepository: app.Repository.CategoryRepository,
+		BookRule:           config.AppConfig.CategoryBookRule,
 	}
 	bookDependency := book.UsecaseDependency{
-		DB:             db,
-		Validator:      validator,
-		Storage:        storage,
-		BookRepository: app.Repository.BookRepository,
+		DB:                  db,
+		Validator:           validator,
+		Audit:               auditUsecase,
+		Storage:             storage,
+		Metrics:             recorder,
+		Metadata:            metadataProvider,
+		BookRepository:      app.Repository.BookRepository,
+		AuthorRepository:    app.Repository.AuthorRepository,
+		PublisherRepository: app.Repository.PublisherRepository,
 	}
 	publisherDependency := publisher.UsecaseDependency{
 		DB:                  db,
 		Validator:           validator,
+		Audit:               auditUsecase,
 		PublisherRepository: app.Repository.PublisherRepository,
+		RedirectRepository:  app.Repository.RedirectRepository,
+		BookRule:            config.AppConfig.PublisherBookRule,
+	}
+	trashDependency := trash.UsecaseDependency{
+		DB:              db,
+		Validator:       validator,
+		Audit:           auditUsecase,
+		TrashRepository: app.Repository.TrashRepository,
+	}
+	reviewDependency := review.UsecaseDependency{
+		DB:               db,
+		Validator:        validator,
+		Audit:            auditUsecase,
+		ReviewRepository: app.Repository.ReviewRepository,
+		InitialStatus:    config.AppConfig.ReviewInitialStatus,
+	}
+	shelfDependency := shelf.UsecaseDependency{
+		DB:              db,
+		Validator:       validator,
+		Storage:         storage,
+		Audit:           auditUsecase,
+		ShelfRepository: app.Repository.ShelfRepository,
+	}
+	readingDependency := reading.UsecaseDependency{
+		DB:                db,
+		Validator:         validator,
+		Audit:             auditUsecase,
+		ReadingRepository: app.Repository.ReadingRepository,
+	}
+	recommendationDependency := recommendation.UsecaseDependency{
+		DB:                       db,
+		Validator:                validator,
+		Storage:                  storage,
+		Interval:                 config.AppConfig.RecommendationInterval,
+		Neighbours:               config.AppConfig.RecommendationNeighbours,
+		Weights:                  recommendation.DefaultWeights,
+		RecommendationRepository: app.Repository.RecommendationRepository,
+	}
+	tagDependency := tag.UsecaseDependency{
+		DB:            db,
+		Validator:     validator,
+		Audit:         auditUsecase,
+		TagRepository: app.Repository.TagRepository,
+	}
+	healthDependency := health.UsecaseDependency{
+		Checks: map[string]health.Checker{
+			"postgres": database.NewPostgresChecker(db),
+			"storage":  health.CheckerFunc(storage.Ping),
+		},
+		Timeout: config.AppConfig.HealthTimeout,
 	}
 
 	return &Usecase{
-		UserUsecase:      user.NewUsecase(userDependency),
-		AuthUsecase:      auth.NewUsecase(authDependency),
-		AuthorUsecase:    author.NewUsecase(authorDependency),
-		CategoryUsecase:  category.NewUsecase(categoryDependency),
-		PublisherUsecase: publisher.NewUsecase(publisherDependency),
-		BookUsecase:      book.NewUsecase(bookDependency),
-		Storage:          storage,
+		UserUsecase:           user.NewUsecase(userDependency),
+		AuthUsecase:           auth.NewUsecase(authDependency),
+		AuthorUsecase:         author.NewUsecase(authorDependency),
+		CategoryUsecase:       category.NewUsecase(categoryDependency),
+		PublisherUsecase:      publisher.NewUsecase(publisherDependency),
+		BookUsecase:           book.NewUsecase(bookDependency),
+		HealthUsecase:         health.NewUsecase(healthDependency),
+		AuditUsecase:          auditUsecase,
+		TrashUsecase:          trash.NewUsecase(trashDependency),
+		ReviewUsecase:         review.NewUsecase(reviewDependency),
+		ShelfUsecase:          shelf.NewUsecase(shelfDependency),
+		ReadingUsecase:        reading.NewUsecase(readingDependency),
+		RecommendationUsecase: recommendation.NewUsecase(recommendationDependency),
+		TagUsecase:            tag.NewUsecase(tagDependency),
+		Storage:               storage,
 	}
 }
 
 type Repository struct {
-	UserRepository      user.Repository
-	AuthorRepository    author.Repository
-	CategoryRepository  category.Repository
-	PublisherRepository publisher.Repository
-	BookRepository      book.Repository
+	UserRepository           user.Repository
+	AuthorRepository         author.Repository
+	CategoryRepository       category.Repository
+	PublisherRepository      publisher.Repository
+	BookRepository           book.Repository
+	AuditRepository          audit.Repository
+	TrashRepository          trash.Repository
+	RedirectRepository       merge.RedirectRepository
+	ReviewRepository         review.Repository
+	ShelfRepository          shelf.Repository
+	ReadingRepository        reading.Repository
+	RecommendationRepository recommendation.Repository
+	TagRepository            tag.Repository
 }
 
 func (app *App) NewRepositories() *Repository {
 	return &Repository{
-		UserRepository:      database.NewUserRepository(),
-		AuthorRepository:    database.NewAuthorRepository(),
-		CategoryRepository:  database.NewCategoryRepository(),
-		PublisherRepository: database.NewPublisherRepository(),
-		BookRepository:      database.NewBookRepository(),
+		UserRepository:           database.NewUserRepository(),
+		AuthorRepository:         database.NewAuthorRepository(),
+		CategoryRepository:       database.NewCategoryRepository(),
+		PublisherRepository:      database.NewPublisherRepository(),
+		BookRepository:           database.NewBookRepository(),
+		AuditRepository:          database.NewAuditRepository(),
+		TrashRepository:          database.NewTrashRepository(),
+		RedirectRepository:       database.NewRedirectRepository(),
+		ReviewRepository:         database.NewReviewRepository(),
+		ShelfRepository:          database.NewShelfRepository(),
+		ReadingRepository:        database.NewReadingRepository(),
+		RecommendationRepository: database.NewRecommendationRepository(),
+		TagRepository:            database.NewTagRepository(),
+	}
+}
+
+type Store struct {
+	IdempotencyStore idempotency.Store
+	RateLimitStore   ratelimit.Store
+}
+
+func (app *App) NewStores(db *gorm.DB) *Store {
+	idempotencyStore := database.NewIdempotencyStore(db)
+	if config.AppConfig.IdempotencyStore == "memory" {
+		idempotencyStore = memory.NewIdempotencyStore()
+	}
+
+	rateLimitStore := database.NewRateLimitStore(db)
+	if config.AppConfig.RateLimitStore == "memory" {
+		rateLimitStore = memory.NewRateLimitStore()
+	}
+
+	return &Store{
+		IdempotencyStore: idempotencyStore,
+		RateLimitStore:   rateLimitStore,
+	}
+}
+
+type Middleware struct {
+	Idempotency   fiber.Handler
+	AuthRateLimit []fiber.Handler
+}
+
+func (app *App) NewMiddleware() *Middleware {
+	authIPLimit, err := ratelimit.ParseLimit(config.AppConfig.AuthIPRateLimit)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid RATE_LIMIT_AUTH_IP")
+	}
+	authAccountLimit, err := ratelimit.ParseLimit(config.AppConfig.AuthAccountRateLimit)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid RATE_LIMIT_AUTH_ACCOUNT")
+	}
+
+	return &Middleware{
+		Idempotency: middleware.IdempotencyMiddleware(app.Store.IdempotencyStore, config.AppConfig.IdempotencyTTL),
+		AuthRateLimit: []fiber.Handler{
+			middleware.RateLimitMiddleware(app.Store.RateLimitStore, "auth:ip", authIPLimit, middleware.KeyByIP),
+			middleware.RateLimitMiddleware(app.Store.RateLimitStore, "auth:account", authAccountLimit, middleware.KeyByBodyField("email")),
+		},
 	}
 }
 
 type Route struct {
-	UserRoute      route.UserRoutes
-	AuthRoute      route.AuthRoutes
-	AuthorRoute    route.AuthorRoutes
-	CategoryRoute  route.CategoryRoutes
-	PublisherRoute route.PublisherRoutes
-	BookRoute      route.BookRoutes
-	StorageRoute   route.StorageRoutes
+	UserRoute           route.UserRoutes
+	AuthRoute           route.AuthRoutes
+	AuthorRoute         route.AuthorRoutes
+	CategoryRoute       route.CategoryRoutes
+	PublisherRoute      route.PublisherRoutes
+	BookRoute           route.BookRoutes
+	StorageRoute        route.StorageRoutes
+	HealthRoute         route.HealthRoutes
+	MetricsRoute        route.MetricsRoutes
+	AuditRoute          route.AuditRoutes
+	TrashRoute          route.TrashRoutes
+	ReviewRoute         route.ReviewRoutes
+	ShelfRoute          route.ShelfRoutes
+	ReadingRoute        route.ReadingRoutes
+	RecommendationRoute route.RecommendationRoutes
+	TagRoute            route.TagRoutes
 }
 
 func (app *App) NewRoutes(fiber *fiber.App) *Route {
-	userRoute := *route.NewUserRoutes(&app.Handlers.UserHandler)
-	authRoute := *route.NewAuthRoutes(&app.Handlers.AuthHandler)
-	authorRoute := *route.NewAuthorRoutes(&app.Handlers.AuthorHandler)
-	categoryRoute := *route.NewCategoryRoutes(&app.Handlers.CategoryHandler)
-	publisherRoute := *route.NewPublisherRoutes(&app.Handlers.PublisherHandler)
-	bookRoute := *route.NewBookRoutes(&app.Handlers.BookHandler)
+	userRoute := *route.NewUserRoutes(&app.Handlers.UserHandler, app.Middleware.Idempotency)
+	authRoute := *route.NewAuthRoutes(&app.Handlers.AuthHandler, app.Middleware.Idempotency, app.Middleware.AuthRateLimit...)
+	authorRoute := *route.NewAuthorRoutes(&app.Handlers.AuthorHandler, app.Middleware.Idempotency)
+	categoryRoute := *route.NewCategoryRoutes(&app.Handlers.CategoryHandler, app.Middleware.Idempotency)
+	publisherRoute := *route.NewPublisherRoutes(&app.Handlers.PublisherHandler, app.Middleware.Idempotency)
+	bookRoute := *route.NewBookRoutes(&app.Handlers.BookHandler, app.Middleware.Idempotency)
 	storageRoute := *route.NewStorageRoutes(&app.Handlers.StorageHandler)
+	healthRoute := *route.NewHealthRoutes(&app.Handlers.HealthHandler)
+	metricsRoute := *route.NewMetricsRoutes()
+	auditRoute := *route.NewAuditRoutes(&app.Handlers.AuditHandler)
+	trashRoute := *route.NewTrashRoutes(&app.Handlers.TrashHandler)
+	reviewRoute := *route.NewReviewRoutes(&app.Handlers.ReviewHandler, app.Middleware.Idempotency)
+	shelfRoute := *route.NewShelfRoutes(&app.Handlers.ShelfHandler, app.Middleware.Idempotency)
+	readingRoute := *route.NewReadingRoutes(&app.Handlers.ReadingHandler, app.Middleware.Idempotency)
+	recommendationRoute := *route.NewRecommendationRoutes(&app.Handlers.RecommendationHandler)
+	tagRoute := *route.NewTagRoutes(&app.Handlers.TagHandler, app.Middleware.Idempotency)
+
+	healthRoute.InstallRoutes(fiber)
+	metricsRoute.InstallRoutes(fiber)
 
 	router := fiber.Group("/api/v1")
 	userRoute.InstallRoutes(router)
@@ -162,13 +381,30 @@ func (app *App) NewRoutes(fiber *fiber.App) *Route {
 	publisherRoute.InstallRoutes(router)
 	bookRoute.InstallRoutes(router)
 	storageRoute.InstallRoutes(router)
+	auditRoute.InstallRoutes(router)
+	trashRoute.InstallRoutes(router)
+	reviewRoute.InstallRoutes(router)
+	shelfRoute.InstallRoutes(router)
+	readingRoute.InstallRoutes(router)
+	recommendationRoute.InstallRoutes(router)
+	tagRoute.InstallRoutes(router)
 
 	return &Route{
-		UserRoute:      userRoute,
-		AuthRoute:      authRoute,
-		AuthorRoute:    authorRoute,
-		CategoryRoute:  categoryRoute,
-		PublisherRoute: publisherRoute,
-		BookRoute:      bookRoute,
+		UserRoute:           userRoute,
+		AuthRoute:           authRoute,
+		AuthorRoute:         authorRoute,
+		CategoryRoute:       categoryRoute,
+		PublisherRoute:      publisherRoute,
+		BookRoute:           bookRoute,
+		StorageRoute:        storageRoute,
+		HealthRoute:         healthRoute,
+		MetricsRoute:        metricsRoute,
+		AuditRoute:          auditRoute,
+		TrashRoute:          trashRoute,
+		ReviewRoute:         reviewRoute,
+		ShelfRoute:          shelfRoute,
+		ReadingRoute:        readingRoute,
+		RecommendationRoute: recommendationRoute,
+		TagRoute:            tagRoute,
 	}
 }
@@ -1,26 +1,27 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"os"
+	"os/signal"
 	"starter/config"
 	"starter/internal/adapters/api/http/middleware"
 	"starter/internal/adapters/database"
+	"starter/internal/adapters/tracing"
+	"starter/pkg/logger"
+	"syscall"
 	"time"
 )
 
 func main() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).
-		Level(zerolog.TraceLevel).
-		With().
-		Timestamp().
-		Caller().
-		Logger()
+	zerolog.DefaultContextLogger = &log.Logger
+	log.Logger, _ = logger.New("info", "console")
 
 	err := config.LoadConfig("../")
 	if err != nil {
@@ -29,6 +30,21 @@ func main() {
 			Msg("failed to load config file")
 	}
 
+	appLogger, err := logger.New(config.AppConfig.LogLevel, config.AppConfig.LogFormat)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("invalid log configuration")
+	}
+	log.Logger = appLogger
+
+	shutdownTracing, err := tracing.NewTracerProvider(context.Background())
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("failed to set up tracing")
+	}
+
 	db, err := database.NewPostgresConn()
 	if err != nil {
 		log.Fatal().
@@ -41,13 +57,21 @@ func main() {
 	router := fiber.New()
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     "http://localhost:5173",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
-		AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, If-Match, If-None-Match, Idempotency-Key, Traceparent, Tracestate, X-Request-ID",
+		ExposeHeaders:    "X-Request-ID, ETag, Idempotent-Replayed, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset",
+		AllowMethods:     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
 		AllowCredentials: true,
 	}))
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.TracingMiddleware())
 	router.Use(middleware.ZerologMiddleware())
+	router.Use(middleware.MetricsMiddleware())
+	router.Use(middleware.LocaleMiddleware())
 	app.Bootstrap(router, db)
 
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	go app.Usecase.RecommendationUsecase.Run(jobCtx)
+
 	log.Info().Msgf("Starting server on :%s", config.AppConfig.AppPort)
 	for _, route := range router.GetRoutes(true) {
 		log.Info().
@@ -55,7 +79,44 @@ func main() {
 			Str("path", route.Path).
 			Msg("Registered Route")
 	}
-	if err := router.Listen(fmt.Sprintf(":%s", config.AppConfig.AppPort)); err != nil {
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- router.Listen(fmt.Sprintf(":%s", config.AppConfig.AppPort))
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErr:
 		log.Fatal().Err(err).Msg("Failed to start server")
+	case sig := <-quit:
+		log.Info().Str("signal", sig.String()).Msg("Shutting down server")
+	}
+
+	// Readiness fails from here on, the listener keeps serving for the drain
+	// delay so that load balancers notice before connections are refused.
+	app.Usecase.HealthUsecase.Drain()
+	stopJobs()
+	time.Sleep(config.AppConfig.ShutdownDrainDelay)
+	if err := router.ShutdownWithTimeout(config.AppConfig.ShutdownTimeout); err != nil {
+		log.Error().Err(err).Msg("Failed to drain server")
+	}
+
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = sqlDB.Close()
 	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to close database connection")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.AppConfig.ShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to flush traces")
+	}
+
+	log.Info().Msg("Server stopped")
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
+	"time"
 )
 
 var AppConfig *Config
@@ -27,6 +28,43 @@ type Config struct {
 
 	Timezone  string `mapstructure:"TIMEZONE"`
 	JWTSecret string `mapstructure:"JWT_SECRET"`
+
+	IdempotencyStore string        `mapstructure:"IDEMPOTENCY_STORE"`
+	IdempotencyTTL   time.Duration `mapstructure:"IDEMPOTENCY_TTL"`
+
+	RateLimitStore       string        `mapstructure:"RATE_LIMIT_STORE"`
+	AuthIPRateLimit      string        `mapstructure:"RATE_LIMIT_AUTH_IP"`
+	AuthAccountRateLimit string        `mapstructure:"RATE_LIMIT_AUTH_ACCOUNT"`
+	LockoutThreshold     int           `mapstructure:"LOCKOUT_THRESHOLD"`
+	LockoutBase          time.Duration `mapstructure:"LOCKOUT_BASE"`
+	LockoutMax           time.Duration `mapstructure:"LOCKOUT_MAX"`
+	LockoutForget        time.Duration `mapstructure:"LOCKOUT_FORGET"`
+
+	ShutdownTimeout    time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	ShutdownDrainDelay time.Duration `mapstructure:"SHUTDOWN_DRAIN_DELAY"`
+	HealthTimeout      time.Duration `mapstructure:"HEALTH_TIMEOUT"`
+
+	TracingExporter    string  `mapstructure:"TRACING_EXPORTER"`
+	TracingFile        string  `mapstructure:"TRACING_FILE"`
+	TracingServiceName string  `mapstructure:"TRACING_SERVICE_NAME"`
+	TracingSampleRatio float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+
+	LogLevel  string `mapstructure:"LOG_LEVEL"`
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+
+	AuthorBookRule    string `mapstructure:"DELETE_RULE_AUTHOR_BOOKS"`
+	PublisherBookRule string `mapstructure:"DELETE_RULE_PUBLISHER_BOOKS"`
+	CategoryBookRule  string `mapstructure:"DELETE_RULE_CATEGORY_BOOKS"`
+
+	ReviewInitialStatus string `mapstructure:"REVIEW_INITIAL_STATUS"`
+
+	RecommendationInterval   time.Duration `mapstructure:"RECOMMENDATION_INTERVAL"`
+	RecommendationNeighbours int           `mapstructure:"RECOMMENDATION_NEIGHBOURS"`
+
+	MetadataBaseURL   string        `mapstructure:"METADATA_BASE_URL"`
+	MetadataTimeout   time.Duration `mapstructure:"METADATA_TIMEOUT"`
+	MetadataCacheTTL  time.Duration `mapstructure:"METADATA_CACHE_TTL"`
+	MetadataCacheSize int           `mapstructure:"METADATA_CACHE_SIZE"`
 }
 
 func LoadConfig(path string) (err error) {
@@ -36,6 +74,34 @@ func LoadConfig(path string) (err error) {
 	viper.SetConfigType("env")
 
 	viper.AutomaticEnv()
+	viper.SetDefault("IDEMPOTENCY_STORE", "postgres")
+	viper.SetDefault("IDEMPOTENCY_TTL", "24h")
+	viper.SetDefault("RATE_LIMIT_STORE", "postgres")
+	viper.SetDefault("RATE_LIMIT_AUTH_IP", "20/1m")
+	viper.SetDefault("RATE_LIMIT_AUTH_ACCOUNT", "5/1m")
+	viper.SetDefault("LOCKOUT_THRESHOLD", 5)
+	viper.SetDefault("LOCKOUT_BASE", "1m")
+	viper.SetDefault("LOCKOUT_MAX", "1h")
+	viper.SetDefault("LOCKOUT_FORGET", "24h")
+	viper.SetDefault("SHUTDOWN_TIMEOUT", "15s")
+	viper.SetDefault("SHUTDOWN_DRAIN_DELAY", "5s")
+	viper.SetDefault("HEALTH_TIMEOUT", "2s")
+	viper.SetDefault("TRACING_EXPORTER", "none")
+	viper.SetDefault("TRACING_FILE", "traces.json")
+	viper.SetDefault("TRACING_SERVICE_NAME", "books-backend")
+	viper.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
+	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("LOG_FORMAT", "console")
+	viper.SetDefault("DELETE_RULE_AUTHOR_BOOKS", "restrict")
+	viper.SetDefault("DELETE_RULE_PUBLISHER_BOOKS", "restrict")
+	viper.SetDefault("DELETE_RULE_CATEGORY_BOOKS", "restrict")
+	viper.SetDefault("REVIEW_INITIAL_STATUS", "pending")
+	viper.SetDefault("RECOMMENDATION_INTERVAL", "6h")
+	viper.SetDefault("RECOMMENDATION_NEIGHBOURS", 20)
+	viper.SetDefault("METADATA_BASE_URL", "https://openlibrary.org")
+	viper.SetDefault("METADATA_TIMEOUT", "5s")
+	viper.SetDefault("METADATA_CACHE_TTL", "24h")
+	viper.SetDefault("METADATA_CACHE_SIZE", 10000)
 
 	err = viper.ReadInConfig()
 	if err != nil {
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ETag renders a record version as a strong entity tag.
+func ETag(version uint) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// RepresentationETag tags a rendered response that holds more than the
+// versioned row, such as counters updated outside the version or parts that
+// depend on the caller or the locale. The version leads so that ParseIfMatch
+// still reads it, the digest of body tells the representations apart.
+func RepresentationETag(version uint, body any) string {
+	data, _ := json.Marshal(body)
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(`"%d-%s"`, version, hex.EncodeToString(sum[:8]))
+}
+
+// MatchesETag reports whether an If-None-Match style header lists etag.
+func MatchesETag(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseIfMatch turns an If-Match header into the version the client expects
+// to overwrite, from an ETag or a RepresentationETag. An absent header or "*"
+// yields 0, meaning no precondition. ok is false when the header can't be
+// matched against any version.
+func ParseIfMatch(header string) (version uint, ok bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	tag := strings.TrimSpace(strings.Split(header, ",")[0])
+	if strings.HasPrefix(tag, "W/") {
+		return 0, false
+	}
+
+	number, _, _ := strings.Cut(strings.Trim(tag, `"`), "-")
+	parsed, err := strconv.ParseUint(number, 10, 64)
+	if err != nil || parsed == 0 {
+		return 0, false
+	}
+	return uint(parsed), true
+}
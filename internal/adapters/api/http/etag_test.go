@@ -0,0 +1,64 @@
+package http
+
+import "testing"
+
+func TestMatchesETag(t *testing.T) {
+	tests := []struct {
+		header string
+		etag   string
+		want   bool
+	}{
+		{`"3"`, `"3"`, true},
+		{`"1", "3"`, `"3"`, true},
+		{`W/"3"`, `"3"`, true},
+		{`*`, `"3"`, true},
+		{`"4"`, `"3"`, false},
+		{``, `"3"`, false},
+		{`"3-ab"`, `"3-cd"`, false},
+	}
+
+	for _, test := range tests {
+		if got := MatchesETag(test.header, test.etag); got != test.want {
+			t.Errorf("MatchesETag(%q, %q) = %v, want %v", test.header, test.etag, got, test.want)
+		}
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header      string
+		wantVersion uint
+		wantOK      bool
+	}{
+		{``, 0, true},
+		{`*`, 0, true},
+		{`"7"`, 7, true},
+		{` "7" `, 7, true},
+		{`"7", "8"`, 7, true},
+		{`"7-0123456789abcdef"`, 7, true},
+		{`W/"7"`, 0, false},
+		{`"0"`, 0, false},
+		{`"seven"`, 0, false},
+		{`"-1"`, 0, false},
+	}
+
+	for _, test := range tests {
+		version, ok := ParseIfMatch(test.header)
+		if version != test.wantVersion || ok != test.wantOK {
+			t.Errorf("ParseIfMatch(%q) = %d, %v, want %d, %v", test.header, version, ok, test.wantVersion, test.wantOK)
+		}
+	}
+}
+
+func TestETagRoundTrip(t *testing.T) {
+	for _, etag := range []string{ETag(12), RepresentationETag(12, map[string]any{"title": "Refactoring"})} {
+		version, ok := ParseIfMatch(etag)
+		if !ok || version != 12 {
+			t.Errorf("ParseIfMatch(%s) = %d, %v, want 12, true", etag, version, ok)
+		}
+	}
+
+	if RepresentationETag(12, "a") == RepresentationETag(12, "b") {
+		t.Error("RepresentationETag() is the same for different bodies")
+	}
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"starter/internal/adapters/api/http"
+	"starter/internal/core/audit"
+	"starter/internal/core/filter"
+	ivalidator "starter/internal/core/validator"
+)
+
+type AuditHandler struct {
+	AuditUsecase audit.Usecase
+}
+
+func NewAuditHandler(auditUsecase audit.Usecase) *AuditHandler {
+	return &AuditHandler{
+		AuditUsecase: auditUsecase,
+	}
+}
+
+func (handler *AuditHandler) List(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	request := newPaginationRequest(ctx)
+	filter := filter.AuditFilter{
+		ActorID:    uint(ctx.QueryInt("actor_id")),
+		Action:     ctx.Query("action"),
+		EntityType: ctx.Query("entity_type"),
+		EntityID:   uint(ctx.QueryInt("entity_id")),
+		RequestID:  ctx.Query("request_id"),
+		StartDate:  ctx.Query("start_date"),
+		EndDate:    ctx.Query("end_date"),
+	}
+
+	response, err := handler.AuditUsecase.FindAll(ctx.UserContext(), &request, &filter)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch audit entries")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch audit entries")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to fetch audit entries"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Audit entries fetched successfully"),
+	)
+}
@@ -23,10 +23,11 @@ func NewAuthHandler(authUsecase auth.Usecase) *AuthHandler {
 
 func (handler *AuthHandler) Login(ctx *fiber.Ctx) error {
 	var validationError ivalidator.ValidationErrors
+	var lockedError auth.LockedError
 
 	request := new(auth.LoginRequest)
 	if err := ctx.BodyParser(&request); err != nil {
-		log.Error().Err(err).Msg("failed to parse request body")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
 		return ctx.Status(fiber.StatusBadRequest).JSON(
 			http.ErrorResponse("Invalid request body"),
 		)
@@ -34,14 +35,23 @@ func (handler *AuthHandler) Login(ctx *fiber.Ctx) error {
 
 	response, err := handler.AuthUsecase.Login(ctx.UserContext(), *request)
 	if errors.As(err, &validationError) {
-		log.Error().Err(err).Msg("failed to login")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to login")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ValidationResponse(validationError),
 		)
 	}
 
+	if errors.As(err, &lockedError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to login")
+		retryAfter := http.Seconds(lockedError.RetryAfter)
+		ctx.Set(fiber.HeaderRetryAfter, retryAfter)
+		return ctx.Status(fiber.StatusTooManyRequests).JSON(
+			http.ErrorResponse("Too many failed login attempts, try again in " + retryAfter + " seconds"),
+		)
+	}
+
 	if err != nil {
-		log.Error().Err(err).Msg("failed to login")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to login")
 		return ctx.Status(fiber.StatusUnauthorized).JSON(
 			http.ErrorResponse("Invalid email or password"),
 		)
@@ -57,7 +67,7 @@ func (handler *AuthHandler) Register(ctx *fiber.Ctx) error {
 
 	request := new(auth.RegisterRequest)
 	if err := ctx.BodyParser(&request); err != nil {
-		log.Error().Err(err).Msg("failed to parse request body")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
 		return ctx.Status(fiber.StatusBadRequest).JSON(
 			http.ErrorResponse("Invalid request body"),
 		)
@@ -65,14 +75,14 @@ func (handler *AuthHandler) Register(ctx *fiber.Ctx) error {
 
 	response, err := handler.AuthUsecase.Register(ctx.UserContext(), *request)
 	if errors.As(err, &validationError) {
-		log.Error().Err(err).Msg("failed to create user")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create user")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ValidationResponse(validationError),
 		)
 	}
 
 	if err != nil {
-		log.Error().Err(err).Msg("failed to register user")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to register user")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to register user"),
 		)
@@ -86,7 +96,7 @@ func (handler *AuthHandler) Register(ctx *fiber.Ctx) error {
 func (handler *AuthHandler) Current(ctx *fiber.Ctx) error {
 	userToken, ok := ctx.Locals("user").(*jwt.Token)
 	if !ok {
-		log.Error().Msg("failed to get current user")
+		log.Ctx(ctx.UserContext()).Error().Msg("failed to get current user")
 		return ctx.Status(fiber.StatusUnauthorized).JSON(
 			http.ErrorResponse("Invalid or expired token"),
 		)
@@ -94,13 +104,13 @@ func (handler *AuthHandler) Current(ctx *fiber.Ctx) error {
 
 	claim, ok := userToken.Claims.(jwt.MapClaims)
 	if !ok {
-		log.Error().Msg("failed to parse token")
+		log.Ctx(ctx.UserContext()).Error().Msg("failed to parse token")
 		return ctx.Status(fiber.StatusUnauthorized).JSON(
 			http.ErrorResponse("Invalid or expired token"),
 		)
 	}
 
-	authCtx := context.WithValue(ctx.Context(), "user", auth.AuthenticatedUser{
+	authCtx := context.WithValue(ctx.UserContext(), "user", auth.AuthenticatedUser{
 		Id:   uint(claim["user_id"].(float64)),
 		Role: claim["role"].(string),
 	})
@@ -108,7 +118,7 @@ func (handler *AuthHandler) Current(ctx *fiber.Ctx) error {
 
 	response, err := handler.AuthUsecase.Current(ctx.UserContext())
 	if err != nil {
-		log.Error().Err(err).Msg("failed to login")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to login")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Invalid email or password"),
 		)
@@ -6,7 +6,10 @@ import (
 	"github.com/rs/zerolog/log"
 	"starter/internal/adapters/api/http"
 	"starter/internal/core/author"
-	"starter/internal/core/pagination"
+	"starter/internal/core/concurrency"
+	"starter/internal/core/integrity"
+	"starter/internal/core/merge"
+	"starter/internal/core/patch"
 	ivalidator "starter/internal/core/validator"
 )
 
@@ -25,7 +28,7 @@ func (handler *AuthorHandler) Create(ctx *fiber.Ctx) error {
 
 	request := new(author.CreateRequest)
 	if err := ctx.BodyParser(&request); err != nil {
-		log.Error().Err(err).Msg("failed to parse request body")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
 		return ctx.Status(fiber.StatusBadRequest).JSON(
 			http.ErrorResponse("Invalid request body"),
 		)
@@ -33,14 +36,14 @@ func (handler *AuthorHandler) Create(ctx *fiber.Ctx) error {
 
 	response, err := handler.AuthorUsecase.Save(ctx.UserContext(), *request)
 	if errors.As(err, &validationError) {
-		log.Error().Err(err).Msg("failed to create author")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create author")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ValidationResponse(validationError),
 		)
 	}
 
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create author")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create author")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create author"),
 		)
@@ -57,39 +60,145 @@ func (handler *AuthorHandler) Update(ctx *fiber.Ctx) error {
 	id, _ := ctx.ParamsInt("id")
 	request := new(author.UpdateRequest)
 	if err := ctx.BodyParser(&request); err != nil {
-		log.Error().Err(err).Msg("failed to parse request body")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
 		return ctx.Status(fiber.StatusBadRequest).JSON(
 			http.ErrorResponse("Invalid request body"),
 		)
 	}
 	request.Id = id
 
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any author version"),
+		)
+	}
+	request.Version = version
+
 	response, err := handler.AuthorUsecase.Update(ctx.UserContext(), *request)
 	if errors.As(err, &validationError) {
-		log.Error().Err(err).Msg("failed to create author")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create author")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ValidationResponse(validationError),
 		)
 	}
 
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to update author")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Author was modified by another request"),
+		)
+	}
+
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create author")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create author")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create author"),
 		)
 	}
 
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Author updated successfully"),
+	)
+}
+
+func (handler *AuthorHandler) Patch(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	id, _ := ctx.ParamsInt("id")
+	if !http.IsMergePatch(ctx.Get(fiber.HeaderContentType)) {
+		return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(
+			http.ErrorResponse("Content-Type must be " + http.MergePatchContentType),
+		)
+	}
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any author version"),
+		)
+	}
+
+	response, err := handler.AuthorUsecase.Patch(ctx.UserContext(), patch.Request{
+		Id:      id,
+		Version: version,
+		Patch:   ctx.Body(),
+	})
+	if errors.Is(err, patch.ErrInvalidPatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch author")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid merge patch"),
+		)
+	}
+
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch author")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch author")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Author was modified by another request"),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch author")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to patch author"),
+		)
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
 	return ctx.Status(fiber.StatusOK).JSON(
 		http.SuccessResponse(response, "Author updated successfully"),
 	)
 }
 
 func (handler *AuthorHandler) Delete(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+	var restricted integrity.RestrictedError
+
 	id, _ := ctx.ParamsInt("id")
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any author version"),
+		)
+	}
+
+	request := integrity.DeleteRequest{
+		Books:      ctx.Query("books"),
+		ReassignTo: uint(ctx.QueryInt("reassign_to")),
+	}
+
+	err := handler.AuthorUsecase.Delete(ctx.UserContext(), id, version, request)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to delete author")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Author was modified by another request"),
+		)
+	}
+
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to delete author")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+	if errors.As(err, &restricted) {
+		return ctx.Status(fiber.StatusConflict).JSON(
+			http.ErrorDataResponse("Author is still referenced by active books", fiber.Map{
+				"books": restricted.Count,
+			}),
+		)
+	}
 
-	err := handler.AuthorUsecase.Delete(ctx.UserContext(), id)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create author")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create author")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create author"),
 		)
@@ -101,43 +210,125 @@ func (handler *AuthorHandler) Delete(ctx *fiber.Ctx) error {
 }
 
 func (handler *AuthorHandler) List(ctx *fiber.Ctx) error {
-	sortBy := ctx.Query("sort_by")
-	orderBy := ctx.Query("order_by")
-	page := ctx.QueryInt("page")
-	limit := ctx.QueryInt("limit")
+	var validationError ivalidator.ValidationErrors
+
+	request := newPaginationRequest(ctx)
+	view := newProjectionRequest(ctx)
 
-	request := pagination.Request{
-		SortBy:  sortBy,
-		OrderBy: orderBy,
-		Page:    page,
-		Limit:   limit,
+	response, err := handler.AuthorUsecase.FindAll(ctx.UserContext(), &request, &view)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch authors")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
 	}
 
-	response, err := handler.AuthorUsecase.FindAll(ctx.UserContext(), &request)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create author")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create author")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to fetch authors"),
 		)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(
-		http.SuccessResponse(response, "Authors fetched successfully"),
+		http.SuccessResponse(http.ProjectPage(response, view), "Authors fetched successfully"),
 	)
 }
 
 func (handler *AuthorHandler) GetByID(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+	var moved merge.MovedError
+
 	id, _ := ctx.ParamsInt("id")
+	view := newProjectionRequest(ctx)
+
+	response, err := handler.AuthorUsecase.FindById(ctx.UserContext(), id, &view)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch author")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if errors.As(err, &moved) {
+		return movedPermanently(ctx, moved.ID)
+	}
 
-	response, err := handler.AuthorUsecase.FindById(ctx.UserContext(), id)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create author")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create author")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create author"),
 		)
 	}
 
+	etag := http.ETag(response.Version)
+	ctx.Set(fiber.HeaderETag, etag)
+	if http.MatchesETag(ctx.Get(fiber.HeaderIfNoneMatch), etag) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(http.Project(response, view), "Author fetched successfully"),
+	)
+}
+
+func (handler *AuthorHandler) Merge(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	id, _ := ctx.ParamsInt("id")
+	request := new(merge.Request)
+	if err := ctx.BodyParser(request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+	request.TargetID = id
+
+	response, err := handler.AuthorUsecase.Merge(ctx.UserContext(), *request)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to merge authors")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to merge authors")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to merge authors"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Authors merged successfully"),
+	)
+}
+
+func (handler *AuthorHandler) Duplicates(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	request := merge.DuplicatesRequest{
+		Threshold: ctx.QueryFloat("threshold"),
+		Limit:     ctx.QueryInt("limit"),
+	}
+
+	response, err := handler.AuthorUsecase.Duplicates(ctx.UserContext(), &request)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to find duplicate authors")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to find duplicate authors")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to find duplicate authors"),
+		)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(
-		http.SuccessResponse(response, "Author fetched successfully"),
+		http.SuccessResponse(response, "Duplicate authors fetched successfully"),
 	)
 }
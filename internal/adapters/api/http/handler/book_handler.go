@@ -6,8 +6,9 @@ import (
 	"github.com/rs/zerolog/log"
 	"starter/internal/adapters/api/http"
 	"starter/internal/core/book"
+	"starter/internal/core/concurrency"
 	"starter/internal/core/filter"
-	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
 	ivalidator "starter/internal/core/validator"
 	"starter/pkg/helper"
 )
@@ -27,7 +28,7 @@ func (handler *BookHandler) Create(ctx *fiber.Ctx) error {
 
 	request := new(book.CreateRequest)
 	if err := ctx.BodyParser(&request); err != nil {
-		log.Error().Err(err).Msg("failed to parse request body")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
 		return ctx.Status(fiber.StatusBadRequest).JSON(
 			http.ErrorResponse("Invalid request body"),
 		)
@@ -35,14 +36,14 @@ func (handler *BookHandler) Create(ctx *fiber.Ctx) error {
 
 	response, err := handler.BookUsecase.Save(ctx.UserContext(), *request)
 	if errors.As(err, &validationError) {
-		log.Error().Err(err).Msg("failed to create book")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create book")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ValidationResponse(validationError),
 		)
 	}
 
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create book")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create book")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create book"),
 		)
@@ -59,28 +60,99 @@ func (handler *BookHandler) Update(ctx *fiber.Ctx) error {
 	id, _ := ctx.ParamsInt("id")
 	request := new(book.UpdateRequest)
 	if err := ctx.BodyParser(&request); err != nil {
-		log.Error().Err(err).Msg("failed to parse request body")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
 		return ctx.Status(fiber.StatusBadRequest).JSON(
 			http.ErrorResponse("Invalid request body"),
 		)
 	}
 	request.Id = id
 
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any book version"),
+		)
+	}
+	request.Version = version
+
 	response, err := handler.BookUsecase.Update(ctx.UserContext(), *request)
 	if errors.As(err, &validationError) {
-		log.Error().Err(err).Msg("failed to create book")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create book")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ValidationResponse(validationError),
 		)
 	}
 
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to update book")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Book was modified by another request"),
+		)
+	}
+
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create book")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create book")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create book"),
 		)
 	}
 
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Book updated successfully"),
+	)
+}
+
+func (handler *BookHandler) Patch(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	id, _ := ctx.ParamsInt("id")
+	if !http.IsMergePatch(ctx.Get(fiber.HeaderContentType)) {
+		return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(
+			http.ErrorResponse("Content-Type must be " + http.MergePatchContentType),
+		)
+	}
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any book version"),
+		)
+	}
+
+	response, err := handler.BookUsecase.Patch(ctx.UserContext(), patch.Request{
+		Id:      id,
+		Version: version,
+		Patch:   ctx.Body(),
+	})
+	if errors.Is(err, patch.ErrInvalidPatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch book")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid merge patch"),
+		)
+	}
+
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch book")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch book")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Book was modified by another request"),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch book")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to patch book"),
+		)
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
 	return ctx.Status(fiber.StatusOK).JSON(
 		http.SuccessResponse(response, "Book updated successfully"),
 	)
@@ -88,10 +160,23 @@ func (handler *BookHandler) Update(ctx *fiber.Ctx) error {
 
 func (handler *BookHandler) Delete(ctx *fiber.Ctx) error {
 	id, _ := ctx.ParamsInt("id")
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any book version"),
+		)
+	}
+
+	err := handler.BookUsecase.Delete(ctx.UserContext(), id, version)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to delete book")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Book was modified by another request"),
+		)
+	}
 
-	err := handler.BookUsecase.Delete(ctx.UserContext(), id)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create book")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create book")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create book"),
 		)
@@ -104,16 +189,12 @@ func (handler *BookHandler) Delete(ctx *fiber.Ctx) error {
 
 func (handler *BookHandler) List(ctx *fiber.Ctx) error {
 	var validationError ivalidator.ValidationErrors
-	
+
 	ctg := ctx.Query("categories")
 	categories := helper.ParseUintSlice(ctg)
 
-	request := pagination.Request{
-		SortBy:  ctx.Query("sort_by"),
-		OrderBy: ctx.Query("order_by"),
-		Page:    ctx.QueryInt("page"),
-		Limit:   ctx.QueryInt("limit"),
-	}
+	request := newPaginationRequest(ctx)
+	view := newProjectionRequest(ctx)
 
 	filter := filter.BookFilter{
 		Default: filter.Default{
@@ -121,43 +202,248 @@ func (handler *BookHandler) List(ctx *fiber.Ctx) error {
 			StartDate: ctx.Query("start_date"),
 			EndDate:   ctx.Query("end_date"),
 		},
-		Categories: categories,
-		From:       ctx.Query("from"),
-		To:         ctx.Query("to"),
+		Categories:  categories,
+		Descendants: ctx.QueryBool("descendants", true),
+		Tags:        helper.ParseStringSlice(ctx.Query("tags")),
+		Subjects:    helper.ParseStringSlice(ctx.Query("subjects")),
+		TagMatch:    ctx.Query("tag_match"),
+		Language:    ctx.Query("language"),
+		From:        ctx.Query("from"),
+		To:          ctx.Query("to"),
 	}
 
-	response, err := handler.BookUsecase.FindAll(ctx.UserContext(), &request, &filter)
+	response, err := handler.BookUsecase.FindAll(ctx.UserContext(), &request, &filter, &view)
 	if errors.As(err, &validationError) {
-		log.Error().Err(err).Msg("failed to create book")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create book")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ValidationResponse(validationError),
 		)
 	}
 
 	if err != nil {
-		log.Error().Err(err).Msg("failed to fetch book")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch book")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to fetch categories"),
 		)
 	}
 
+	ctx.Vary(fiber.HeaderAcceptLanguage)
 	return ctx.Status(fiber.StatusOK).JSON(
-		http.SuccessResponse(response, "Books fetched successfully"),
+		http.SuccessResponse(http.ProjectPage(response, view), "Books fetched successfully"),
 	)
 }
 
 func (handler *BookHandler) GetByID(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
 	id, _ := ctx.ParamsInt("id")
+	view := newProjectionRequest(ctx)
+
+	response, err := handler.BookUsecase.FindById(ctx.UserContext(), id, &view)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch book")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
 
-	response, err := handler.BookUsecase.FindById(ctx.UserContext(), id)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create book")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create book")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create book"),
 		)
 	}
 
+	// The title and description served depend on Accept-Language, the
+	// shelves on the caller.
+	ctx.Vary(fiber.HeaderAcceptLanguage, fiber.HeaderAuthorization)
+	if response.Locale != "" {
+		ctx.Set(fiber.HeaderContentLanguage, response.Locale)
+	}
+
+	// The ratings change without a new version, so the tag covers what is
+	// rendered.
+	body := http.Project(response, view)
+	etag := http.RepresentationETag(response.Version, body)
+	ctx.Set(fiber.HeaderETag, etag)
+	if http.MatchesETag(ctx.Get(fiber.HeaderIfNoneMatch), etag) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(body, "Book fetched successfully"),
+	)
+}
+
+func (handler *BookHandler) Revisions(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	id, _ := ctx.ParamsInt("id")
+	request := newPaginationRequest(ctx)
+
+	response, err := handler.BookUsecase.FindRevisions(ctx.UserContext(), id, &request)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch book revisions")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch book revisions")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to fetch book revisions"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Book revisions fetched successfully"),
+	)
+}
+
+func (handler *BookHandler) Revision(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+	number, _ := ctx.ParamsInt("number")
+
+	response, err := handler.BookUsecase.FindRevision(ctx.UserContext(), id, number)
+	if errors.Is(err, book.ErrRevisionNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Book revision not found"),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch book revision")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to fetch book revision"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Book revision fetched successfully"),
+	)
+}
+
+func (handler *BookHandler) DiffRevisions(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	id, _ := ctx.ParamsInt("id")
+
+	response, err := handler.BookUsecase.DiffRevisions(ctx.UserContext(), id, ctx.QueryInt("from"), ctx.QueryInt("to"))
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to diff book revisions")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if errors.Is(err, book.ErrRevisionNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Book revision not found"),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to diff book revisions")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to diff book revisions"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Book revisions compared successfully"),
+	)
+}
+
+func (handler *BookHandler) Revert(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	id, _ := ctx.ParamsInt("id")
+	number, _ := ctx.ParamsInt("number")
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any book version"),
+		)
+	}
+
+	response, err := handler.BookUsecase.Revert(ctx.UserContext(), book.RevertRequest{
+		Id:      id,
+		Number:  number,
+		Version: version,
+	})
+	if errors.Is(err, book.ErrRevisionNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Book revision not found"),
+		)
+	}
+
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to revert book")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to revert book")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Book was modified by another request"),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to revert book")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to revert book"),
+		)
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Book reverted successfully"),
+	)
+}
+
+func (handler *BookHandler) Lookup(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	request := new(book.LookupRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+
+	response, err := handler.BookUsecase.Lookup(ctx.UserContext(), *request)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to look up book")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if errors.Is(err, book.ErrMetadataNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("No book found for the ISBN"),
+		)
+	}
+
+	if errors.Is(err, book.ErrMetadataUnavailable) {
+		return ctx.Status(fiber.StatusBadGateway).JSON(
+			http.ErrorResponse("Book metadata source is unavailable"),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to look up book")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to look up book"),
+		)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(
-		http.SuccessResponse(response, "Book fetched successfully"),
+		http.SuccessResponse(response, "Book looked up successfully"),
 	)
 }
@@ -6,7 +6,9 @@ import (
 	"github.com/rs/zerolog/log"
 	"starter/internal/adapters/api/http"
 	"starter/internal/core/category"
-	"starter/internal/core/pagination"
+	"starter/internal/core/concurrency"
+	"starter/internal/core/integrity"
+	"starter/internal/core/patch"
 	ivalidator "starter/internal/core/validator"
 )
 
@@ -25,7 +27,7 @@ func (handler *CategoryHandler) Create(ctx *fiber.Ctx) error {
 
 	request := new(category.CreateRequest)
 	if err := ctx.BodyParser(&request); err != nil {
-		log.Error().Err(err).Msg("failed to parse request body")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
 		return ctx.Status(fiber.StatusBadRequest).JSON(
 			http.ErrorResponse("Invalid request body"),
 		)
@@ -33,14 +35,14 @@ func (handler *CategoryHandler) Create(ctx *fiber.Ctx) error {
 
 	response, err := handler.CategoryUsecase.Save(ctx.UserContext(), *request)
 	if errors.As(err, &validationError) {
-		log.Error().Err(err).Msg("failed to create category")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create category")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ValidationResponse(validationError),
 		)
 	}
 
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create category")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create category")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create category"),
 		)
@@ -57,39 +59,145 @@ func (handler *CategoryHandler) Update(ctx *fiber.Ctx) error {
 	id, _ := ctx.ParamsInt("id")
 	request := new(category.UpdateRequest)
 	if err := ctx.BodyParser(&request); err != nil {
-		log.Error().Err(err).Msg("failed to parse request body")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
 		return ctx.Status(fiber.StatusBadRequest).JSON(
 			http.ErrorResponse("Invalid request body"),
 		)
 	}
 	request.Id = id
 
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any category version"),
+		)
+	}
+	request.Version = version
+
 	response, err := handler.CategoryUsecase.Update(ctx.UserContext(), *request)
 	if errors.As(err, &validationError) {
-		log.Error().Err(err).Msg("failed to create category")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create category")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ValidationResponse(validationError),
 		)
 	}
 
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to update category")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Category was modified by another request"),
+		)
+	}
+
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create category")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create category")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create category"),
 		)
 	}
 
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Category updated successfully"),
+	)
+}
+
+func (handler *CategoryHandler) Patch(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	id, _ := ctx.ParamsInt("id")
+	if !http.IsMergePatch(ctx.Get(fiber.HeaderContentType)) {
+		return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(
+			http.ErrorResponse("Content-Type must be " + http.MergePatchContentType),
+		)
+	}
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any category version"),
+		)
+	}
+
+	response, err := handler.CategoryUsecase.Patch(ctx.UserContext(), patch.Request{
+		Id:      id,
+		Version: version,
+		Patch:   ctx.Body(),
+	})
+	if errors.Is(err, patch.ErrInvalidPatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch category")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid merge patch"),
+		)
+	}
+
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch category")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch category")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Category was modified by another request"),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch category")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to patch category"),
+		)
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
 	return ctx.Status(fiber.StatusOK).JSON(
 		http.SuccessResponse(response, "Category updated successfully"),
 	)
 }
 
 func (handler *CategoryHandler) Delete(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+	var restricted integrity.RestrictedError
+
 	id, _ := ctx.ParamsInt("id")
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any category version"),
+		)
+	}
+
+	request := integrity.DeleteRequest{
+		Books:      ctx.Query("books"),
+		ReassignTo: uint(ctx.QueryInt("reassign_to")),
+	}
+
+	err := handler.CategoryUsecase.Delete(ctx.UserContext(), id, version, request)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to delete category")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Category was modified by another request"),
+		)
+	}
+
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to delete category")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+	if errors.As(err, &restricted) {
+		return ctx.Status(fiber.StatusConflict).JSON(
+			http.ErrorDataResponse("Category is still referenced by active "+restricted.Relation, fiber.Map{
+				restricted.Relation: restricted.Count,
+			}),
+		)
+	}
 
-	err := handler.CategoryUsecase.Delete(ctx.UserContext(), id)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create category")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create category")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create category"),
 		)
@@ -101,43 +209,136 @@ func (handler *CategoryHandler) Delete(ctx *fiber.Ctx) error {
 }
 
 func (handler *CategoryHandler) List(ctx *fiber.Ctx) error {
-	sortBy := ctx.Query("sort_by")
-	orderBy := ctx.Query("order_by")
-	page := ctx.QueryInt("page")
-	limit := ctx.QueryInt("limit")
+	var validationError ivalidator.ValidationErrors
 
-	request := pagination.Request{
-		SortBy:  sortBy,
-		OrderBy: orderBy,
-		Page:    page,
-		Limit:   limit,
+	request := newPaginationRequest(ctx)
+	view := newProjectionRequest(ctx)
+
+	response, err := handler.CategoryUsecase.FindAll(ctx.UserContext(), &request, &view)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch categories")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
 	}
 
-	response, err := handler.CategoryUsecase.FindAll(ctx.UserContext(), &request)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create category")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create category")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to fetch categories"),
 		)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(
-		http.SuccessResponse(response, "Categories fetched successfully"),
+		http.SuccessResponse(http.ProjectPage(response, view), "Categories fetched successfully"),
 	)
 }
 
 func (handler *CategoryHandler) GetByID(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
 	id, _ := ctx.ParamsInt("id")
+	view := newProjectionRequest(ctx)
+
+	response, err := handler.CategoryUsecase.FindById(ctx.UserContext(), id, &view)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch category")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
 
-	response, err := handler.CategoryUsecase.FindById(ctx.UserContext(), id)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create category")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create category")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create category"),
 		)
 	}
 
+	etag := http.ETag(response.Version)
+	ctx.Set(fiber.HeaderETag, etag)
+	if http.MatchesETag(ctx.Get(fiber.HeaderIfNoneMatch), etag) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(http.Project(response, view), "Category fetched successfully"),
+	)
+}
+
+func (handler *CategoryHandler) Move(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	id, _ := ctx.ParamsInt("id")
+	request := new(category.MoveRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+	request.Id = id
+
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any category version"),
+		)
+	}
+	request.Version = version
+
+	response, err := handler.CategoryUsecase.Move(ctx.UserContext(), *request)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to move category")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to move category")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Category was modified by another request"),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to move category")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to move category"),
+		)
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Category moved successfully"),
+	)
+}
+
+func (handler *CategoryHandler) Tree(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	request := category.TreeRequest{
+		Root:  uint(ctx.QueryInt("root")),
+		Depth: ctx.QueryInt("depth"),
+	}
+
+	response, err := handler.CategoryUsecase.FindTree(ctx.UserContext(), request)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch category tree")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch category tree")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to fetch category tree"),
+		)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(
-		http.SuccessResponse(response, "Category fetched successfully"),
+		http.SuccessResponse(response, "Category tree fetched successfully"),
 	)
 }
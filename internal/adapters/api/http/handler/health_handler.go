@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"starter/internal/adapters/api/http"
+	"starter/internal/core/health"
+)
+
+type HealthHandler struct {
+	HealthUsecase health.Usecase
+}
+
+func NewHealthHandler(healthUsecase health.Usecase) *HealthHandler {
+	return &HealthHandler{
+		HealthUsecase: healthUsecase,
+	}
+}
+
+func (handler *HealthHandler) Liveness(ctx *fiber.Ctx) error {
+	response := handler.HealthUsecase.Liveness(ctx.UserContext())
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Alive"),
+	)
+}
+
+func (handler *HealthHandler) Readiness(ctx *fiber.Ctx) error {
+	response, ready := handler.HealthUsecase.Readiness(ctx.UserContext())
+	if !ready {
+		return ctx.Status(fiber.StatusServiceUnavailable).JSON(
+			http.WebResponse[health.Response]{
+				Success: false,
+				Message: "Not ready",
+				Data:    response,
+			},
+		)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Ready"),
+	)
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"starter/internal/core/pagination"
+)
+
+// newPaginationRequest reads the paging query parameters shared by every list
+// endpoint. The total count is on by default for page based requests only,
+// cursor clients have to ask for it with with_total=true.
+func newPaginationRequest(ctx *fiber.Ctx) pagination.Request {
+	request := pagination.Request{
+		Sort:    ctx.Query("sort"),
+		SortBy:  ctx.Query("sort_by"),
+		OrderBy: ctx.Query("order_by"),
+		Page:    ctx.QueryInt("page"),
+		Limit:   ctx.QueryInt("limit"),
+		After:   ctx.Query("after"),
+		Before:  ctx.Query("before"),
+	}
+	request.WithTotal = ctx.QueryBool("with_total", !request.IsCursor())
+
+	return request
+}
@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"starter/internal/core/projection"
+)
+
+func newProjectionRequest(ctx *fiber.Ctx) projection.Request {
+	return projection.Request{
+		Fields:   ctx.Query("fields"),
+		Include:  ctx.Query("include"),
+		Included: ctx.Request().URI().QueryArgs().Has("include"),
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog/log"
 	"starter/internal/adapters/api/http"
-	"starter/internal/core/pagination"
+	"starter/internal/core/concurrency"
+	"starter/internal/core/integrity"
+	"starter/internal/core/merge"
+	"starter/internal/core/patch"
 	"starter/internal/core/publisher"
 	ivalidator "starter/internal/core/validator"
 )
@@ -25,7 +28,7 @@ func (handler *PublisherHandler) Create(ctx *fiber.Ctx) error {
 
 	request := new(publisher.CreateRequest)
 	if err := ctx.BodyParser(&request); err != nil {
-		log.Error().Err(err).Msg("failed to parse request body")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
 		return ctx.Status(fiber.StatusBadRequest).JSON(
 			http.ErrorResponse("Invalid request body"),
 		)
@@ -33,14 +36,14 @@ func (handler *PublisherHandler) Create(ctx *fiber.Ctx) error {
 
 	response, err := handler.PublisherUsecase.Save(ctx.UserContext(), *request)
 	if errors.As(err, &validationError) {
-		log.Error().Err(err).Msg("failed to create publisher")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create publisher")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ValidationResponse(validationError),
 		)
 	}
 
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create publisher")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create publisher")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create publisher"),
 		)
@@ -57,39 +60,145 @@ func (handler *PublisherHandler) Update(ctx *fiber.Ctx) error {
 	id, _ := ctx.ParamsInt("id")
 	request := new(publisher.UpdateRequest)
 	if err := ctx.BodyParser(&request); err != nil {
-		log.Error().Err(err).Msg("failed to parse request body")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
 		return ctx.Status(fiber.StatusBadRequest).JSON(
 			http.ErrorResponse("Invalid request body"),
 		)
 	}
 	request.Id = id
 
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any publisher version"),
+		)
+	}
+	request.Version = version
+
 	response, err := handler.PublisherUsecase.Update(ctx.UserContext(), *request)
 	if errors.As(err, &validationError) {
-		log.Error().Err(err).Msg("failed to create publisher")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create publisher")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ValidationResponse(validationError),
 		)
 	}
 
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to update publisher")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Publisher was modified by another request"),
+		)
+	}
+
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create publisher")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create publisher")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create publisher"),
 		)
 	}
 
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Publisher updated successfully"),
+	)
+}
+
+func (handler *PublisherHandler) Patch(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	id, _ := ctx.ParamsInt("id")
+	if !http.IsMergePatch(ctx.Get(fiber.HeaderContentType)) {
+		return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(
+			http.ErrorResponse("Content-Type must be " + http.MergePatchContentType),
+		)
+	}
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any publisher version"),
+		)
+	}
+
+	response, err := handler.PublisherUsecase.Patch(ctx.UserContext(), patch.Request{
+		Id:      id,
+		Version: version,
+		Patch:   ctx.Body(),
+	})
+	if errors.Is(err, patch.ErrInvalidPatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch publisher")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid merge patch"),
+		)
+	}
+
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch publisher")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch publisher")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Publisher was modified by another request"),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch publisher")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to patch publisher"),
+		)
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
 	return ctx.Status(fiber.StatusOK).JSON(
 		http.SuccessResponse(response, "Publisher updated successfully"),
 	)
 }
 
 func (handler *PublisherHandler) Delete(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+	var restricted integrity.RestrictedError
+
 	id, _ := ctx.ParamsInt("id")
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any publisher version"),
+		)
+	}
+
+	request := integrity.DeleteRequest{
+		Books:      ctx.Query("books"),
+		ReassignTo: uint(ctx.QueryInt("reassign_to")),
+	}
+
+	err := handler.PublisherUsecase.Delete(ctx.UserContext(), id, version, request)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to delete publisher")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Publisher was modified by another request"),
+		)
+	}
+
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to delete publisher")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+	if errors.As(err, &restricted) {
+		return ctx.Status(fiber.StatusConflict).JSON(
+			http.ErrorDataResponse("Publisher is still referenced by active books", fiber.Map{
+				"books": restricted.Count,
+			}),
+		)
+	}
 
-	err := handler.PublisherUsecase.Delete(ctx.UserContext(), id)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create publisher")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create publisher")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create publisher"),
 		)
@@ -101,43 +210,125 @@ func (handler *PublisherHandler) Delete(ctx *fiber.Ctx) error {
 }
 
 func (handler *PublisherHandler) List(ctx *fiber.Ctx) error {
-	sortBy := ctx.Query("sort_by")
-	orderBy := ctx.Query("order_by")
-	page := ctx.QueryInt("page")
-	limit := ctx.QueryInt("limit")
+	var validationError ivalidator.ValidationErrors
+
+	request := newPaginationRequest(ctx)
+	view := newProjectionRequest(ctx)
 
-	request := pagination.Request{
-		SortBy:  sortBy,
-		OrderBy: orderBy,
-		Page:    page,
-		Limit:   limit,
+	response, err := handler.PublisherUsecase.FindAll(ctx.UserContext(), &request, &view)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch publishers")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
 	}
 
-	response, err := handler.PublisherUsecase.FindAll(ctx.UserContext(), &request)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create publisher")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create publisher")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to fetch publishers"),
 		)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(
-		http.SuccessResponse(response, "Publishers fetched successfully"),
+		http.SuccessResponse(http.ProjectPage(response, view), "Publishers fetched successfully"),
 	)
 }
 
 func (handler *PublisherHandler) GetByID(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+	var moved merge.MovedError
+
 	id, _ := ctx.ParamsInt("id")
+	view := newProjectionRequest(ctx)
+
+	response, err := handler.PublisherUsecase.FindById(ctx.UserContext(), id, &view)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch publisher")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if errors.As(err, &moved) {
+		return movedPermanently(ctx, moved.ID)
+	}
 
-	response, err := handler.PublisherUsecase.FindById(ctx.UserContext(), id)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create publisher")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create publisher")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create publisher"),
 		)
 	}
 
+	etag := http.ETag(response.Version)
+	ctx.Set(fiber.HeaderETag, etag)
+	if http.MatchesETag(ctx.Get(fiber.HeaderIfNoneMatch), etag) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(http.Project(response, view), "Publisher fetched successfully"),
+	)
+}
+
+func (handler *PublisherHandler) Merge(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	id, _ := ctx.ParamsInt("id")
+	request := new(merge.Request)
+	if err := ctx.BodyParser(request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+	request.TargetID = id
+
+	response, err := handler.PublisherUsecase.Merge(ctx.UserContext(), *request)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to merge publishers")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to merge publishers")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to merge publishers"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Publishers merged successfully"),
+	)
+}
+
+func (handler *PublisherHandler) Duplicates(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	request := merge.DuplicatesRequest{
+		Threshold: ctx.QueryFloat("threshold"),
+		Limit:     ctx.QueryInt("limit"),
+	}
+
+	response, err := handler.PublisherUsecase.Duplicates(ctx.UserContext(), &request)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to find duplicate publishers")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to find duplicate publishers")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to find duplicate publishers"),
+		)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(
-		http.SuccessResponse(response, "Publisher fetched successfully"),
+		http.SuccessResponse(response, "Duplicate publishers fetched successfully"),
 	)
 }
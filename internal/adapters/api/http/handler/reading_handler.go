@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"starter/internal/adapters/api/http"
+	"starter/internal/core/concurrency"
+	"starter/internal/core/filter"
+	"starter/internal/core/reading"
+	ivalidator "starter/internal/core/validator"
+)
+
+type ReadingHandler struct {
+	ReadingUsecase reading.Usecase
+}
+
+func NewReadingHandler(readingUsecase reading.Usecase) *ReadingHandler {
+	return &ReadingHandler{
+		ReadingUsecase: readingUsecase,
+	}
+}
+
+// failed answers the errors every reading endpoint shares, fallback is the
+// message of a 500.
+func (handler *ReadingHandler) failed(ctx *fiber.Ctx, err error, fallback string) error {
+	var validationError ivalidator.ValidationErrors
+
+	switch {
+	case errors.As(err, &validationError):
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	case errors.Is(err, reading.ErrNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Book is not being tracked"),
+		)
+	case errors.Is(err, reading.ErrBookNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Book not found"),
+		)
+	case errors.Is(err, reading.ErrGoalNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("No goal for that year"),
+		)
+	case errors.Is(err, reading.ErrAlreadyTracking):
+		return ctx.Status(fiber.StatusConflict).JSON(
+			http.ErrorResponse("Book is already being tracked"),
+		)
+	case errors.Is(err, concurrency.ErrVersionMismatch):
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Reading progress was modified by another request"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusInternalServerError).JSON(
+		http.ErrorResponse(fallback),
+	)
+}
+
+func (handler *ReadingHandler) Create(ctx *fiber.Ctx) error {
+	request := new(reading.CreateRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+
+	response, err := handler.ReadingUsecase.Save(ctx.UserContext(), *request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to start tracking book")
+		return handler.failed(ctx, err, "Failed to start tracking book")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(
+		http.SuccessResponse(response, "Reading progress created successfully"),
+	)
+}
+
+func (handler *ReadingHandler) Update(ctx *fiber.Ctx) error {
+	bookID, _ := ctx.ParamsInt("book_id")
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any reading progress version"),
+		)
+	}
+
+	request := new(reading.UpdateRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+	request.BookId = bookID
+	request.Version = version
+
+	response, err := handler.ReadingUsecase.Update(ctx.UserContext(), *request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to update reading progress")
+		return handler.failed(ctx, err, "Failed to update reading progress")
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Reading progress updated successfully"),
+	)
+}
+
+func (handler *ReadingHandler) Delete(ctx *fiber.Ctx) error {
+	bookID, _ := ctx.ParamsInt("book_id")
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any reading progress version"),
+		)
+	}
+
+	err := handler.ReadingUsecase.Delete(ctx.UserContext(), bookID, version)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to delete reading progress")
+		return handler.failed(ctx, err, "Failed to delete reading progress")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse("", "Reading progress deleted successfully"),
+	)
+}
+
+func (handler *ReadingHandler) List(ctx *fiber.Ctx) error {
+	request := newPaginationRequest(ctx)
+	filter := filter.ReadingFilter{
+		Status: ctx.Query("status"),
+	}
+
+	response, err := handler.ReadingUsecase.FindAll(ctx.UserContext(), &request, &filter)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch reading progress")
+		return handler.failed(ctx, err, "Failed to fetch reading progress")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Reading progress fetched successfully"),
+	)
+}
+
+func (handler *ReadingHandler) GetByBook(ctx *fiber.Ctx) error {
+	bookID, _ := ctx.ParamsInt("book_id")
+
+	response, err := handler.ReadingUsecase.FindByBook(ctx.UserContext(), bookID)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch reading progress")
+		return handler.failed(ctx, err, "Failed to fetch reading progress")
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Reading progress fetched successfully"),
+	)
+}
+
+func (handler *ReadingHandler) CreateSession(ctx *fiber.Ctx) error {
+	request := new(reading.SessionRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+	request.BookId, _ = ctx.ParamsInt("book_id")
+
+	response, err := handler.ReadingUsecase.SaveSession(ctx.UserContext(), *request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to log reading session")
+		return handler.failed(ctx, err, "Failed to log reading session")
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusCreated).JSON(
+		http.SuccessResponse(response, "Reading session logged successfully"),
+	)
+}
+
+func (handler *ReadingHandler) Stats(ctx *fiber.Ctx) error {
+	response, err := handler.ReadingUsecase.Stats(ctx.UserContext(), ctx.QueryInt("year"))
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to build reading statistics")
+		return handler.failed(ctx, err, "Failed to build reading statistics")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Reading statistics fetched successfully"),
+	)
+}
+
+func (handler *ReadingHandler) Goals(ctx *fiber.Ctx) error {
+	response, err := handler.ReadingUsecase.FindGoals(ctx.UserContext())
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch reading goals")
+		return handler.failed(ctx, err, "Failed to fetch reading goals")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Reading goals fetched successfully"),
+	)
+}
+
+func (handler *ReadingHandler) SaveGoal(ctx *fiber.Ctx) error {
+	request := new(reading.GoalRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+	request.Year, _ = ctx.ParamsInt("year")
+
+	response, err := handler.ReadingUsecase.SaveGoal(ctx.UserContext(), *request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to save reading goal")
+		return handler.failed(ctx, err, "Failed to save reading goal")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Reading goal saved successfully"),
+	)
+}
+
+func (handler *ReadingHandler) DeleteGoal(ctx *fiber.Ctx) error {
+	year, _ := ctx.ParamsInt("year")
+
+	err := handler.ReadingUsecase.DeleteGoal(ctx.UserContext(), year)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to delete reading goal")
+		return handler.failed(ctx, err, "Failed to delete reading goal")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse("", "Reading goal deleted successfully"),
+	)
+}
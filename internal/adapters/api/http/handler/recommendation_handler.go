@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"starter/internal/adapters/api/http"
+	"starter/internal/core/recommendation"
+	ivalidator "starter/internal/core/validator"
+)
+
+type RecommendationHandler struct {
+	RecommendationUsecase recommendation.Usecase
+}
+
+func NewRecommendationHandler(recommendationUsecase recommendation.Usecase) *RecommendationHandler {
+	return &RecommendationHandler{
+		RecommendationUsecase: recommendationUsecase,
+	}
+}
+
+func (handler *RecommendationHandler) Similar(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+	request := recommendation.Request{
+		Limit: ctx.QueryInt("limit"),
+	}
+
+	response, err := handler.RecommendationUsecase.FindSimilar(ctx.UserContext(), id, request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch similar books")
+
+		var validationError ivalidator.ValidationErrors
+		if errors.As(err, &validationError) {
+			return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+				http.ValidationResponse(validationError),
+			)
+		}
+		if errors.Is(err, recommendation.ErrBookNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(
+				http.ErrorResponse("Book not found"),
+			)
+		}
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to fetch similar books"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Similar books fetched successfully"),
+	)
+}
+
+func (handler *RecommendationHandler) Recommended(ctx *fiber.Ctx) error {
+	request := recommendation.Request{
+		Limit: ctx.QueryInt("limit"),
+	}
+
+	response, err := handler.RecommendationUsecase.FindRecommended(ctx.UserContext(), request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch recommendations")
+
+		var validationError ivalidator.ValidationErrors
+		if errors.As(err, &validationError) {
+			return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+				http.ValidationResponse(validationError),
+			)
+		}
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to fetch recommendations"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Recommendations fetched successfully"),
+	)
+}
+
+// Refresh rebuilds the similarities now instead of waiting for the job.
+func (handler *RecommendationHandler) Refresh(ctx *fiber.Ctx) error {
+	response, err := handler.RecommendationUsecase.Refresh(ctx.UserContext())
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to refresh similarities")
+
+		if errors.Is(err, recommendation.ErrRefreshing) {
+			return ctx.Status(fiber.StatusConflict).JSON(
+				http.ErrorResponse("Similarities are already being refreshed"),
+			)
+		}
+
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to refresh similarities"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Similarities refreshed successfully"),
+	)
+}
@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"strings"
+)
+
+// movedPermanently answers a request for a record that was merged away with a
+// 301 to the same path under the id of the survivor, keeping the query.
+func movedPermanently(ctx *fiber.Ctx, id uint) error {
+	path := ctx.Path()
+	location := path[:strings.LastIndex(path, "/")+1] + strconv.FormatUint(uint64(id), 10)
+	if query := ctx.Request().URI().QueryString(); len(query) > 0 {
+		location += "?" + string(query)
+	}
+
+	return ctx.Redirect(location, fiber.StatusMovedPermanently)
+}
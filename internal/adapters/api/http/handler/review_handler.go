@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"starter/internal/adapters/api/http"
+	"starter/internal/core/concurrency"
+	"starter/internal/core/filter"
+	"starter/internal/core/review"
+	ivalidator "starter/internal/core/validator"
+)
+
+type ReviewHandler struct {
+	ReviewUsecase review.Usecase
+}
+
+func NewReviewHandler(reviewUsecase review.Usecase) *ReviewHandler {
+	return &ReviewHandler{
+		ReviewUsecase: reviewUsecase,
+	}
+}
+
+// failed answers the errors every review endpoint shares, fallback is the
+// message of a 500.
+func (handler *ReviewHandler) failed(ctx *fiber.Ctx, err error, fallback string) error {
+	var validationError ivalidator.ValidationErrors
+
+	switch {
+	case errors.As(err, &validationError):
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	case errors.Is(err, review.ErrNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Review not found"),
+		)
+	case errors.Is(err, review.ErrBookNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Book not found"),
+		)
+	case errors.Is(err, review.ErrNotOwner):
+		return ctx.Status(fiber.StatusForbidden).JSON(
+			http.ErrorResponse("Review belongs to another user"),
+		)
+	case errors.Is(err, review.ErrAlreadyReviewed):
+		return ctx.Status(fiber.StatusConflict).JSON(
+			http.ErrorResponse("Book was already reviewed by this user"),
+		)
+	case errors.Is(err, review.ErrOwnReview), errors.Is(err, review.ErrNotApproved):
+		return ctx.Status(fiber.StatusConflict).JSON(
+			http.ErrorResponse(err.Error()),
+		)
+	case errors.Is(err, concurrency.ErrVersionMismatch):
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Review was modified by another request"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusInternalServerError).JSON(
+		http.ErrorResponse(fallback),
+	)
+}
+
+func (handler *ReviewHandler) Create(ctx *fiber.Ctx) error {
+	request := new(review.CreateRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+	request.BookId, _ = ctx.ParamsInt("id")
+
+	response, err := handler.ReviewUsecase.Save(ctx.UserContext(), *request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create review")
+		return handler.failed(ctx, err, "Failed to create review")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(
+		http.SuccessResponse(response, "Review created successfully"),
+	)
+}
+
+func (handler *ReviewHandler) Update(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any review version"),
+		)
+	}
+
+	request := new(review.UpdateRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+	request.Id = id
+	request.Version = version
+
+	response, err := handler.ReviewUsecase.Update(ctx.UserContext(), *request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to update review")
+		return handler.failed(ctx, err, "Failed to update review")
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Review updated successfully"),
+	)
+}
+
+func (handler *ReviewHandler) Moderate(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any review version"),
+		)
+	}
+
+	request := new(review.ModerateRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+	request.Id = id
+	request.Version = version
+
+	response, err := handler.ReviewUsecase.Moderate(ctx.UserContext(), *request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to moderate review")
+		return handler.failed(ctx, err, "Failed to moderate review")
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Review moderated successfully"),
+	)
+}
+
+func (handler *ReviewHandler) Delete(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any review version"),
+		)
+	}
+
+	err := handler.ReviewUsecase.Delete(ctx.UserContext(), id, version, ctx.Locals("role") == "admin")
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to delete review")
+		return handler.failed(ctx, err, "Failed to delete review")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse("", "Review deleted successfully"),
+	)
+}
+
+// List returns the reviews of a book. Regular users only ever see approved
+// ones, admins may ask for any status.
+func (handler *ReviewHandler) List(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+	request := newPaginationRequest(ctx)
+
+	filter := filter.ReviewFilter{
+		Status: ctx.Query("status"),
+		Rating: ctx.QueryInt("rating"),
+	}
+	if ctx.Locals("role") != "admin" {
+		filter.Status = review.StatusApproved
+	}
+
+	response, err := handler.ReviewUsecase.FindAll(ctx.UserContext(), id, &request, &filter)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch reviews")
+		return handler.failed(ctx, err, "Failed to fetch reviews")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Reviews fetched successfully"),
+	)
+}
+
+func (handler *ReviewHandler) Vote(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+
+	response, err := handler.ReviewUsecase.Vote(ctx.UserContext(), id)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to vote on review")
+		return handler.failed(ctx, err, "Failed to vote on review")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Review voted helpful successfully"),
+	)
+}
+
+func (handler *ReviewHandler) Unvote(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+
+	response, err := handler.ReviewUsecase.Unvote(ctx.UserContext(), id)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to remove vote on review")
+		return handler.failed(ctx, err, "Failed to remove vote on review")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Review vote removed successfully"),
+	)
+}
@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"starter/internal/adapters/api/http"
+	"starter/internal/core/concurrency"
+	"starter/internal/core/filter"
+	"starter/internal/core/shelf"
+	ivalidator "starter/internal/core/validator"
+)
+
+type ShelfHandler struct {
+	ShelfUsecase shelf.Usecase
+}
+
+func NewShelfHandler(shelfUsecase shelf.Usecase) *ShelfHandler {
+	return &ShelfHandler{
+		ShelfUsecase: shelfUsecase,
+	}
+}
+
+// failed answers the errors every shelf endpoint shares, fallback is the
+// message of a 500.
+func (handler *ShelfHandler) failed(ctx *fiber.Ctx, err error, fallback string) error {
+	var validationError ivalidator.ValidationErrors
+
+	switch {
+	case errors.As(err, &validationError):
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	case errors.Is(err, shelf.ErrNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Shelf not found"),
+		)
+	case errors.Is(err, shelf.ErrBookNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Book not found"),
+		)
+	case errors.Is(err, shelf.ErrItemNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Book is not on the shelf"),
+		)
+	case errors.Is(err, shelf.ErrAlreadyOnIt):
+		return ctx.Status(fiber.StatusConflict).JSON(
+			http.ErrorResponse("Book is already on the shelf"),
+		)
+	case errors.Is(err, shelf.ErrDefaultShelf):
+		return ctx.Status(fiber.StatusConflict).JSON(
+			http.ErrorResponse("Default shelves cannot be deleted"),
+		)
+	case errors.Is(err, concurrency.ErrVersionMismatch):
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("Shelf was modified by another request"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusInternalServerError).JSON(
+		http.ErrorResponse(fallback),
+	)
+}
+
+func (handler *ShelfHandler) Create(ctx *fiber.Ctx) error {
+	request := new(shelf.CreateRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+
+	response, err := handler.ShelfUsecase.Save(ctx.UserContext(), *request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create shelf")
+		return handler.failed(ctx, err, "Failed to create shelf")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(
+		http.SuccessResponse(response, "Shelf created successfully"),
+	)
+}
+
+func (handler *ShelfHandler) Update(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any shelf version"),
+		)
+	}
+
+	request := new(shelf.UpdateRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+	request.Id = id
+	request.Version = version
+
+	response, err := handler.ShelfUsecase.Update(ctx.UserContext(), *request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to update shelf")
+		return handler.failed(ctx, err, "Failed to update shelf")
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Shelf updated successfully"),
+	)
+}
+
+func (handler *ShelfHandler) Delete(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any shelf version"),
+		)
+	}
+
+	err := handler.ShelfUsecase.Delete(ctx.UserContext(), id, version)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to delete shelf")
+		return handler.failed(ctx, err, "Failed to delete shelf")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse("", "Shelf deleted successfully"),
+	)
+}
+
+func (handler *ShelfHandler) List(ctx *fiber.Ctx) error {
+	request := newPaginationRequest(ctx)
+
+	response, err := handler.ShelfUsecase.FindAll(ctx.UserContext(), &request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch shelves")
+		return handler.failed(ctx, err, "Failed to fetch shelves")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Shelves fetched successfully"),
+	)
+}
+
+func (handler *ShelfHandler) GetByID(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+
+	response, err := handler.ShelfUsecase.FindById(ctx.UserContext(), id)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch shelf")
+		return handler.failed(ctx, err, "Failed to fetch shelf")
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Shelf fetched successfully"),
+	)
+}
+
+func (handler *ShelfHandler) AddItem(ctx *fiber.Ctx) error {
+	request := new(shelf.ItemRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+	request.ShelfId, _ = ctx.ParamsInt("id")
+
+	response, err := handler.ShelfUsecase.SaveItem(ctx.UserContext(), *request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to add book to shelf")
+		return handler.failed(ctx, err, "Failed to add book to shelf")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(
+		http.SuccessResponse(response, "Book added to shelf successfully"),
+	)
+}
+
+func (handler *ShelfHandler) UpdateItem(ctx *fiber.Ctx) error {
+	request := new(shelf.ItemRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+	request.ShelfId, _ = ctx.ParamsInt("id")
+	request.BookId, _ = ctx.ParamsInt("book_id")
+
+	response, err := handler.ShelfUsecase.UpdateItem(ctx.UserContext(), *request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to update shelf item")
+		return handler.failed(ctx, err, "Failed to update shelf item")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Shelf item updated successfully"),
+	)
+}
+
+func (handler *ShelfHandler) RemoveItem(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+	bookID, _ := ctx.ParamsInt("book_id")
+
+	response, err := handler.ShelfUsecase.DeleteItem(ctx.UserContext(), id, bookID)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to remove book from shelf")
+		return handler.failed(ctx, err, "Failed to remove book from shelf")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Book removed from shelf successfully"),
+	)
+}
+
+func (handler *ShelfHandler) Public(ctx *fiber.Ctx) error {
+	request := newPaginationRequest(ctx)
+	filter := filter.ShelfFilter{
+		UserID: uint(ctx.QueryInt("user_id")),
+		Search: ctx.Query("search"),
+	}
+
+	response, err := handler.ShelfUsecase.FindPublic(ctx.UserContext(), &request, &filter)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch public shelves")
+		return handler.failed(ctx, err, "Failed to fetch shelves")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Shelves fetched successfully"),
+	)
+}
+
+func (handler *ShelfHandler) PublicByID(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+
+	response, err := handler.ShelfUsecase.FindPublicById(ctx.UserContext(), id)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch public shelf")
+		return handler.failed(ctx, err, "Failed to fetch shelf")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Shelf fetched successfully"),
+	)
+}
+
+func (handler *ShelfHandler) Shared(ctx *fiber.Ctx) error {
+	response, err := handler.ShelfUsecase.FindShared(ctx.UserContext(), ctx.Params("token"))
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch shared shelf")
+		return handler.failed(ctx, err, "Failed to fetch shelf")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Shelf fetched successfully"),
+	)
+}
@@ -21,7 +21,7 @@ func NewStorageHandler(storage storage.Storage) *StorageHandler {
 func (handler *StorageHandler) Upload(ctx *fiber.Ctx) error {
 	file, err := ctx.FormFile("file")
 	if err != nil {
-		log.Error().Err(err).Msg("failed to upload file")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to upload file")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ErrorResponse("Failed to upload file"),
 		)
@@ -31,7 +31,7 @@ func (handler *StorageHandler) Upload(ctx *fiber.Ctx) error {
 	defer f.Close()
 
 	if err != nil {
-		log.Error().Err(err).Msg("failed to open file")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to open file")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ErrorResponse("Failed to open file"),
 		)
@@ -39,7 +39,7 @@ func (handler *StorageHandler) Upload(ctx *fiber.Ctx) error {
 
 	data, err := io.ReadAll(f)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to read file")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to read file")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ErrorResponse("Failed to open file"),
 		)
@@ -53,7 +53,7 @@ func (handler *StorageHandler) Upload(ctx *fiber.Ctx) error {
 
 	upload := handler.Storage.Upload(ctx.Context(), uploadRequest)
 	if upload == nil {
-		log.Error().Err(err).Msg("failed to save file")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to save file")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to save file"),
 		)
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"starter/internal/adapters/api/http"
+	"starter/internal/core/tag"
+	ivalidator "starter/internal/core/validator"
+)
+
+type TagHandler struct {
+	TagUsecase tag.Usecase
+}
+
+func NewTagHandler(tagUsecase tag.Usecase) *TagHandler {
+	return &TagHandler{
+		TagUsecase: tagUsecase,
+	}
+}
+
+func (handler *TagHandler) failed(ctx *fiber.Ctx, err error, fallback string) error {
+	var validationError ivalidator.ValidationErrors
+
+	switch {
+	case errors.As(err, &validationError):
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	case errors.Is(err, tag.ErrNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Tag not found"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusInternalServerError).JSON(
+		http.ErrorResponse(fallback),
+	)
+}
+
+func (handler *TagHandler) Create(ctx *fiber.Ctx) error {
+	request := new(tag.CreateRequest)
+	if err := ctx.BodyParser(&request); err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid request body"),
+		)
+	}
+
+	response, err := handler.TagUsecase.Save(ctx.UserContext(), *request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create tag")
+		return handler.failed(ctx, err, "Failed to create tag")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(
+		http.SuccessResponse(response, "Tag created successfully"),
+	)
+}
+
+func (handler *TagHandler) Delete(ctx *fiber.Ctx) error {
+	id, _ := ctx.ParamsInt("id")
+
+	err := handler.TagUsecase.Delete(ctx.UserContext(), uint(id))
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to delete tag")
+		return handler.failed(ctx, err, "Failed to delete tag")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse("", "Tag deleted successfully"),
+	)
+}
+
+// Search completes ?prefix= against the tags, the most used first.
+func (handler *TagHandler) Search(ctx *fiber.Ctx) error {
+	request := tag.SearchRequest{
+		Prefix: ctx.Query("prefix"),
+		Kind:   ctx.Query("kind"),
+		Limit:  ctx.QueryInt("limit"),
+	}
+
+	response, err := handler.TagUsecase.Search(ctx.UserContext(), request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to search tags")
+		return handler.failed(ctx, err, "Failed to fetch tags")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Tags fetched successfully"),
+	)
+}
+
+func (handler *TagHandler) Cloud(ctx *fiber.Ctx) error {
+	request := tag.CloudRequest{
+		Kind:  ctx.Query("kind"),
+		Limit: ctx.QueryInt("limit"),
+	}
+
+	response, err := handler.TagUsecase.Cloud(ctx.UserContext(), request)
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to count tags")
+		return handler.failed(ctx, err, "Failed to fetch tag cloud")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Tag cloud fetched successfully"),
+	)
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"starter/internal/adapters/api/http"
+	"starter/internal/core/trash"
+	ivalidator "starter/internal/core/validator"
+)
+
+type TrashHandler struct {
+	TrashUsecase trash.Usecase
+}
+
+func NewTrashHandler(trashUsecase trash.Usecase) *TrashHandler {
+	return &TrashHandler{
+		TrashUsecase: trashUsecase,
+	}
+}
+
+// allowed keeps the trash of admin only entities, users, to admins even on
+// the routes regular users can reach.
+func (handler *TrashHandler) allowed(ctx *fiber.Ctx, entity string) bool {
+	kind, ok := trash.Kinds[entity]
+	return !ok || !kind.AdminOnly || ctx.Locals("role") == "admin"
+}
+
+func (handler *TrashHandler) List(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	entity := ctx.Params("entity")
+	if !handler.allowed(ctx, entity) {
+		return ctx.Status(fiber.StatusForbidden).JSON(
+			http.ErrorResponse("Insufficient roles"),
+		)
+	}
+	request := newPaginationRequest(ctx)
+
+	response, err := handler.TrashUsecase.FindAll(ctx.UserContext(), entity, &request)
+	if errors.Is(err, trash.ErrUnknownEntity) {
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Unknown trash entity"),
+		)
+	}
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch trash")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch trash")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to fetch trash"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Trash fetched successfully"),
+	)
+}
+
+func (handler *TrashHandler) Restore(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+	var trashedParent trash.TrashedParentError
+
+	entity := ctx.Params("entity")
+	if !handler.allowed(ctx, entity) {
+		return ctx.Status(fiber.StatusForbidden).JSON(
+			http.ErrorResponse("Insufficient roles"),
+		)
+	}
+	id, _ := ctx.ParamsInt("id")
+
+	response, err := handler.TrashUsecase.Restore(ctx.UserContext(), entity, id)
+	if errors.Is(err, trash.ErrUnknownEntity) || errors.Is(err, trash.ErrNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Item not found in the trash"),
+		)
+	}
+	if errors.As(err, &trashedParent) {
+		return ctx.Status(fiber.StatusConflict).JSON(
+			http.ErrorResponse("Restore the referenced " + trashedParent.Table + " first"),
+		)
+	}
+	if errors.As(err, &validationError) {
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to restore item")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to restore item"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "Item restored successfully"),
+	)
+}
+
+func (handler *TrashHandler) Purge(ctx *fiber.Ctx) error {
+	var referenced trash.ReferencedError
+
+	entity := ctx.Params("entity")
+	id, _ := ctx.ParamsInt("id")
+
+	err := handler.TrashUsecase.Purge(ctx.UserContext(), entity, id)
+	if errors.Is(err, trash.ErrUnknownEntity) || errors.Is(err, trash.ErrNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(
+			http.ErrorResponse("Item not found in the trash"),
+		)
+	}
+	if errors.As(err, &referenced) {
+		return ctx.Status(fiber.StatusConflict).JSON(
+			http.ErrorResponse(fmt.Sprintf("Item is still referenced by %d rows of %s", referenced.Count, referenced.Table)),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to purge item")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to purge item"),
+		)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse("", "Item purged successfully"),
+	)
+}
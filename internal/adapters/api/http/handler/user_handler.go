@@ -5,7 +5,8 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog/log"
 	"starter/internal/adapters/api/http"
-	"starter/internal/core/pagination"
+	"starter/internal/core/concurrency"
+	"starter/internal/core/patch"
 	"starter/internal/core/user"
 	ivalidator "starter/internal/core/validator"
 )
@@ -25,7 +26,7 @@ func (handler *UserHandler) Create(ctx *fiber.Ctx) error {
 
 	request := new(user.CreateRequest)
 	if err := ctx.BodyParser(&request); err != nil {
-		log.Error().Err(err).Msg("failed to parse request body")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
 		return ctx.Status(fiber.StatusBadRequest).JSON(
 			http.ErrorResponse("Invalid request body"),
 		)
@@ -33,14 +34,14 @@ func (handler *UserHandler) Create(ctx *fiber.Ctx) error {
 
 	response, err := handler.UserUsecase.Save(ctx.UserContext(), *request)
 	if errors.As(err, &validationError) {
-		log.Error().Err(err).Msg("failed to create user")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create user")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ValidationResponse(validationError),
 		)
 	}
 
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create user")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create user")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create user"),
 		)
@@ -57,28 +58,99 @@ func (handler *UserHandler) Update(ctx *fiber.Ctx) error {
 	id, _ := ctx.ParamsInt("id")
 	request := new(user.UpdateRequest)
 	if err := ctx.BodyParser(&request); err != nil {
-		log.Error().Err(err).Msg("failed to parse request body")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to parse request body")
 		return ctx.Status(fiber.StatusBadRequest).JSON(
 			http.ErrorResponse("Invalid request body"),
 		)
 	}
 	request.Id = id
 
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any user version"),
+		)
+	}
+	request.Version = version
+
 	response, err := handler.UserUsecase.Update(ctx.UserContext(), *request)
 	if errors.As(err, &validationError) {
-		log.Error().Err(err).Msg("failed to create user")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create user")
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
 			http.ValidationResponse(validationError),
 		)
 	}
 
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to update user")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("User was modified by another request"),
+		)
+	}
+
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create user")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create user")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create user"),
 		)
 	}
 
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
+	return ctx.Status(fiber.StatusOK).JSON(
+		http.SuccessResponse(response, "User updated successfully"),
+	)
+}
+
+func (handler *UserHandler) Patch(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
+	id, _ := ctx.ParamsInt("id")
+	if !http.IsMergePatch(ctx.Get(fiber.HeaderContentType)) {
+		return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(
+			http.ErrorResponse("Content-Type must be " + http.MergePatchContentType),
+		)
+	}
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any user version"),
+		)
+	}
+
+	response, err := handler.UserUsecase.Patch(ctx.UserContext(), patch.Request{
+		Id:      id,
+		Version: version,
+		Patch:   ctx.Body(),
+	})
+	if errors.Is(err, patch.ErrInvalidPatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch user")
+		return ctx.Status(fiber.StatusBadRequest).JSON(
+			http.ErrorResponse("Invalid merge patch"),
+		)
+	}
+
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch user")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
+
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch user")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("User was modified by another request"),
+		)
+	}
+
+	if err != nil {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to patch user")
+		return ctx.Status(fiber.StatusInternalServerError).JSON(
+			http.ErrorResponse("Failed to patch user"),
+		)
+	}
+
+	ctx.Set(fiber.HeaderETag, http.ETag(response.Version))
 	return ctx.Status(fiber.StatusOK).JSON(
 		http.SuccessResponse(response, "User updated successfully"),
 	)
@@ -86,10 +158,23 @@ func (handler *UserHandler) Update(ctx *fiber.Ctx) error {
 
 func (handler *UserHandler) Delete(ctx *fiber.Ctx) error {
 	id, _ := ctx.ParamsInt("id")
+	version, ok := http.ParseIfMatch(ctx.Get(fiber.HeaderIfMatch))
+	if !ok {
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("If-Match does not match any user version"),
+		)
+	}
+
+	err := handler.UserUsecase.Delete(ctx.UserContext(), id, version)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to delete user")
+		return ctx.Status(fiber.StatusPreconditionFailed).JSON(
+			http.ErrorResponse("User was modified by another request"),
+		)
+	}
 
-	err := handler.UserUsecase.Delete(ctx.UserContext(), id)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create user")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create user")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create user"),
 		)
@@ -101,59 +186,91 @@ func (handler *UserHandler) Delete(ctx *fiber.Ctx) error {
 }
 
 func (handler *UserHandler) List(ctx *fiber.Ctx) error {
-	sortBy := ctx.Query("sort_by")
-	orderBy := ctx.Query("order_by")
-	page := ctx.QueryInt("page")
-	limit := ctx.QueryInt("limit")
+	var validationError ivalidator.ValidationErrors
 
-	request := pagination.Request{
-		SortBy:  sortBy,
-		OrderBy: orderBy,
-		Page:    page,
-		Limit:   limit,
+	request := newPaginationRequest(ctx)
+	view := newProjectionRequest(ctx)
+
+	response, err := handler.UserUsecase.FindAll(ctx.UserContext(), &request, &view)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch users")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
 	}
 
-	response, err := handler.UserUsecase.FindAll(ctx.UserContext(), &request)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create user")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create user")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to fetch users"),
 		)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(
-		http.SuccessResponse(response, "Users fetched successfully"),
+		http.SuccessResponse(http.ProjectPage(response, view), "Users fetched successfully"),
 	)
 }
 
 func (handler *UserHandler) GetByID(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
 	id, _ := ctx.ParamsInt("id")
+	view := newProjectionRequest(ctx)
+
+	response, err := handler.UserUsecase.FindById(ctx.UserContext(), id, &view)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch user")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
 
-	response, err := handler.UserUsecase.FindById(ctx.UserContext(), id)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create user")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create user")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create user"),
 		)
 	}
 
+	etag := http.ETag(response.Version)
+	ctx.Set(fiber.HeaderETag, etag)
+	if http.MatchesETag(ctx.Get(fiber.HeaderIfNoneMatch), etag) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(
-		http.SuccessResponse(response, "User fetched successfully"),
+		http.SuccessResponse(http.Project(response, view), "User fetched successfully"),
 	)
 }
 
 func (handler *UserHandler) GetByEmail(ctx *fiber.Ctx) error {
+	var validationError ivalidator.ValidationErrors
+
 	email := ctx.Params("email")
+	view := newProjectionRequest(ctx)
+
+	response, err := handler.UserUsecase.FindByEmail(ctx.UserContext(), email, &view)
+	if errors.As(err, &validationError) {
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to fetch user")
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(
+			http.ValidationResponse(validationError),
+		)
+	}
 
-	response, err := handler.UserUsecase.FindByEmail(ctx.UserContext(), email)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create user")
+		log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to create user")
 		return ctx.Status(fiber.StatusInternalServerError).JSON(
 			http.ErrorResponse("Failed to create user"),
 		)
 	}
 
+	etag := http.ETag(response.Version)
+	ctx.Set(fiber.HeaderETag, etag)
+	if http.MatchesETag(ctx.Get(fiber.HeaderIfNoneMatch), etag) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(
-		http.SuccessResponse(response, "User fetched successfully"),
+		http.SuccessResponse(http.Project(response, view), "User fetched successfully"),
 	)
 }
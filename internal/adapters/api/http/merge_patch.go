@@ -0,0 +1,17 @@
+package http
+
+import (
+	"mime"
+)
+
+const MergePatchContentType = "application/merge-patch+json"
+
+// IsMergePatch reports whether a Content-Type header announces an RFC 7396
+// merge patch. Plain application/json is accepted too, as most clients send it.
+func IsMergePatch(header string) bool {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return false
+	}
+	return mediaType == MergePatchContentType || mediaType == "application/json"
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/rs/zerolog/log"
 	"starter/config"
 	"starter/internal/adapters/api/http"
+	"starter/internal/core/audit"
+	"strconv"
 )
 
 func JWTMiddleware() fiber.Handler {
@@ -26,7 +28,7 @@ func RoleMiddleware(allowedRoles ...string) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		userToken, ok := ctx.Locals("user").(*jwt.Token)
 		if !ok {
-			log.Error().Msg("failed to get current user")
+			log.Ctx(ctx.UserContext()).Error().Msg("failed to get current user")
 			return ctx.Status(fiber.StatusUnauthorized).JSON(
 				http.ErrorResponse("Invalid or expired token"),
 			)
@@ -34,19 +36,27 @@ func RoleMiddleware(allowedRoles ...string) fiber.Handler {
 
 		claims, ok := userToken.Claims.(jwt.MapClaims)
 		if !ok {
-			log.Error().Msg("failed to parse token")
+			log.Ctx(ctx.UserContext()).Error().Msg("failed to parse token")
 			return ctx.Status(fiber.StatusUnauthorized).JSON(
 				http.ErrorResponse("Invalid or expired token"),
 			)
 		}
 
 		role, _ := claims["role"].(string)
-		userID, _ := claims["user_id"].(string)
+		userID := ""
+		actor := audit.ActorFrom(ctx.UserContext())
+		if id, ok := claims["user_id"].(float64); ok {
+			userID = strconv.FormatUint(uint64(id), 10)
+			actor.UserID = uint(id)
+		}
 
 		for _, allowed := range allowedRoles {
 			if role == allowed {
 				ctx.Locals("user_id", userID)
 				ctx.Locals("role", role)
+
+				logger := log.Ctx(ctx.UserContext()).With().Str("user_id", userID).Logger()
+				ctx.SetUserContext(audit.WithActor(logger.WithContext(ctx.UserContext()), actor))
 				return ctx.Next()
 			}
 		}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"starter/internal/adapters/api/http"
+	"starter/internal/core/idempotency"
+	"time"
+)
+
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// IdempotencyMiddleware replays the stored response when a request is retried
+// with the same Idempotency-Key. Keys are scoped to the caller and the route,
+// and reusing one with a different body is rejected with 409. Responses with a
+// 5xx status are not kept so the client can retry them. Requests without a
+// known caller, such as a registration, share one scope, the body fingerprint
+// keeps them from being answered with the response to another body.
+func IdempotencyMiddleware(store idempotency.Store, ttl time.Duration) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		key := ctx.Get(HeaderIdempotencyKey)
+		if key == "" {
+			return ctx.Next()
+		}
+		if len(key) > 255 {
+			return ctx.Status(fiber.StatusBadRequest).JSON(
+				http.ErrorResponse("Idempotency-Key must be at most 255 characters"),
+			)
+		}
+
+		scope, _ := ctx.Locals("user_id").(string)
+		if scope == "" {
+			scope = "anonymous"
+		}
+
+		record := idempotency.Record{
+			Key:         digest([]byte(fmt.Sprintf("%s\n%s\n%s\n%s", scope, ctx.Method(), ctx.Path(), key))),
+			Fingerprint: digest(ctx.Body()),
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+
+		existing, err := store.Reserve(ctx.UserContext(), record)
+		if err != nil {
+			log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to reserve idempotency key")
+			return ctx.Status(fiber.StatusInternalServerError).JSON(
+				http.ErrorResponse("Something went wrong"),
+			)
+		}
+
+		if existing != nil {
+			if existing.Fingerprint != record.Fingerprint {
+				return ctx.Status(fiber.StatusConflict).JSON(
+					http.ErrorResponse("Idempotency-Key was already used with a different request"),
+				)
+			}
+			if !existing.Completed {
+				return ctx.Status(fiber.StatusConflict).JSON(
+					http.ErrorResponse("A request with this Idempotency-Key is still being processed"),
+				)
+			}
+
+			ctx.Set("Idempotent-Replayed", "true")
+			ctx.Set(fiber.HeaderContentType, existing.ContentType)
+			return ctx.Status(existing.StatusCode).Send(existing.Body)
+		}
+
+		err = ctx.Next()
+		status := ctx.Response().StatusCode()
+		if err != nil || status >= fiber.StatusInternalServerError {
+			if err := store.Release(ctx.UserContext(), record.Key); err != nil {
+				log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to release idempotency key")
+			}
+			return err
+		}
+
+		record.StatusCode = status
+		record.ContentType = string(ctx.Response().Header.ContentType())
+		record.Body = append([]byte(nil), ctx.Response().Body()...)
+		if err := store.Complete(ctx.UserContext(), record); err != nil {
+			log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to store idempotent response")
+		}
+
+		return nil
+	}
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
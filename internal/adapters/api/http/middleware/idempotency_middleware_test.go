@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"net/http/httptest"
+	"starter/internal/adapters/memory"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newIdempotentApp serves POST /books behind the middleware, answering with
+// the statuses in order and counting the calls that get through. The caller
+// is taken from the X-User header the way the auth middleware would set it.
+func newIdempotentApp(statuses ...int) (*fiber.App, *int) {
+	calls := 0
+	app := fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		if user := ctx.Get("X-User"); user != "" {
+			ctx.Locals("user_id", user)
+		}
+		return ctx.Next()
+	})
+	app.Use(IdempotencyMiddleware(memory.NewIdempotencyStore(), time.Hour))
+	app.Post("/books", func(ctx *fiber.Ctx) error {
+		status := statuses[min(calls, len(statuses)-1)]
+		calls++
+		return ctx.Status(status).JSON(fiber.Map{"call": calls})
+	})
+	return app, &calls
+}
+
+type idempotentRequest struct {
+	user string
+	key  string
+	body string
+}
+
+func send(t *testing.T, app *fiber.App, request idempotentRequest) (int, string, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(fiber.MethodPost, "/books", strings.NewReader(request.body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if request.key != "" {
+		req.Header.Set(HeaderIdempotencyKey, request.key)
+	}
+	if request.user != "" {
+		req.Header.Set("X-User", request.user)
+	}
+
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, res.Header.Get("Idempotent-Replayed"), string(body)
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	first := idempotentRequest{user: "1", key: "abc", body: `{"title":"Refactoring"}`}
+
+	tests := []struct {
+		name         string
+		statuses     []int
+		anonymous    bool
+		retry        idempotentRequest
+		wantStatus   int
+		wantReplayed string
+		wantCalls    int
+	}{
+		{
+			name:         "replays the stored response",
+			statuses:     []int{fiber.StatusCreated},
+			retry:        first,
+			wantStatus:   fiber.StatusCreated,
+			wantReplayed: "true",
+			wantCalls:    1,
+		},
+		{
+			name:       "rejects the key with another body",
+			statuses:   []int{fiber.StatusCreated},
+			retry:      idempotentRequest{user: "1", key: "abc", body: `{"title":"Clean Code"}`},
+			wantStatus: fiber.StatusConflict,
+			wantCalls:  1,
+		},
+		{
+			name:       "keeps keys of other callers apart",
+			statuses:   []int{fiber.StatusCreated},
+			retry:      idempotentRequest{user: "2", key: "abc", body: first.body},
+			wantStatus: fiber.StatusCreated,
+			wantCalls:  2,
+		},
+		{
+			name:       "runs requests without a key every time",
+			statuses:   []int{fiber.StatusCreated},
+			retry:      idempotentRequest{user: "1", body: first.body},
+			wantStatus: fiber.StatusCreated,
+			wantCalls:  2,
+		},
+		{
+			name:       "releases the key after a server error",
+			statuses:   []int{fiber.StatusInternalServerError, fiber.StatusCreated},
+			retry:      first,
+			wantStatus: fiber.StatusCreated,
+			wantCalls:  2,
+		},
+		{
+			name:         "replays anonymous requests",
+			statuses:     []int{fiber.StatusCreated},
+			anonymous:    true,
+			retry:        first,
+			wantStatus:   fiber.StatusCreated,
+			wantReplayed: "true",
+			wantCalls:    1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app, calls := newIdempotentApp(test.statuses...)
+			request, retry := first, test.retry
+			if test.anonymous {
+				request.user, retry.user = "", ""
+			}
+
+			send(t, app, request)
+			status, replayed, _ := send(t, app, retry)
+
+			if status != test.wantStatus {
+				t.Errorf("status = %d, want %d", status, test.wantStatus)
+			}
+			if replayed != test.wantReplayed {
+				t.Errorf("Idempotent-Replayed = %q, want %q", replayed, test.wantReplayed)
+			}
+			if *calls != test.wantCalls {
+				t.Errorf("handler called %d times, want %d", *calls, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestIdempotencyMiddlewareReplaysBody(t *testing.T) {
+	app, _ := newIdempotentApp(fiber.StatusCreated)
+	request := idempotentRequest{user: "1", key: "abc", body: `{}`}
+
+	_, _, body := send(t, app, request)
+	_, _, replayed := send(t, app, request)
+	if replayed != body {
+		t.Errorf("replayed body = %s, want %s", replayed, body)
+	}
+}
+
+func TestIdempotencyMiddlewareKeyLength(t *testing.T) {
+	app, calls := newIdempotentApp(fiber.StatusCreated)
+
+	status, _, _ := send(t, app, idempotentRequest{key: strings.Repeat("k", 256), body: `{}`})
+	if status != fiber.StatusBadRequest || *calls != 0 {
+		t.Errorf("status = %d after %d calls, want 400 before the handler", status, *calls)
+	}
+}
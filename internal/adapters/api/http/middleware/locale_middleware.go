@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/text/language"
+	"starter/internal/core/locale"
+)
+
+// LocaleMiddleware puts the languages of Accept-Language in UserContext, which
+// is where the usecases pick localised content from. A header that doesn't
+// parse is ignored, the content then comes in its own language.
+func LocaleMiddleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		tags, _, err := language.ParseAcceptLanguage(ctx.Get(fiber.HeaderAcceptLanguage))
+		if err == nil && len(tags) > 0 {
+			ctx.SetUserContext(locale.WithPreferred(ctx.UserContext(), tags))
+		}
+
+		return ctx.Next()
+	}
+}
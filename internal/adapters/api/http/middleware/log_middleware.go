@@ -12,7 +12,7 @@ func ZerologMiddleware() fiber.Handler {
 		err := c.Next()
 		stop := time.Now()
 
-		log.Info().
+		log.Ctx(c.UserContext()).Info().
 			Str("method", c.Method()).
 			Str("path", c.Path()).
 			Int("status", c.Response().StatusCode()).
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"starter/internal/adapters/metrics"
+	"strconv"
+	"time"
+)
+
+// MetricsMiddleware counts and times requests by the route template they
+// matched, e.g. /api/v1/books/:id, so ids don't blow up the label set.
+func MetricsMiddleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+		err := ctx.Next()
+
+		status := ctx.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if fiberError, ok := err.(*fiber.Error); ok {
+				status = fiberError.Code
+			}
+		}
+
+		route := ctx.Route().Path
+		if status == fiber.StatusNotFound && route == "/" {
+			route = "unmatched"
+		}
+
+		labels := []string{ctx.Method(), route, strconv.Itoa(status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"starter/internal/adapters/api/http"
+	"starter/internal/core/ratelimit"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitMiddleware allows limit requests per sliding window for every key
+// returned by key, and answers 429 with Retry-After past that. name keeps the
+// windows of different groups apart. Requests without a key are not limited,
+// and a failing store lets requests through rather than locking everyone out.
+func RateLimitMiddleware(store ratelimit.Store, name string, limit ratelimit.Limit, key func(ctx *fiber.Ctx) string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if !limit.Enabled() {
+			return ctx.Next()
+		}
+
+		id := key(ctx)
+		if id == "" {
+			return ctx.Next()
+		}
+
+		now := time.Now()
+		window, err := store.Take(ctx.UserContext(), name+":"+id, now, limit)
+		if err != nil {
+			log.Ctx(ctx.UserContext()).Error().Err(err).Msg("failed to apply rate limit")
+			return ctx.Next()
+		}
+
+		reset := http.Seconds(limit.Reset(window, now))
+		ctx.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		ctx.Set("RateLimit-Remaining", strconv.Itoa(limit.Remaining(window)))
+		ctx.Set("RateLimit-Reset", reset)
+
+		if !window.Allowed {
+			ctx.Set(fiber.HeaderRetryAfter, reset)
+			return ctx.Status(fiber.StatusTooManyRequests).JSON(
+				http.ErrorResponse("Too many requests, try again in " + reset + " seconds"),
+			)
+		}
+
+		return ctx.Next()
+	}
+}
+
+// KeyByIP limits each client address on its own.
+func KeyByIP(ctx *fiber.Ctx) string {
+	return ctx.IP()
+}
+
+// KeyByBodyField limits each value of a JSON body field on its own, e.g. the
+// email an auth request is for. Values are compared case insensitively.
+func KeyByBodyField(field string) func(ctx *fiber.Ctx) string {
+	return func(ctx *fiber.Ctx) string {
+		var body map[string]any
+		if err := json.Unmarshal(ctx.Body(), &body); err != nil {
+			return ""
+		}
+
+		value, _ := body[field].(string)
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+}
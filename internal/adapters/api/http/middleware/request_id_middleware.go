@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"starter/internal/core/audit"
+	"sync/atomic"
+)
+
+const HeaderRequestID = "X-Request-ID"
+
+// RequestIDMiddleware tags every request with an id, reusing a sane incoming
+// X-Request-ID, and echoes it back. It puts a logger carrying the id in
+// UserContext so every layer can log through log.Ctx(ctx), next to the audit
+// actor that RoleMiddleware completes with the user. The route
+// template is only known once routing is done, so it is added when a line is
+// written rather than up front.
+func RequestIDMiddleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		id := ctx.Get(HeaderRequestID)
+		if !validRequestID(id) {
+			id = utils.UUIDv4()
+		}
+		ctx.Set(HeaderRequestID, id)
+		ctx.Locals("request_id", id)
+
+		var finished atomic.Bool
+		defer finished.Store(true)
+
+		logger := log.Logger.With().
+			Str("request_id", id).
+			Logger().
+			Hook(zerolog.HookFunc(func(event *zerolog.Event, level zerolog.Level, message string) {
+				// ctx is recycled by fiber once the request is over.
+				if !finished.Load() {
+					event.Str("route", ctx.Route().Path)
+				}
+			}))
+		ctx.SetUserContext(audit.WithActor(logger.WithContext(ctx.UserContext()), audit.Actor{
+			IP:        ctx.IP(),
+			RequestID: id,
+		}))
+
+		return ctx.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
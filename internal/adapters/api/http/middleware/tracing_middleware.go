@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+var tracer = otel.Tracer("starter/internal/adapters/api/http")
+
+// TracingMiddleware starts a server span for every request, continuing the
+// trace from the incoming traceparent header, and hands its context to the
+// handlers through UserContext. The span is named after the route template
+// once routing is done.
+func TracingMiddleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		carrier := propagation.MapCarrier{}
+		for key, value := range ctx.GetReqHeaders() {
+			if len(value) > 0 {
+				carrier[strings.ToLower(key)] = value[0]
+			}
+		}
+		parent := otel.GetTextMapPropagator().Extract(ctx.UserContext(), carrier)
+
+		spanCtx, span := tracer.Start(parent, ctx.Method()+" "+ctx.Path(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", ctx.Method()),
+				attribute.String("url.path", ctx.Path()),
+				attribute.String("client.address", ctx.IP()),
+			),
+		)
+		defer span.End()
+		ctx.SetUserContext(spanCtx)
+
+		err := ctx.Next()
+
+		route := ctx.Route().Path
+		status := ctx.Response().StatusCode()
+		span.SetName(ctx.Method() + " " + route)
+		span.SetAttributes(
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", status),
+		)
+		if err != nil {
+			span.RecordError(err)
+		}
+		if err != nil || status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+
+		return err
+	}
+}
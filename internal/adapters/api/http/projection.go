@@ -0,0 +1,48 @@
+package http
+
+import (
+	"encoding/json"
+	"starter/internal/core/pagination"
+	"starter/internal/core/projection"
+)
+
+// Project trims data down to the keys the client asked for through fields and
+// include. Responses keep their usual shape when view was never parsed.
+func Project(data any, view projection.Request) any {
+	if view.Keys == nil {
+		return data
+	}
+
+	var fields map[string]any
+	raw, err := json.Marshal(data)
+	if err != nil || json.Unmarshal(raw, &fields) != nil || fields == nil {
+		return data
+	}
+
+	for key := range fields {
+		if !view.Has(key) {
+			delete(fields, key)
+		}
+	}
+
+	return fields
+}
+
+func ProjectPage[T any](page pagination.Page[T], view projection.Request) pagination.Page[any] {
+	items := make([]any, 0, len(page.Items))
+	for _, item := range page.Items {
+		items = append(items, Project(item, view))
+	}
+
+	return pagination.Page[any]{
+		Items:       items,
+		CurrentPage: page.CurrentPage,
+		PerPage:     page.PerPage,
+		TotalPages:  page.TotalPages,
+		TotalItems:  page.TotalItems,
+		NextPage:    page.NextPage,
+		PrevPage:    page.PrevPage,
+		NextCursor:  page.NextCursor,
+		PrevCursor:  page.PrevCursor,
+	}
+}
@@ -0,0 +1,13 @@
+package http
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Seconds renders a duration the way Retry-After and RateLimit-Reset expect
+// it, in whole seconds rounded up.
+func Seconds(duration time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(duration.Seconds())))
+}
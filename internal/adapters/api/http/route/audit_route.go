@@ -0,0 +1,26 @@
+package route
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"starter/internal/adapters/api/http/handler"
+	"starter/internal/adapters/api/http/middleware"
+)
+
+type AuditRoutes struct {
+	auditHandler *handler.AuditHandler
+}
+
+func NewAuditRoutes(auditHandler *handler.AuditHandler) *AuditRoutes {
+	return &AuditRoutes{
+		auditHandler: auditHandler,
+	}
+}
+
+func (r *AuditRoutes) InstallRoutes(app fiber.Router) {
+	auditGroup := app.Group("/audit",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("admin"),
+	)
+
+	auditGroup.Get("/", r.auditHandler.List)
+}
@@ -8,19 +8,23 @@ import (
 
 type AuthRoutes struct {
 	authHandler *handler.AuthHandler
+	idempotency fiber.Handler
+	rateLimit   []fiber.Handler
 }
 
-func NewAuthRoutes(authHandler *handler.AuthHandler) *AuthRoutes {
+func NewAuthRoutes(authHandler *handler.AuthHandler, idempotency fiber.Handler, rateLimit ...fiber.Handler) *AuthRoutes {
 	return &AuthRoutes{
 		authHandler: authHandler,
+		idempotency: idempotency,
+		rateLimit:   rateLimit,
 	}
 }
 
 func (r *AuthRoutes) InstallRoutes(app fiber.Router) {
 	authGroup := app.Group("/auth")
 
-	authGroup.Post("/login", r.authHandler.Login)
-	authGroup.Post("/register", r.authHandler.Register)
+	authGroup.Post("/login", r.limited(r.authHandler.Login)...)
+	authGroup.Post("/register", r.limited(r.idempotency, r.authHandler.Register)...)
 
 	authGroup.Get("/current",
 		middleware.JWTMiddleware(),
@@ -28,3 +32,8 @@ func (r *AuthRoutes) InstallRoutes(app fiber.Router) {
 		r.authHandler.Current,
 	)
 }
+
+// limited puts the rate limits in front of handlers.
+func (r *AuthRoutes) limited(handlers ...fiber.Handler) []fiber.Handler {
+	return append(append([]fiber.Handler{}, r.rateLimit...), handlers...)
+}
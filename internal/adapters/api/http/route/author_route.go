@@ -8,11 +8,13 @@ import (
 
 type AuthorRoutes struct {
 	authorHandler *handler.AuthorHandler
+	idempotency   fiber.Handler
 }
 
-func NewAuthorRoutes(authorHandler *handler.AuthorHandler) *AuthorRoutes {
+func NewAuthorRoutes(authorHandler *handler.AuthorHandler, idempotency fiber.Handler) *AuthorRoutes {
 	return &AuthorRoutes{
 		authorHandler: authorHandler,
+		idempotency:   idempotency,
 	}
 }
 
@@ -22,9 +24,12 @@ func (r *AuthorRoutes) InstallRoutes(app fiber.Router) {
 		middleware.RoleMiddleware("admin", "user"),
 	)
 
-	authorGroup.Post("/", r.authorHandler.Create)
+	authorGroup.Post("/", r.idempotency, r.authorHandler.Create)
 	authorGroup.Get("/", r.authorHandler.List)
+	authorGroup.Get("/duplicates", middleware.RoleMiddleware("admin"), r.authorHandler.Duplicates)
 	authorGroup.Get("/:id", r.authorHandler.GetByID)
 	authorGroup.Put("/:id", r.authorHandler.Update)
+	authorGroup.Patch("/:id", r.authorHandler.Patch)
 	authorGroup.Delete("/:id", r.authorHandler.Delete)
+	authorGroup.Post("/:id/merge", middleware.RoleMiddleware("admin"), r.authorHandler.Merge)
 }
@@ -8,11 +8,13 @@ import (
 
 type BookRoutes struct {
 	bookHandler *handler.BookHandler
+	idempotency fiber.Handler
 }
 
-func NewBookRoutes(bookHandler *handler.BookHandler) *BookRoutes {
+func NewBookRoutes(bookHandler *handler.BookHandler, idempotency fiber.Handler) *BookRoutes {
 	return &BookRoutes{
 		bookHandler: bookHandler,
+		idempotency: idempotency,
 	}
 }
 
@@ -22,9 +24,15 @@ func (r *BookRoutes) InstallRoutes(app fiber.Router) {
 		middleware.RoleMiddleware("admin", "user"),
 	)
 
-	bookGroup.Post("/", r.bookHandler.Create)
+	bookGroup.Post("/", r.idempotency, r.bookHandler.Create)
+	bookGroup.Post("/lookup", r.bookHandler.Lookup)
 	bookGroup.Get("/", r.bookHandler.List)
 	bookGroup.Get("/:id", r.bookHandler.GetByID)
 	bookGroup.Put("/:id", r.bookHandler.Update)
+	bookGroup.Patch("/:id", r.bookHandler.Patch)
 	bookGroup.Delete("/:id", r.bookHandler.Delete)
+	bookGroup.Get("/:id/revisions", r.bookHandler.Revisions)
+	bookGroup.Get("/:id/revisions/diff", r.bookHandler.DiffRevisions)
+	bookGroup.Get("/:id/revisions/:number", r.bookHandler.Revision)
+	bookGroup.Post("/:id/revisions/:number/revert", r.bookHandler.Revert)
 }
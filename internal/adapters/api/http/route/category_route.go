@@ -8,11 +8,13 @@ import (
 
 type CategoryRoutes struct {
 	categoryHandler *handler.CategoryHandler
+	idempotency     fiber.Handler
 }
 
-func NewCategoryRoutes(categoryHandler *handler.CategoryHandler) *CategoryRoutes {
+func NewCategoryRoutes(categoryHandler *handler.CategoryHandler, idempotency fiber.Handler) *CategoryRoutes {
 	return &CategoryRoutes{
 		categoryHandler: categoryHandler,
+		idempotency:     idempotency,
 	}
 }
 
@@ -22,9 +24,12 @@ func (r *CategoryRoutes) InstallRoutes(app fiber.Router) {
 		middleware.RoleMiddleware("admin", "user"),
 	)
 
-	categoryGroup.Post("/", r.categoryHandler.Create)
+	categoryGroup.Post("/", r.idempotency, r.categoryHandler.Create)
 	categoryGroup.Get("/", r.categoryHandler.List)
+	categoryGroup.Get("/tree", r.categoryHandler.Tree)
 	categoryGroup.Get("/:id", r.categoryHandler.GetByID)
 	categoryGroup.Put("/:id", r.categoryHandler.Update)
+	categoryGroup.Patch("/:id", r.categoryHandler.Patch)
 	categoryGroup.Delete("/:id", r.categoryHandler.Delete)
+	categoryGroup.Put("/:id/parent", r.categoryHandler.Move)
 }
@@ -0,0 +1,22 @@
+package route
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"starter/internal/adapters/api/http/handler"
+)
+
+type HealthRoutes struct {
+	healthHandler *handler.HealthHandler
+}
+
+func NewHealthRoutes(healthHandler *handler.HealthHandler) *HealthRoutes {
+	return &HealthRoutes{
+		healthHandler: healthHandler,
+	}
+}
+
+// InstallRoutes mounts the probes outside of the versioned API, without auth.
+func (r *HealthRoutes) InstallRoutes(app fiber.Router) {
+	app.Get("/healthz", r.healthHandler.Liveness)
+	app.Get("/readyz", r.healthHandler.Readiness)
+}
@@ -0,0 +1,17 @@
+package route
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"starter/internal/adapters/metrics"
+)
+
+type MetricsRoutes struct {
+}
+
+func NewMetricsRoutes() *MetricsRoutes {
+	return &MetricsRoutes{}
+}
+
+func (r *MetricsRoutes) InstallRoutes(app fiber.Router) {
+	app.Get("/metrics", metrics.Handler())
+}
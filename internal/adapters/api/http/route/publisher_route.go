@@ -8,11 +8,13 @@ import (
 
 type PublisherRoutes struct {
 	publisherHandler *handler.PublisherHandler
+	idempotency      fiber.Handler
 }
 
-func NewPublisherRoutes(publisherHandler *handler.PublisherHandler) *PublisherRoutes {
+func NewPublisherRoutes(publisherHandler *handler.PublisherHandler, idempotency fiber.Handler) *PublisherRoutes {
 	return &PublisherRoutes{
 		publisherHandler: publisherHandler,
+		idempotency:      idempotency,
 	}
 }
 
@@ -22,9 +24,12 @@ func (r *PublisherRoutes) InstallRoutes(app fiber.Router) {
 		middleware.RoleMiddleware("admin", "user"),
 	)
 
-	publisherGroup.Post("/", r.publisherHandler.Create)
+	publisherGroup.Post("/", r.idempotency, r.publisherHandler.Create)
 	publisherGroup.Get("/", r.publisherHandler.List)
+	publisherGroup.Get("/duplicates", middleware.RoleMiddleware("admin"), r.publisherHandler.Duplicates)
 	publisherGroup.Get("/:id", r.publisherHandler.GetByID)
 	publisherGroup.Put("/:id", r.publisherHandler.Update)
+	publisherGroup.Patch("/:id", r.publisherHandler.Patch)
 	publisherGroup.Delete("/:id", r.publisherHandler.Delete)
+	publisherGroup.Post("/:id/merge", middleware.RoleMiddleware("admin"), r.publisherHandler.Merge)
 }
@@ -0,0 +1,45 @@
+package route
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"starter/internal/adapters/api/http/handler"
+	"starter/internal/adapters/api/http/middleware"
+)
+
+type ReadingRoutes struct {
+	readingHandler *handler.ReadingHandler
+	idempotency    fiber.Handler
+}
+
+func NewReadingRoutes(readingHandler *handler.ReadingHandler, idempotency fiber.Handler) *ReadingRoutes {
+	return &ReadingRoutes{
+		readingHandler: readingHandler,
+		idempotency:    idempotency,
+	}
+}
+
+// InstallRoutes mounts the caller's reading progress under /me/reading, keyed
+// by book, and the statistics and yearly goals under /me/stats.
+func (r *ReadingRoutes) InstallRoutes(app fiber.Router) {
+	readingGroup := app.Group("/me/reading",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("admin", "user"),
+	)
+
+	readingGroup.Post("/", r.idempotency, r.readingHandler.Create)
+	readingGroup.Get("/", r.readingHandler.List)
+	readingGroup.Get("/:book_id", r.readingHandler.GetByBook)
+	readingGroup.Put("/:book_id", r.readingHandler.Update)
+	readingGroup.Delete("/:book_id", r.readingHandler.Delete)
+	readingGroup.Post("/:book_id/sessions", r.idempotency, r.readingHandler.CreateSession)
+
+	statsGroup := app.Group("/me/stats",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("admin", "user"),
+	)
+
+	statsGroup.Get("/", r.readingHandler.Stats)
+	statsGroup.Get("/goals", r.readingHandler.Goals)
+	statsGroup.Put("/goals/:year", r.readingHandler.SaveGoal)
+	statsGroup.Delete("/goals/:year", r.readingHandler.DeleteGoal)
+}
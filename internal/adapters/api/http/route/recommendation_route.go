@@ -0,0 +1,37 @@
+package route
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"starter/internal/adapters/api/http/handler"
+	"starter/internal/adapters/api/http/middleware"
+)
+
+type RecommendationRoutes struct {
+	recommendationHandler *handler.RecommendationHandler
+}
+
+func NewRecommendationRoutes(recommendationHandler *handler.RecommendationHandler) *RecommendationRoutes {
+	return &RecommendationRoutes{
+		recommendationHandler: recommendationHandler,
+	}
+}
+
+// InstallRoutes serves the precomputed similarities. They are refreshed by a
+// background job, admins can trigger a refresh by hand.
+func (r *RecommendationRoutes) InstallRoutes(app fiber.Router) {
+	app.Get("/books/:id/similar",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("admin", "user"),
+		r.recommendationHandler.Similar,
+	)
+	app.Get("/me/recommendations",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("admin", "user"),
+		r.recommendationHandler.Recommended,
+	)
+	app.Post("/recommendations/refresh",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("admin"),
+		r.recommendationHandler.Refresh,
+	)
+}
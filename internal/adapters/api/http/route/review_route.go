@@ -0,0 +1,46 @@
+package route
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"starter/internal/adapters/api/http/handler"
+	"starter/internal/adapters/api/http/middleware"
+)
+
+type ReviewRoutes struct {
+	reviewHandler *handler.ReviewHandler
+	idempotency   fiber.Handler
+}
+
+func NewReviewRoutes(reviewHandler *handler.ReviewHandler, idempotency fiber.Handler) *ReviewRoutes {
+	return &ReviewRoutes{
+		reviewHandler: reviewHandler,
+		idempotency:   idempotency,
+	}
+}
+
+// InstallRoutes mounts writing and listing under the book, as in
+// /books/:id/reviews, while a review is addressed on its own afterwards.
+func (r *ReviewRoutes) InstallRoutes(app fiber.Router) {
+	app.Get("/books/:id/reviews",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("admin", "user"),
+		r.reviewHandler.List,
+	)
+	app.Post("/books/:id/reviews",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("admin", "user"),
+		r.idempotency,
+		r.reviewHandler.Create,
+	)
+
+	reviewGroup := app.Group("/reviews",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("admin", "user"),
+	)
+
+	reviewGroup.Put("/:id", r.reviewHandler.Update)
+	reviewGroup.Delete("/:id", r.reviewHandler.Delete)
+	reviewGroup.Put("/:id/status", middleware.RoleMiddleware("admin"), r.reviewHandler.Moderate)
+	reviewGroup.Post("/:id/helpful", r.reviewHandler.Vote)
+	reviewGroup.Delete("/:id/helpful", r.reviewHandler.Unvote)
+}
@@ -0,0 +1,44 @@
+package route
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"starter/internal/adapters/api/http/handler"
+	"starter/internal/adapters/api/http/middleware"
+)
+
+type ShelfRoutes struct {
+	shelfHandler *handler.ShelfHandler
+	idempotency  fiber.Handler
+}
+
+func NewShelfRoutes(shelfHandler *handler.ShelfHandler, idempotency fiber.Handler) *ShelfRoutes {
+	return &ShelfRoutes{
+		shelfHandler: shelfHandler,
+		idempotency:  idempotency,
+	}
+}
+
+// InstallRoutes mounts the caller's own shelves under /me/shelves. Public
+// and shared shelves are readable without logging in, /shelves/shared/:token
+// is the link a shelf with link visibility hands out.
+func (r *ShelfRoutes) InstallRoutes(app fiber.Router) {
+	meGroup := app.Group("/me/shelves",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("admin", "user"),
+	)
+
+	meGroup.Post("/", r.idempotency, r.shelfHandler.Create)
+	meGroup.Get("/", r.shelfHandler.List)
+	meGroup.Get("/:id", r.shelfHandler.GetByID)
+	meGroup.Put("/:id", r.shelfHandler.Update)
+	meGroup.Delete("/:id", r.shelfHandler.Delete)
+	meGroup.Post("/:id/items", r.shelfHandler.AddItem)
+	meGroup.Put("/:id/items/:book_id", r.shelfHandler.UpdateItem)
+	meGroup.Delete("/:id/items/:book_id", r.shelfHandler.RemoveItem)
+
+	shelfGroup := app.Group("/shelves")
+
+	shelfGroup.Get("/", r.shelfHandler.Public)
+	shelfGroup.Get("/shared/:token", r.shelfHandler.Shared)
+	shelfGroup.Get("/:id", r.shelfHandler.PublicByID)
+}
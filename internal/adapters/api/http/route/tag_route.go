@@ -0,0 +1,33 @@
+package route
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"starter/internal/adapters/api/http/handler"
+	"starter/internal/adapters/api/http/middleware"
+)
+
+type TagRoutes struct {
+	tagHandler  *handler.TagHandler
+	idempotency fiber.Handler
+}
+
+func NewTagRoutes(tagHandler *handler.TagHandler, idempotency fiber.Handler) *TagRoutes {
+	return &TagRoutes{
+		tagHandler:  tagHandler,
+		idempotency: idempotency,
+	}
+}
+
+// InstallRoutes lets everyone look tags up. Only admins add to the vocabulary
+// by hand or take tags out of it, free-form tags come in through books.
+func (r *TagRoutes) InstallRoutes(app fiber.Router) {
+	tagGroup := app.Group("/tags",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("admin", "user"),
+	)
+
+	tagGroup.Get("/", r.tagHandler.Search)
+	tagGroup.Get("/cloud", r.tagHandler.Cloud)
+	tagGroup.Post("/", middleware.RoleMiddleware("admin"), r.idempotency, r.tagHandler.Create)
+	tagGroup.Delete("/:id", middleware.RoleMiddleware("admin"), r.tagHandler.Delete)
+}
@@ -0,0 +1,35 @@
+package route
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"starter/internal/adapters/api/http/handler"
+	"starter/internal/adapters/api/http/middleware"
+)
+
+type TrashRoutes struct {
+	trashHandler *handler.TrashHandler
+}
+
+func NewTrashRoutes(trashHandler *handler.TrashHandler) *TrashRoutes {
+	return &TrashRoutes{
+		trashHandler: trashHandler,
+	}
+}
+
+// InstallRoutes mounts restore next to the entity itself, as in
+// POST /books/:id/restore, while listing and purging live under /trash.
+func (r *TrashRoutes) InstallRoutes(app fiber.Router) {
+	trashGroup := app.Group("/trash",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("admin", "user"),
+	)
+
+	trashGroup.Get("/:entity", r.trashHandler.List)
+	trashGroup.Delete("/:entity/:id", middleware.RoleMiddleware("admin"), r.trashHandler.Purge)
+
+	app.Post("/:entity/:id/restore",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("admin", "user"),
+		r.trashHandler.Restore,
+	)
+}
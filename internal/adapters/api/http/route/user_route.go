@@ -8,11 +8,13 @@ import (
 
 type UserRoutes struct {
 	userHandler *handler.UserHandler
+	idempotency fiber.Handler
 }
 
-func NewUserRoutes(userHandler *handler.UserHandler) *UserRoutes {
+func NewUserRoutes(userHandler *handler.UserHandler, idempotency fiber.Handler) *UserRoutes {
 	return &UserRoutes{
 		userHandler: userHandler,
+		idempotency: idempotency,
 	}
 }
 
@@ -22,10 +24,11 @@ func (r *UserRoutes) InstallRoutes(app fiber.Router) {
 		middleware.RoleMiddleware("admin"),
 	)
 
-	userGroup.Post("/", r.userHandler.Create)
+	userGroup.Post("/", r.idempotency, r.userHandler.Create)
 	userGroup.Get("/", r.userHandler.List)
 	userGroup.Get("/:id", r.userHandler.GetByID)
 	userGroup.Get("/:email", r.userHandler.GetByEmail)
 	userGroup.Put("/:id", r.userHandler.Update)
+	userGroup.Patch("/:id", r.userHandler.Patch)
 	userGroup.Delete("/:id", r.userHandler.Delete)
 }
@@ -37,3 +37,11 @@ func ValidationResponse(data ivalidator.ValidationErrors) WebResponse[any] {
 		Data:    data.Errors,
 	}
 }
+
+// ErrorDataResponse is an ErrorResponse carrying details the client can act
+// on, such as how many rows block a delete.
+func ErrorDataResponse(message string, data any) WebResponse[any] {
+	response := ErrorResponse(message)
+	response.Data = data
+	return response
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"starter/internal/core/audit"
+	"starter/internal/core/filter"
+	"starter/internal/core/pagination"
+)
+
+type AuditRepository struct {
+}
+
+func NewAuditRepository() audit.Repository {
+	return &AuditRepository{}
+}
+
+func (repository *AuditRepository) Save(db *gorm.DB, entry *audit.Entry) error {
+	result := db.Create(entry)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to save audit entry")
+		return result.Error
+	}
+
+	return nil
+}
+
+func (repository *AuditRepository) FindAll(db *gorm.DB, params pagination.Request, filter filter.AuditFilter) ([]audit.Entry, pagination.Meta, error) {
+	var entries []audit.Entry
+
+	query := db.Model(&audit.Entry{})
+	if filter.ActorID != 0 {
+		query = query.Where("audit_entries.actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("audit_entries.action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("audit_entries.entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("audit_entries.entity_id = ?", filter.EntityID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("audit_entries.request_id = ?", filter.RequestID)
+	}
+	if filter.StartDate != "" {
+		query = query.Where("audit_entries.created_at >= ?::date", filter.StartDate)
+	}
+	if filter.EndDate != "" {
+		query = query.Where("audit_entries.created_at < ?::date + 1", filter.EndDate)
+	}
+
+	meta, err := paginate(query, "audit_entries", params, &entries)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to find all audit entries")
+	}
+
+	return entries, meta, err
+}
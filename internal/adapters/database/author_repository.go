@@ -1,11 +1,13 @@
 package database
 
 import (
-	"fmt"
+	"errors"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 	"starter/internal/core/author"
+	"starter/internal/core/merge"
 	"starter/internal/core/pagination"
+	"starter/internal/core/projection"
 )
 
 type AuthorRepository struct {
@@ -16,9 +18,10 @@ func NewAuthorRepository() author.Repository {
 }
 
 func (repository *AuthorRepository) Save(db *gorm.DB, author *author.Author) error {
+	author.SetKey()
 	result := db.Create(author)
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
 			Msgf("Failed to save author")
 		return result.Error
@@ -28,56 +31,94 @@ func (repository *AuthorRepository) Save(db *gorm.DB, author *author.Author) err
 }
 
 func (repository *AuthorRepository) Update(db *gorm.DB, author *author.Author) error {
-	result := db.Updates(author)
-	if result.Error != nil {
-		log.Error().
-			Err(result.Error).
+	author.SetKey()
+	err := updateVersioned(db, author, &author.Version)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
 			Msgf("Failed to save author")
-		return result.Error
+		return err
 	}
 
 	return nil
 }
 
-func (repository *AuthorRepository) Delete(db *gorm.DB, id int) error {
-	result := db.Delete(&author.Author{}, id)
-	if result.Error != nil {
-		log.Error().
-			Err(result.Error).
+func (repository *AuthorRepository) Patch(db *gorm.DB, author *author.Author, columns []string) error {
+	author.SetKey()
+	err := patchVersioned(db, author, &author.Version, keyed(columns, "name_key", "first_name", "last_name"))
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to patch author")
+		return err
+	}
+
+	return nil
+}
+
+func (repository *AuthorRepository) Delete(db *gorm.DB, id int, version uint) error {
+	err := deleteVersioned(db, &author.Author{}, id, version)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
 			Msgf("Failed to delete author")
-		return result.Error
+		return err
 	}
 
 	return nil
 }
 
-func (repository *AuthorRepository) FindAll(db *gorm.DB, params pagination.Request) ([]author.Author, int64, error) {
-	var authors []author.Author
-	var count int64
+func (repository *AuthorRepository) CountBooks(db *gorm.DB, id int) (int64, error) {
+	count, err := countBooks(db, "author_id", id)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to count books of author")
+	}
 
-	db.Model(&authors).Count(&count)
+	return count, err
+}
 
-	result := db.
-		Debug().
-		Order(fmt.Sprintf("%s %s", params.OrderBy, params.SortBy)).
-		Limit(params.Limit).
-		Offset((params.Page - 1) * params.Limit).
-		Find(&authors)
+func (repository *AuthorRepository) DeleteBooks(db *gorm.DB, id int) ([]uint, error) {
+	books, err := deleteBooks(db, "author_id", id)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to delete books of author")
+	}
 
-	if result.Error != nil {
-		log.Error().
-			Err(result.Error).
+	return books, err
+}
+
+func (repository *AuthorRepository) ReassignBooks(db *gorm.DB, id int, target uint) ([]uint, error) {
+	books, err := reassignBooks(db, "author_id", id, target)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to reassign books of author")
+	}
+
+	return books, err
+}
+
+func (repository *AuthorRepository) FindAll(db *gorm.DB, params pagination.Request, view projection.Request) ([]author.Author, pagination.Meta, error) {
+	var authors []author.Author
+
+	meta, err := paginate(project(db.Model(&author.Author{}), view), "authors", params, &authors)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
 			Msgf("Failed to find all authors")
 	}
 
-	return authors, count, result.Error
+	return authors, meta, err
 }
 
-func (repository *AuthorRepository) FindByID(db *gorm.DB, id int) (author.Author, error) {
+func (repository *AuthorRepository) FindByID(db *gorm.DB, id int, view projection.Request) (author.Author, error) {
 	var author author.Author
-	result := db.First(&author, id)
+	result := project(db, view).First(&author, id)
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
 			Msgf("Failed to find author")
 		return author, result.Error
@@ -85,3 +126,26 @@ func (repository *AuthorRepository) FindByID(db *gorm.DB, id int) (author.Author
 
 	return author, nil
 }
+
+func (repository *AuthorRepository) Duplicates(db *gorm.DB, request merge.DuplicatesRequest) ([]merge.Candidate, error) {
+	candidates, err := findDuplicates(db, "authors", nameKey, "concat_ws(' ', %[1]s.first_name, %[1]s.last_name)", request)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to find duplicate authors")
+	}
+
+	return candidates, err
+}
+
+func (repository *AuthorRepository) FindByKey(db *gorm.DB, key string) (author.Author, error) {
+	var author author.Author
+	err := db.Where("name_key = ?", key).Order("id").First(&author).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to find author by key")
+	}
+
+	return author, err
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// The helpers below act on the books referencing an author or publisher
+// through column, for the delete rules of those repositories. Only active
+// books count, the trash has its own checks.
+
+func countBooks(db *gorm.DB, column string, id int) (int64, error) {
+	var count int64
+	result := db.Table("books").
+		Where(fmt.Sprintf("%s = ? AND deleted_at IS NULL", column), id).
+		Count(&count)
+
+	return count, result.Error
+}
+
+func deleteBooks(db *gorm.DB, column string, id int) ([]uint, error) {
+	var ids []uint
+	result := db.Raw(fmt.Sprintf(`
+		UPDATE books SET deleted_at = now(), version = version + 1, updated_at = now()
+		WHERE %s = ? AND deleted_at IS NULL
+		RETURNING id`, column), id).
+		Scan(&ids)
+
+	return ids, result.Error
+}
+
+func reassignBooks(db *gorm.DB, column string, id int, target uint) ([]uint, error) {
+	var ids []uint
+	result := db.Raw(fmt.Sprintf(`
+		UPDATE books SET %[1]s = ?, version = version + 1, updated_at = now()
+		WHERE %[1]s = ? AND deleted_at IS NULL
+		RETURNING id`, column), target, id).
+		Scan(&ids)
+
+	return ids, result.Error
+}
@@ -1,14 +1,22 @@
 package database
 
 import (
-	"fmt"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"slices"
 	"starter/internal/core/book"
 	"starter/internal/core/filter"
 	"starter/internal/core/pagination"
+	"starter/internal/core/projection"
+	"starter/internal/core/tag"
+	"starter/pkg/helper"
 )
 
+// bookAssociations are the many2many links of a book that writes replace as
+// a whole, by the names PatchableFields maps them to.
+var bookAssociations = []string{"Categories", "Tags", "Subjects", "Localizations"}
+
 type BookRepository struct {
 }
 
@@ -17,9 +25,10 @@ func NewBookRepository() book.Repository {
 }
 
 func (repository *BookRepository) Save(db *gorm.DB, book *book.Book) error {
+	book.SetKey()
 	result := db.Create(book)
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
 			Msgf("Failed to save book")
 		return result.Error
@@ -27,55 +36,130 @@ func (repository *BookRepository) Save(db *gorm.DB, book *book.Book) error {
 	result = db.
 		Preload("Author").
 		Preload("Categories").
+		Preload("Tags").
+		Preload("Subjects").
+		Preload("Localizations").
 		First(&book, book.ID)
 
 	return nil
 }
 
+// Update writes book and replaces its tags, subjects and localizations with
+// the ones it carries, categories keep being added to as they always were.
 func (repository *BookRepository) Update(db *gorm.DB, book *book.Book) error {
-	result := db.Debug().Updates(book)
-	if result.Error != nil {
-		log.Error().
-			Err(result.Error).
+	book.SetKey()
+	err := updateVersioned(db.Omit("Tags", "Subjects", "Localizations"), book, &book.Version)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
 			Msgf("Failed to save book")
-		return result.Error
+		return err
+	}
+
+	return replaceAssociations(db, book, []string{"Tags", "Subjects", "Localizations"})
+}
+
+func (repository *BookRepository) Patch(db *gorm.DB, book *book.Book, columns []string) error {
+	var fields, associations []string
+	for _, column := range columns {
+		if slices.Contains(bookAssociations, column) {
+			associations = append(associations, column)
+			continue
+		}
+		fields = append(fields, column)
+	}
+
+	book.SetKey()
+	err := patchVersioned(db, book, &book.Version, keyed(fields, "title_key", "title"))
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to patch book")
+		return err
+	}
+
+	return replaceAssociations(db, book, associations)
+}
+
+func replaceAssociations(db *gorm.DB, book *book.Book, associations []string) error {
+	values := map[string]any{
+		"Categories": book.Categories,
+		"Tags":       book.Tags,
+		"Subjects":   book.Subjects,
+	}
+	for _, association := range associations {
+		if association == "Localizations" {
+			if err := replaceLocalizations(db, book); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := db.Model(book).Association(association).Replace(values[association])
+		if err != nil {
+			log.Ctx(db.Statement.Context).Error().
+				Err(err).
+				Msgf("Failed to replace book %s", association)
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (repository *BookRepository) Delete(db *gorm.DB, id int) error {
-	result := db.Delete(&book.Book{}, id)
+// replaceLocalizations swaps the stored variants for the ones of book. The
+// old rows go first so that a locale can be written again.
+func replaceLocalizations(db *gorm.DB, entity *book.Book) error {
+	result := db.Where("book_id = ?", entity.ID).Delete(&book.Localization{})
+	if result.Error == nil && len(entity.Localizations) > 0 {
+		for i := range entity.Localizations {
+			entity.Localizations[i].ID = 0
+			entity.Localizations[i].BookID = entity.ID
+		}
+		result = db.Create(&entity.Localizations)
+	}
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
+			Msgf("Failed to replace book localizations")
+	}
+
+	return result.Error
+}
+
+func (repository *BookRepository) Delete(db *gorm.DB, id int, version uint) error {
+	err := deleteVersioned(db, &book.Book{}, id, version)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
 			Msgf("Failed to delete book")
-		return result.Error
+		return err
 	}
 
 	return nil
 }
 
-func (repository *BookRepository) FindAll(db *gorm.DB, params pagination.Request, filter filter.BookFilter) ([]book.Book, int64, error) {
+func (repository *BookRepository) FindAll(db *gorm.DB, params pagination.Request, filter filter.BookFilter, view projection.Request) ([]book.Book, pagination.Meta, error) {
 	var books []book.Book
-	var count int64
 
-	query := db.
-		Model(&book.Book{}).
-		Joins("LEFT JOIN authors ON authors.id = books.author_id").
-		Joins("LEFT JOIN publishers ON publishers.id = books.publisher_id").
-		Preload("Author").
-		Preload("Publisher").
-		Preload("Categories")
+	query := project(db.Model(&book.Book{}), view, "Author", "Publisher", "Categories", "Tags", "Subjects", "Localizations")
+
+	// The joins only serve search and sorting, the associations in the
+	// response come from the preloads above.
+	if filter.Search != "" || sortsOn(params.Sorts, "authors") {
+		query = query.Joins("LEFT JOIN authors ON authors.id = books.author_id")
+	}
+	if filter.Search != "" || sortsOn(params.Sorts, "publishers") {
+		query = query.Joins("LEFT JOIN publishers ON publishers.id = books.publisher_id")
+	}
 
 	if filter.Search != "" {
-		search := "%" + filter.Search + "%"
+		search := "%" + helper.Fold(filter.Search) + "%"
 		query = query.Where(`
-		books.title ILIKE ? OR
-		authors.first_name ILIKE ? OR
-		authors.last_name ILIKE ? OR
-		publishers.name ILIKE ?
-	`, search, search, search, search)
+		books.title_key LIKE ? OR
+		authors.name_key LIKE ? OR
+		publishers.name_key LIKE ?
+	`, search, search, search)
 	}
 
 	if filter.StartDate != "" {
@@ -91,37 +175,57 @@ func (repository *BookRepository) FindAll(db *gorm.DB, params pagination.Request
 		query = query.Where("books.created_at <= ?", filter.EndDate)
 	}
 
+	// A subquery rather than a join, so books in several of the categories
+	// are listed once.
 	if len(filter.Categories) > 0 {
-		query = query.
-			Joins("JOIN book_category bc ON bc.book_id = books.id").
-			Where("bc.category_id IN ?", filter.Categories)
+		linked := db.Table("book_category").
+			Select("book_id").
+			Where("category_id IN ?", filter.Categories)
+		if filter.Descendants {
+			linked = linked.Or("category_id IN (?)", db.Table("category_closure").
+				Select("descendant_id").
+				Where("ancestor_id IN ?", filter.Categories))
+		}
+		query = query.Where("books.id IN (?)", linked)
+	}
+	// A language matches its regional variants as well, en finds en-GB.
+	if filter.Language != "" {
+		query = query.Where("books.language = ? OR books.language LIKE ?", filter.Language, filter.Language+"-%")
+	}
+	if len(filter.Tags) > 0 {
+		query = query.Where("books.id IN (?)", tagged(db, "book_tag", filter.Tags, filter.TagMatch))
+	}
+	if len(filter.Subjects) > 0 {
+		query = query.Where("books.id IN (?)", tagged(db, "book_subject", filter.Subjects, filter.TagMatch))
 	}
 
-	query.Count(&count)
+	meta, err := paginate(query, "books", params, &books)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().Err(err).Msg("Failed to find all books")
+	}
 
-	result := query.
-		Debug().
-		Order(fmt.Sprintf("%s %s", params.OrderBy, params.SortBy)).
-		Limit(params.Limit).
-		Offset((params.Page - 1) * params.Limit).
-		Find(&books)
+	return books, meta, err
+}
 
-	if result.Error != nil {
-		log.Error().Err(result.Error).Msg("Failed to find all books")
+// tagged selects the books linked through table to the tags with slugs: to
+// every one of them when match is all, to any of them otherwise.
+func tagged(db *gorm.DB, table string, slugs []string, match string) *gorm.DB {
+	query := db.Table(table).
+		Select(table+".book_id").
+		Joins("JOIN tags ON tags.id = "+table+".tag_id").
+		Where("tags.slug IN ?", slugs)
+	if match == filter.MatchAll {
+		query = query.Group(table+".book_id").Having("count(DISTINCT tags.id) = ?", len(slugs))
 	}
-
-	return books, count, result.Error
+	return query
 }
 
-func (repository *BookRepository) FindByID(db *gorm.DB, id int) (book.Book, error) {
+func (repository *BookRepository) FindByID(db *gorm.DB, id int, view projection.Request) (book.Book, error) {
 	var book book.Book
-	result := db.
-		Preload("Author").
-		Preload("Publisher").
-		Preload("Categories").
+	result := project(db, view, "Author", "Publisher", "Categories", "Tags", "Subjects", "Localizations").
 		First(&book, id)
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
 			Msgf("Failed to find book")
 		return book, result.Error
@@ -129,3 +233,180 @@ func (repository *BookRepository) FindByID(db *gorm.DB, id int) (book.Book, erro
 
 	return book, nil
 }
+
+// FindShelves groups the live shelves of userID by which of the books in ids
+// they hold.
+func (repository *BookRepository) FindShelves(db *gorm.DB, userID uint, ids []uint) (map[uint][]book.ShelfResponse, error) {
+	var rows []struct {
+		BookID uint
+		book.ShelfResponse
+	}
+	result := db.Table("shelf_items").
+		Select("shelf_items.book_id, shelves.id, shelves.name, shelves.kind").
+		Joins("JOIN shelves ON shelves.id = shelf_items.shelf_id AND shelves.deleted_at IS NULL").
+		Where("shelves.user_id = ? AND shelf_items.book_id IN ?", userID, ids).
+		Order("shelves.id").
+		Scan(&rows)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find shelves of books")
+		return nil, result.Error
+	}
+
+	shelves := make(map[uint][]book.ShelfResponse)
+	for _, row := range rows {
+		shelves[row.BookID] = append(shelves[row.BookID], row.ShelfResponse)
+	}
+	return shelves, nil
+}
+
+// FindLocalizations groups the variants of the books in ids by book.
+func (repository *BookRepository) FindLocalizations(db *gorm.DB, ids []uint) (map[uint][]book.Localization, error) {
+	var rows []book.Localization
+	result := db.Where("book_id IN ?", ids).
+		Order("book_id, locale").
+		Find(&rows)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find localizations of books")
+		return nil, result.Error
+	}
+
+	localizations := make(map[uint][]book.Localization)
+	for _, row := range rows {
+		localizations[row.BookID] = append(localizations[row.BookID], row)
+	}
+	return localizations, nil
+}
+
+// FindTranslations groups the live books translated from the books in ids by
+// the book they were translated from.
+func (repository *BookRepository) FindTranslations(db *gorm.DB, ids []uint) (map[uint][]book.TranslationResponse, error) {
+	var rows []struct {
+		OriginalID uint
+		book.TranslationResponse
+	}
+	result := db.Model(&book.Book{}).
+		Select("books.original_id, books.id, books.title, books.language").
+		Where("books.original_id IN ?", ids).
+		Order("books.language, books.id").
+		Scan(&rows)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find translations of books")
+		return nil, result.Error
+	}
+
+	translations := make(map[uint][]book.TranslationResponse)
+	for _, row := range rows {
+		translations[row.OriginalID] = append(translations[row.OriginalID], row.TranslationResponse)
+	}
+	return translations, nil
+}
+
+func (repository *BookRepository) CountTranslations(db *gorm.DB, id uint) (int64, error) {
+	var count int64
+	result := db.Model(&book.Book{}).
+		Where("original_id = ?", id).
+		Count(&count)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to count translations of book")
+	}
+
+	return count, result.Error
+}
+
+// SaveTags stores the tags that don't exist yet and leaves the others alone.
+func (repository *BookRepository) SaveTags(db *gorm.DB, tags []tag.Tag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to save tags")
+	}
+
+	return result.Error
+}
+
+func (repository *BookRepository) FindTags(db *gorm.DB, kind string, slugs []string) ([]tag.Tag, error) {
+	tags := []tag.Tag{}
+	if len(slugs) == 0 {
+		return tags, nil
+	}
+
+	result := db.Where("kind = ? AND slug IN ?", kind, slugs).
+		Order("slug").
+		Find(&tags)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find tags")
+	}
+
+	return tags, result.Error
+}
+
+func (repository *BookRepository) LastRevisionNumber(db *gorm.DB, id uint) (uint, error) {
+	var number uint
+	result := db.Model(&book.Revision{}).
+		Select("coalesce(max(number), 0)").
+		Where("book_id = ?", id).
+		Scan(&number)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find last book revision")
+		return 0, result.Error
+	}
+
+	return number, nil
+}
+
+func (repository *BookRepository) SaveRevision(db *gorm.DB, revision *book.Revision) error {
+	result := db.Create(revision)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to save book revision")
+		return result.Error
+	}
+
+	return nil
+}
+
+func (repository *BookRepository) FindRevisions(db *gorm.DB, id int, params pagination.Request) ([]book.Revision, pagination.Meta, error) {
+	var revisions []book.Revision
+
+	query := db.Model(&book.Revision{}).Where("book_revisions.book_id = ?", id)
+	meta, err := paginate(query, "book_revisions", params, &revisions)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to find book revisions")
+	}
+
+	return revisions, meta, err
+}
+
+func (repository *BookRepository) FindRevision(db *gorm.DB, id int, number int) (book.Revision, error) {
+	var revision book.Revision
+	result := db.Where("book_id = ? AND number = ?", id, number).
+		Take(&revision)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find book revision")
+		return revision, result.Error
+	}
+
+	return revision, nil
+}
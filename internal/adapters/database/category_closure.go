@@ -0,0 +1,22 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// migrateCategoryClosure adds the closure rows missing for categories written
+// without them, such as those created before the tree existed or inserted by
+// the seeders. parent_id is the source of truth, existing rows are kept.
+func migrateCategoryClosure(db *gorm.DB) error {
+	return db.Exec(`
+		WITH RECURSIVE paths AS (
+			SELECT id AS ancestor_id, id AS descendant_id, 0 AS depth, parent_id FROM categories
+			UNION ALL
+			SELECT categories.id, paths.descendant_id, paths.depth + 1, categories.parent_id
+			FROM paths
+			JOIN categories ON categories.id = paths.parent_id
+		)
+		INSERT INTO category_closure (ancestor_id, descendant_id, depth)
+		SELECT ancestor_id, descendant_id, depth FROM paths
+		ON CONFLICT DO NOTHING`).Error
+}
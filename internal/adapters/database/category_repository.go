@@ -1,11 +1,11 @@
 package database
 
 import (
-	"fmt"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 	"starter/internal/core/category"
 	"starter/internal/core/pagination"
+	"starter/internal/core/projection"
 )
 
 type CategoryRepository struct {
@@ -16,9 +16,10 @@ func NewCategoryRepository() category.Repository {
 }
 
 func (repository *CategoryRepository) Save(db *gorm.DB, category *category.Category) error {
+	category.SetKey()
 	result := db.Create(category)
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
 			Msgf("Failed to save category")
 		return result.Error
@@ -28,56 +29,122 @@ func (repository *CategoryRepository) Save(db *gorm.DB, category *category.Categ
 }
 
 func (repository *CategoryRepository) Update(db *gorm.DB, category *category.Category) error {
-	result := db.Updates(category)
-	if result.Error != nil {
-		log.Error().
-			Err(result.Error).
+	category.SetKey()
+	err := updateVersioned(db, category, &category.Version)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
 			Msgf("Failed to save category")
-		return result.Error
+		return err
 	}
 
 	return nil
 }
 
-func (repository *CategoryRepository) Delete(db *gorm.DB, id int) error {
-	result := db.Delete(&category.Category{}, id)
-	if result.Error != nil {
-		log.Error().
-			Err(result.Error).
+func (repository *CategoryRepository) Patch(db *gorm.DB, category *category.Category, columns []string) error {
+	category.SetKey()
+	err := patchVersioned(db, category, &category.Version, keyed(columns, "name_key", "name"))
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to patch category")
+		return err
+	}
+
+	return nil
+}
+
+func (repository *CategoryRepository) Delete(db *gorm.DB, id int, version uint) error {
+	err := deleteVersioned(db, &category.Category{}, id, version)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
 			Msgf("Failed to delete category")
-		return result.Error
+		return err
 	}
 
 	return nil
 }
 
-func (repository *CategoryRepository) FindAll(db *gorm.DB, params pagination.Request) ([]category.Category, int64, error) {
-	var categories []category.Category
+func (repository *CategoryRepository) CountBooks(db *gorm.DB, id int) (int64, error) {
 	var count int64
+	result := db.Table("book_category").
+		Joins("JOIN books ON books.id = book_category.book_id AND books.deleted_at IS NULL").
+		Where("book_category.category_id = ?", id).
+		Count(&count)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to count books of category")
+	}
+
+	return count, result.Error
+}
 
-	db.Model(&categories).Count(&count)
+func (repository *CategoryRepository) UnlinkBooks(db *gorm.DB, id int) ([]uint, error) {
+	var books []uint
+	result := db.Raw("DELETE FROM book_category WHERE category_id = ? RETURNING book_id", id).
+		Scan(&books)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to unlink books of category")
+	}
 
-	result := db.
-		Debug().
-		Order(fmt.Sprintf("%s %s", params.OrderBy, params.SortBy)).
-		Limit(params.Limit).
-		Offset((params.Page - 1) * params.Limit).
-		Find(&categories)
+	return books, result.Error
+}
 
+// ReassignBooks moves the links of category id over to target, books already
+// in target simply lose the link to id.
+func (repository *CategoryRepository) ReassignBooks(db *gorm.DB, id int, target uint) ([]uint, error) {
+	result := db.Exec(`
+		INSERT INTO book_category (book_id, category_id)
+		SELECT book_id, ? FROM book_category WHERE category_id = ?
+		ON CONFLICT DO NOTHING`, target, id)
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
+			Msgf("Failed to reassign books of category")
+		return nil, result.Error
+	}
+
+	return repository.UnlinkBooks(db, id)
+}
+
+// NameTaken reports whether a live child of parent other than exclude goes
+// by key, a nil parent standing for the roots.
+func (repository *CategoryRepository) NameTaken(db *gorm.DB, parentID *uint, key string, exclude uint) (bool, error) {
+	var count int64
+	err := db.Model(&category.Category{}).
+		Where("parent_id IS NOT DISTINCT FROM ? AND name_key = ? AND id <> ?", parentID, key, exclude).
+		Count(&count).Error
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to look up category name")
+	}
+
+	return count > 0, err
+}
+
+func (repository *CategoryRepository) FindAll(db *gorm.DB, params pagination.Request, view projection.Request) ([]category.Category, pagination.Meta, error) {
+	var categories []category.Category
+
+	meta, err := paginate(project(db.Model(&category.Category{}), view), "categories", params, &categories)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
 			Msgf("Failed to find all categories")
 	}
 
-	return categories, count, result.Error
+	return categories, meta, err
 }
 
-func (repository *CategoryRepository) FindByID(db *gorm.DB, id int) (category.Category, error) {
+func (repository *CategoryRepository) FindByID(db *gorm.DB, id int, view projection.Request) (category.Category, error) {
 	var category category.Category
-	result := db.First(&category, id)
+	result := project(db, view).First(&category, id)
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
 			Msgf("Failed to find category")
 		return category, result.Error
@@ -85,3 +152,157 @@ func (repository *CategoryRepository) FindByID(db *gorm.DB, id int) (category.Ca
 
 	return category, nil
 }
+
+// Link gives category its closure rows: one to itself and one to every
+// ancestor of its parent.
+func (repository *CategoryRepository) Link(db *gorm.DB, category *category.Category) error {
+	result := db.Exec(`
+		INSERT INTO category_closure (ancestor_id, descendant_id, depth)
+		SELECT ancestor_id, @id::bigint, depth + 1 FROM category_closure WHERE descendant_id = @parent
+		UNION ALL
+		SELECT @id::bigint, @id::bigint, 0`,
+		map[string]any{"id": category.ID, "parent": category.ParentID},
+	)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to link category")
+	}
+
+	return result.Error
+}
+
+// Move detaches the subtree of category from its old ancestors and attaches
+// it below each ancestor of the new parent. Paths inside the subtree stay.
+func (repository *CategoryRepository) Move(db *gorm.DB, category *category.Category) error {
+	err := patchVersioned(db, category, &category.Version, []string{"parent_id"})
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to move category")
+		return err
+	}
+
+	result := db.Exec(`
+		DELETE FROM category_closure
+		WHERE descendant_id IN (SELECT descendant_id FROM category_closure WHERE ancestor_id = @id)
+			AND ancestor_id NOT IN (SELECT descendant_id FROM category_closure WHERE ancestor_id = @id)`,
+		map[string]any{"id": category.ID},
+	)
+	if result.Error == nil && category.ParentID != nil {
+		result = db.Exec(`
+			INSERT INTO category_closure (ancestor_id, descendant_id, depth)
+			SELECT supertree.ancestor_id, subtree.descendant_id, supertree.depth + subtree.depth + 1
+			FROM category_closure supertree
+			CROSS JOIN category_closure subtree
+			WHERE supertree.descendant_id = @parent AND subtree.ancestor_id = @id`,
+			map[string]any{"id": category.ID, "parent": *category.ParentID},
+		)
+	}
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to relink category subtree")
+	}
+
+	return result.Error
+}
+
+// LockTree serialises the writes that rewrite the closure table for the rest
+// of the transaction. Moves have to see each other's result before checking
+// for cycles, two opposite moves would otherwise both pass.
+func (repository *CategoryRepository) LockTree(db *gorm.DB) error {
+	err := db.Exec("SELECT pg_advisory_xact_lock(hashtext('category_closure'))").Error
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to lock the category tree")
+	}
+
+	return err
+}
+
+func (repository *CategoryRepository) IsDescendant(db *gorm.DB, id uint, ancestor uint) (bool, error) {
+	var count int64
+	result := db.Model(&category.Closure{}).
+		Where("ancestor_id = ? AND descendant_id = ?", ancestor, id).
+		Count(&count)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to check category ancestry")
+	}
+
+	return count > 0, result.Error
+}
+
+func (repository *CategoryRepository) CountChildren(db *gorm.DB, id int) (int64, error) {
+	var count int64
+	result := db.Model(&category.Category{}).
+		Where("parent_id = ?", id).
+		Count(&count)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to count children of category")
+	}
+
+	return count, result.Error
+}
+
+// FindTree loads the live categories below root, root included, or the whole
+// forest when root is zero. A positive depth stops that many levels down.
+func (repository *CategoryRepository) FindTree(db *gorm.DB, root uint, depth int) ([]category.Node, error) {
+	var nodes []category.Node
+
+	query := db.Model(&category.Category{})
+	if root != 0 {
+		query = query.
+			Select("categories.*, category_closure.depth").
+			Joins("JOIN category_closure ON category_closure.descendant_id = categories.id AND category_closure.ancestor_id = ?", root)
+	} else {
+		query = query.
+			Select("categories.*, category_closure.depth").
+			Joins("JOIN (SELECT descendant_id, max(depth) AS depth FROM category_closure GROUP BY descendant_id) category_closure ON category_closure.descendant_id = categories.id")
+	}
+	if depth > 0 {
+		query = query.Where("category_closure.depth <= ?", depth)
+	}
+
+	result := query.Order("category_closure.depth, categories.name, categories.id").Scan(&nodes)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find category tree")
+	}
+
+	return nodes, result.Error
+}
+
+// FindBreadcrumbs lists the live ancestors of every category in ids, from the
+// root down to the category itself.
+func (repository *CategoryRepository) FindBreadcrumbs(db *gorm.DB, ids []uint) (map[uint][]category.BreadcrumbResponse, error) {
+	var rows []struct {
+		DescendantID uint
+		category.BreadcrumbResponse
+	}
+	result := db.Table("category_closure").
+		Select("category_closure.descendant_id, categories.id, categories.name").
+		Joins("JOIN categories ON categories.id = category_closure.ancestor_id AND categories.deleted_at IS NULL").
+		Where("category_closure.descendant_id IN ?", ids).
+		Order("category_closure.descendant_id, category_closure.depth DESC").
+		Scan(&rows)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find breadcrumbs of categories")
+		return nil, result.Error
+	}
+
+	breadcrumbs := make(map[uint][]category.BreadcrumbResponse, len(ids))
+	for _, row := range rows {
+		breadcrumbs[row.DescendantID] = append(breadcrumbs[row.DescendantID], row.BreadcrumbResponse)
+	}
+
+	return breadcrumbs, nil
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"starter/internal/core/merge"
+)
+
+// trigramIndexes back the duplicate reports. The expressions have to match
+// the ones findDuplicates compares for the indexes to be used. The indexes
+// over the lowercased names came before the search keys and are dropped.
+var trigramIndexes = []string{
+	"DROP INDEX IF EXISTS idx_authors_name_trgm",
+	"DROP INDEX IF EXISTS idx_publishers_name_trgm",
+	"CREATE INDEX IF NOT EXISTS idx_authors_name_key_trgm ON authors USING gin ((" + nameKey("authors") + ") gin_trgm_ops)",
+	"CREATE INDEX IF NOT EXISTS idx_publishers_name_key_trgm ON publishers USING gin ((" + nameKey("publishers") + ") gin_trgm_ops)",
+}
+
+// nameKey is the search key column duplicates are compared by, for authors
+// and publishers alike.
+func nameKey(table string) string {
+	return fmt.Sprintf("%s.name_key", table)
+}
+
+func migrateTrigram(db *gorm.DB) error {
+	err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error
+	if err != nil {
+		return err
+	}
+	for _, index := range trigramIndexes {
+		if err = db.Exec(index).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findDuplicates pairs up the active rows of table whose name, as built by
+// name, has a trigram similarity of at least the requested threshold. The
+// threshold is set for the current transaction only so that the % operator
+// can use the trigram index.
+func findDuplicates(db *gorm.DB, table string, name func(string) string, label string, request merge.DuplicatesRequest) ([]merge.Candidate, error) {
+	var candidates []merge.Candidate
+
+	err := db.Exec("SELECT set_config('pg_trgm.similarity_threshold', ?, true)", fmt.Sprint(request.Threshold)).Error
+	if err != nil {
+		return nil, err
+	}
+
+	first, second := name("a"), name("b")
+	err = db.Raw(fmt.Sprintf(`
+		SELECT a.id AS first_id, %[2]s AS first_name,
+			b.id AS second_id, %[3]s AS second_name,
+			similarity(%[4]s, %[5]s) AS similarity
+		FROM %[1]s a
+		JOIN %[1]s b ON %[4]s %% %[5]s AND a.id < b.id
+		WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL
+		ORDER BY similarity DESC, a.id, b.id
+		LIMIT ?`,
+		table, fmt.Sprintf(label, "a"), fmt.Sprintf(label, "b"), first, second,
+	), request.Limit).Scan(&candidates).Error
+
+	return candidates, err
+}
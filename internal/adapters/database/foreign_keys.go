@@ -0,0 +1,62 @@
+package database
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+)
+
+type foreignKey struct {
+	Table      string
+	Name       string
+	Column     string
+	References string
+	OnDelete   string
+}
+
+// foreignKeys lists the constraints that were created with the default NO
+// ACTION rule before their entities got constraint tags. AutoMigrate only
+// creates constraints that are missing, so migrateForeignKeys replaces these
+// on databases migrated back then. Tables that had their tags from the start
+// are left to AutoMigrate and are not listed.
+var foreignKeys = []foreignKey{
+	{Table: "books", Name: "fk_books_author", Column: "author_id", References: "authors", OnDelete: "RESTRICT"},
+	{Table: "books", Name: "fk_books_publisher", Column: "publisher_id", References: "publishers", OnDelete: "RESTRICT"},
+	{Table: "book_category", Name: "fk_book_category_book", Column: "book_id", References: "books", OnDelete: "CASCADE"},
+	{Table: "book_category", Name: "fk_book_category_category", Column: "category_id", References: "categories", OnDelete: "CASCADE"},
+	{Table: "users", Name: "fk_users_role", Column: "role_id", References: "roles", OnDelete: "RESTRICT"},
+}
+
+// confdeltype values of pg_constraint.
+var onDeleteCodes = map[string]string{
+	"RESTRICT": "r",
+	"CASCADE":  "c",
+}
+
+func migrateForeignKeys(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, key := range foreignKeys {
+			var code string
+			err := tx.Raw(
+				"SELECT confdeltype FROM pg_constraint WHERE conname = ? AND conrelid = ?::regclass",
+				key.Name, key.Table,
+			).Scan(&code).Error
+			if err != nil {
+				return err
+			}
+			if code == onDeleteCodes[key.OnDelete] {
+				continue
+			}
+
+			err = tx.Exec(fmt.Sprintf(
+				`ALTER TABLE %[1]s DROP CONSTRAINT IF EXISTS %[2]s,
+				ADD CONSTRAINT %[2]s FOREIGN KEY (%[3]s) REFERENCES %[4]s (id)
+				ON UPDATE CASCADE ON DELETE %[5]s`,
+				key.Table, key.Name, key.Column, key.References, key.OnDelete,
+			)).Error
+			if err != nil {
+				return fmt.Errorf("replace %s: %w", key.Name, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,18 @@
+package database
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"starter/internal/core/health"
+)
+
+// NewPostgresChecker pings the connection pool behind db.
+func NewPostgresChecker(db *gorm.DB) health.Checker {
+	return health.CheckerFunc(func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"starter/internal/core/idempotency"
+	"time"
+)
+
+// expiredBatch is how many expired records a reservation purges at most, so
+// that the table drains without any one request paying for all of it.
+const expiredBatch = 100
+
+type IdempotencyStore struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyStore(db *gorm.DB) idempotency.Store {
+	return &IdempotencyStore{
+		db: db,
+	}
+}
+
+// Reserve inserts the record, taking over the row only when the one already
+// there has expired. Concurrent retries race on the primary key, so exactly one
+// of them gets to run the request. Every reservation purges a batch of
+// expired records as well, nothing else deletes them.
+func (store *IdempotencyStore) Reserve(ctx context.Context, record idempotency.Record) (*idempotency.Record, error) {
+	result := store.db.WithContext(ctx).
+		Where("key IN (?)", store.db.Model(&idempotency.Record{}).
+			Select("key").
+			Where("expires_at < ?", time.Now()).
+			Limit(expiredBatch)).
+		Delete(&idempotency.Record{})
+	if result.Error != nil {
+		log.Ctx(ctx).Error().
+			Err(result.Error).
+			Msgf("Failed to purge expired idempotency keys")
+		return nil, result.Error
+	}
+
+	result = store.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"fingerprint", "completed", "status_code", "content_type", "body", "expires_at", "created_at",
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Lt{Column: clause.Column{Table: "idempotency_records", Name: "expires_at"}, Value: time.Now()},
+		}},
+	}).Create(&record)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().
+			Err(result.Error).
+			Msgf("Failed to reserve idempotency key")
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil, nil
+	}
+
+	var existing idempotency.Record
+	result = store.db.WithContext(ctx).Where("key = ?", record.Key).First(&existing)
+	if result.Error != nil {
+		log.Ctx(ctx).Error().
+			Err(result.Error).
+			Msgf("Failed to find idempotency key")
+		return nil, result.Error
+	}
+
+	return &existing, nil
+}
+
+func (store *IdempotencyStore) Complete(ctx context.Context, record idempotency.Record) error {
+	result := store.db.WithContext(ctx).
+		Model(&idempotency.Record{}).
+		Where("key = ?", record.Key).
+		Updates(map[string]any{
+			"completed":    true,
+			"status_code":  record.StatusCode,
+			"content_type": record.ContentType,
+			"body":         record.Body,
+		})
+	if result.Error != nil {
+		log.Ctx(ctx).Error().
+			Err(result.Error).
+			Msgf("Failed to complete idempotency key")
+		return result.Error
+	}
+
+	return nil
+}
+
+func (store *IdempotencyStore) Release(ctx context.Context, key string) error {
+	result := store.db.WithContext(ctx).Where("key = ?", key).Delete(&idempotency.Record{})
+	if result.Error != nil {
+		log.Ctx(ctx).Error().
+			Err(result.Error).
+			Msgf("Failed to release idempotency key")
+		return result.Error
+	}
+
+	return nil
+}
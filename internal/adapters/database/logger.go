@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"time"
+)
+
+const slowQueryThreshold = 200 * time.Millisecond
+
+// gormLogger sends gorm's output through the logger of the query context, so
+// SQL lines carry the request they belong to. Statements are only written at
+// debug level, slow ones as warnings and failed ones as errors.
+type gormLogger struct {
+}
+
+func newGormLogger() logger.Interface {
+	return &gormLogger{}
+}
+
+// LogMode is a no-op, verbosity follows the zerolog level.
+func (l *gormLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...any) {
+	log.Ctx(ctx).Info().Msgf(msg, args...)
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...any) {
+	log.Ctx(ctx).Warn().Msgf(msg, args...)
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...any) {
+	log.Ctx(ctx).Error().Msgf(msg, args...)
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	logger := log.Ctx(ctx)
+
+	event := logger.Debug()
+	message := "query"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		event = logger.Error().Err(err)
+		message = "query failed"
+	case elapsed > slowQueryThreshold:
+		event = logger.Warn()
+		message = "slow query"
+	}
+	if !event.Enabled() {
+		return
+	}
+
+	sql, rows := fc()
+	event.
+		Str("sql", sql).
+		Int64("rows", rows).
+		Dur("elapsed", elapsed).
+		Msg(message)
+}
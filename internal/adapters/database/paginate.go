@@ -0,0 +1,236 @@
+package database
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"reflect"
+	"starter/internal/core/pagination"
+	"strings"
+)
+
+// paginate orders query by params.Sorts and loads a single page into dest.
+// Requests carrying an after/before cursor are served by keyset, everything
+// else falls back to OFFSET. One extra row is fetched to know whether more
+// pages exist, so the COUNT only runs when the caller asked for the total.
+//
+// Sort columns come from a pagination.Sortable whitelist and may live on
+// joined tables, which is why the cursor values are read back with a small
+// query instead of from the loaded entities.
+func paginate[T any](query *gorm.DB, table string, params pagination.Request, dest *[]T) (pagination.Meta, error) {
+	var meta pagination.Meta
+
+	stmt := &gorm.Statement{DB: query}
+	if err := stmt.Parse(new(T)); err != nil {
+		return meta, err
+	}
+	idField := stmt.Schema.PrioritizedPrimaryField
+	idColumn := fmt.Sprintf("%s.%s", table, idField.DBName)
+
+	sorts := tieBreak(params.Sorts, idColumn)
+	base := query.Session(&gorm.Session{})
+	query = base
+
+	if params.WithTotal {
+		var total int64
+		if err := base.Count(&total).Error; err != nil {
+			return meta, err
+		}
+		meta.Total = &total
+	}
+
+	backward := params.Before != ""
+	token := params.After
+	if backward {
+		token = params.Before
+	}
+
+	if token != "" {
+		cursor, err := pagination.DecodeCursor(token)
+		if err != nil {
+			return meta, err
+		}
+		if cursor.Key != params.CursorKey() || len(cursor.Values) != len(sorts) {
+			return meta, pagination.ErrInvalidCursor
+		}
+
+		condition, args := keyset(sorts, cursor.Values, backward)
+		query = query.Where(condition, args...)
+	} else {
+		query = query.Offset(params.Offset())
+	}
+
+	// NULL sorts as the largest value either way, which keeps the order
+	// reversible and is what keyset assumes.
+	orders := make([]string, 0, len(sorts))
+	for _, sort := range sorts {
+		direction := "ASC NULLS LAST"
+		if sort.Descending != backward {
+			direction = "DESC NULLS FIRST"
+		}
+		orders = append(orders, fmt.Sprintf("%s %s", sort.Column, direction))
+	}
+
+	result := query.
+		Order(strings.Join(orders, ", ")).
+		Limit(params.Limit + 1).
+		Find(dest)
+	if result.Error != nil {
+		return meta, result.Error
+	}
+
+	items := *dest
+	meta.HasMore = len(items) > params.Limit
+	if meta.HasMore {
+		items = items[:params.Limit]
+	}
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	*dest = items
+
+	if len(items) == 0 {
+		return meta, nil
+	}
+
+	idOf := func(item *T) uint {
+		id, _ := idField.ValueOf(query.Statement.Context, reflect.ValueOf(item).Elem())
+		return uint(reflect.ValueOf(id).Uint())
+	}
+	firstID, lastID := idOf(&items[0]), idOf(&items[len(items)-1])
+
+	values, err := sortValues(base, idColumn, sorts, firstID, lastID)
+	if err != nil {
+		return meta, err
+	}
+	cursorOf := func(id uint) string {
+		return pagination.EncodeCursor(pagination.Cursor{
+			Key:    params.CursorKey(),
+			Values: values[id],
+			ID:     id,
+		})
+	}
+
+	if backward {
+		meta.NextCursor = cursorOf(lastID)
+		if meta.HasMore {
+			meta.PrevCursor = cursorOf(firstID)
+		}
+		return meta, nil
+	}
+
+	if meta.HasMore {
+		meta.NextCursor = cursorOf(lastID)
+	}
+	if params.After != "" || params.Page > 1 {
+		meta.PrevCursor = cursorOf(firstID)
+	}
+
+	return meta, nil
+}
+
+// tieBreak appends the primary key to sorts so every ordering is total and a
+// keyset cursor always points at exactly one row.
+func tieBreak(sorts []pagination.Sort, idColumn string) []pagination.Sort {
+	result := make([]pagination.Sort, 0, len(sorts)+1)
+	for _, sort := range sorts {
+		result = append(result, sort)
+		if sort.Column == idColumn {
+			return result
+		}
+	}
+
+	return append(result, pagination.Sort{Field: "id", Column: idColumn})
+}
+
+// keyset builds the row comparison "comes after values" for sorts that may mix
+// directions, e.g. (a > ?) OR (a = ? AND b < ?) OR (a = ? AND b = ? AND id > ?).
+// Sort columns can be nullable, such as a LEFT JOINed name, so NULL is taken
+// as larger than any value, the way paginate orders it, and compared with IS
+// NULL since = and < never match it.
+func keyset(sorts []pagination.Sort, values []any, backward bool) (string, []any) {
+	var clauses []string
+	var args []any
+
+	for i, sort := range sorts {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			if values[j] == nil {
+				parts = append(parts, fmt.Sprintf("%s IS NULL", sorts[j].Column))
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("%s = ?", sorts[j].Column))
+			args = append(args, values[j])
+		}
+
+		larger := sort.Descending == backward
+		switch {
+		case values[i] == nil && larger:
+			// Nothing is larger than NULL, the rows after it differ on a
+			// later column.
+			continue
+		case values[i] == nil:
+			parts = append(parts, fmt.Sprintf("%s IS NOT NULL", sort.Column))
+		case larger && i < len(sorts)-1:
+			parts = append(parts, fmt.Sprintf("(%[1]s > ? OR %[1]s IS NULL)", sort.Column))
+			args = append(args, values[i])
+		case larger:
+			// The last sort is the primary key tieBreak added.
+			parts = append(parts, fmt.Sprintf("%s > ?", sort.Column))
+			args = append(args, values[i])
+		default:
+			parts = append(parts, fmt.Sprintf("%s < ?", sort.Column))
+			args = append(args, values[i])
+		}
+
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+func sortValues(base *gorm.DB, idColumn string, sorts []pagination.Sort, ids ...uint) (map[uint][]any, error) {
+	columns := make([]string, 0, len(sorts)+1)
+	columns = append(columns, idColumn)
+	for _, sort := range sorts {
+		columns = append(columns, sort.Column)
+	}
+
+	rows, err := base.
+		Select(strings.Join(columns, ", ")).
+		Where(fmt.Sprintf("%s IN ?", idColumn), ids).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[uint][]any, len(ids))
+	for rows.Next() {
+		var id uint
+		row := make([]any, len(sorts))
+		targets := make([]any, 0, len(sorts)+1)
+		targets = append(targets, &id)
+		for i := range row {
+			targets = append(targets, &row[i])
+		}
+
+		if err = rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		values[id] = row
+	}
+
+	return values, rows.Err()
+}
+
+// sortsOn reports whether any of sorts reads a column of table.
+func sortsOn(sorts []pagination.Sort, table string) bool {
+	for _, sort := range sorts {
+		if strings.HasPrefix(sort.Column, table+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"reflect"
+	"starter/internal/core/pagination"
+	"testing"
+)
+
+func TestKeyset(t *testing.T) {
+	name := pagination.Sort{Field: "name", Column: "name"}
+	nameDesc := pagination.Sort{Field: "name", Column: "name", Descending: true}
+	id := pagination.Sort{Field: "id", Column: "id"}
+
+	tests := []struct {
+		name     string
+		sorts    []pagination.Sort
+		values   []any
+		backward bool
+		want     string
+		wantArgs []any
+	}{
+		{
+			name:     "ascending",
+			sorts:    []pagination.Sort{name, id},
+			values:   []any{"b", 2},
+			want:     "(((name > ? OR name IS NULL)) OR (name = ? AND id > ?))",
+			wantArgs: []any{"b", "b", 2},
+		},
+		{
+			name:     "descending",
+			sorts:    []pagination.Sort{nameDesc, id},
+			values:   []any{"b", 2},
+			want:     "((name < ?) OR (name = ? AND id > ?))",
+			wantArgs: []any{"b", "b", 2},
+		},
+		{
+			name:     "backward",
+			sorts:    []pagination.Sort{name, id},
+			values:   []any{"b", 2},
+			backward: true,
+			want:     "((name < ?) OR (name = ? AND id < ?))",
+			wantArgs: []any{"b", "b", 2},
+		},
+		{
+			name:     "after a NULL ascending",
+			sorts:    []pagination.Sort{name, id},
+			values:   []any{nil, 2},
+			want:     "((name IS NULL AND id > ?))",
+			wantArgs: []any{2},
+		},
+		{
+			name:     "after a NULL descending",
+			sorts:    []pagination.Sort{nameDesc, id},
+			values:   []any{nil, 2},
+			want:     "((name IS NOT NULL) OR (name IS NULL AND id > ?))",
+			wantArgs: []any{2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, args := keyset(test.sorts, test.values, test.backward)
+			if got != test.want {
+				t.Errorf("keyset() = %s, want %s", got, test.want)
+			}
+			if !reflect.DeepEqual(args, test.wantArgs) {
+				t.Errorf("keyset() args = %v, want %v", args, test.wantArgs)
+			}
+		})
+	}
+}
@@ -6,11 +6,22 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"starter/config"
+	"starter/internal/adapters/metrics"
+	"starter/internal/adapters/tracing"
+	"starter/internal/core/audit"
 	"starter/internal/core/author"
 	"starter/internal/core/book"
 	"starter/internal/core/category"
+	"starter/internal/core/idempotency"
+	"starter/internal/core/merge"
 	"starter/internal/core/publisher"
+	"starter/internal/core/ratelimit"
+	"starter/internal/core/reading"
+	"starter/internal/core/recommendation"
+	"starter/internal/core/review"
 	"starter/internal/core/role"
+	"starter/internal/core/shelf"
+	"starter/internal/core/tag"
 	"starter/internal/core/user"
 )
 
@@ -24,7 +35,11 @@ func NewPostgresConn() (*gorm.DB, error) {
 		config.AppConfig.Timezone,
 	)
 
-	db, err := gorm.Open(postgres.Open(dsn))
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: newGormLogger(),
+		// Unique violations come back as gorm.ErrDuplicatedKey.
+		TranslateError: true,
+	})
 	if err != nil {
 		log.Panic().
 			Err(err).
@@ -34,7 +49,23 @@ func NewPostgresConn() (*gorm.DB, error) {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&user.User{}, &role.Role{}, &publisher.Publisher{}, &author.Author{}, &category.Category{}, &book.Book{})
+	err = db.Use(metrics.NewGormPlugin())
+	if err != nil {
+		log.Panic().
+			Err(err).
+			Msg("unable to install database metrics")
+		return nil, err
+	}
+
+	err = db.Use(tracing.NewGormPlugin())
+	if err != nil {
+		log.Panic().
+			Err(err).
+			Msg("unable to install database tracing")
+		return nil, err
+	}
+
+	err = db.AutoMigrate(&user.User{}, &role.Role{}, &publisher.Publisher{}, &author.Author{}, &category.Category{}, &category.Closure{}, &tag.Tag{}, &book.Book{}, &book.Localization{}, &idempotency.Record{}, &ratelimit.Hit{}, &ratelimit.Lockout{}, &audit.Entry{}, &merge.Redirect{}, &book.Revision{}, &review.Review{}, &review.Vote{}, &shelf.Shelf{}, &shelf.Item{}, &reading.Progress{}, &reading.Session{}, &reading.Goal{}, &recommendation.Similarity{})
 	if err != nil {
 		log.Panic().
 			Err(err).
@@ -42,5 +73,53 @@ func NewPostgresConn() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	err = migrateForeignKeys(db)
+	if err != nil {
+		log.Panic().
+			Err(err).
+			Msg("unable to migrate the foreign keys")
+		return nil, err
+	}
+
+	err = migrateTrigram(db)
+	if err != nil {
+		log.Panic().
+			Err(err).
+			Msg("unable to set up trigram search")
+		return nil, err
+	}
+
+	err = migrateCategoryClosure(db)
+	if err != nil {
+		log.Panic().
+			Err(err).
+			Msg("unable to migrate the category tree")
+		return nil, err
+	}
+
+	err = migrateTitleCase(db)
+	if err != nil {
+		log.Panic().
+			Err(err).
+			Msg("unable to title case the stored names")
+		return nil, err
+	}
+
+	err = migrateSearchKeys(db)
+	if err != nil {
+		log.Panic().
+			Err(err).
+			Msg("unable to migrate the search keys")
+		return nil, err
+	}
+
+	err = migrateNameIndexes(db)
+	if err != nil {
+		log.Panic().
+			Err(err).
+			Msg("unable to create the unique name indexes")
+		return nil, err
+	}
+
 	return db, nil
 }
@@ -0,0 +1,23 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"starter/internal/core/projection"
+)
+
+// project narrows query to the columns and associations resolved in view. A
+// view that was never parsed (internal callers) loads every column and the
+// given default associations, which is how the repositories behaved before.
+func project(query *gorm.DB, view projection.Request, defaults ...string) *gorm.DB {
+	preloads := defaults
+	if len(view.Columns) > 0 {
+		query = query.Select(view.Columns)
+		preloads = view.Preloads
+	}
+
+	for _, preload := range preloads {
+		query = query.Preload(preload)
+	}
+
+	return query
+}
@@ -1,10 +1,12 @@
 package database
 
 import (
-	"fmt"
+	"errors"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
+	"starter/internal/core/merge"
 	"starter/internal/core/pagination"
+	"starter/internal/core/projection"
 	"starter/internal/core/publisher"
 )
 
@@ -16,9 +18,10 @@ func NewPublisherRepository() publisher.Repository {
 }
 
 func (repository *PublisherRepository) Save(db *gorm.DB, publisher *publisher.Publisher) error {
+	publisher.SetKey()
 	result := db.Create(publisher)
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
 			Msgf("Failed to save publisher")
 		return result.Error
@@ -28,56 +31,109 @@ func (repository *PublisherRepository) Save(db *gorm.DB, publisher *publisher.Pu
 }
 
 func (repository *PublisherRepository) Update(db *gorm.DB, publisher *publisher.Publisher) error {
-	result := db.Updates(publisher)
-	if result.Error != nil {
-		log.Error().
-			Err(result.Error).
+	publisher.SetKey()
+	err := updateVersioned(db, publisher, &publisher.Version)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
 			Msgf("Failed to save publisher")
-		return result.Error
+		return err
 	}
 
 	return nil
 }
 
-func (repository *PublisherRepository) Delete(db *gorm.DB, id int) error {
-	result := db.Delete(&publisher.Publisher{}, id)
-	if result.Error != nil {
-		log.Error().
-			Err(result.Error).
+func (repository *PublisherRepository) Patch(db *gorm.DB, publisher *publisher.Publisher, columns []string) error {
+	publisher.SetKey()
+	err := patchVersioned(db, publisher, &publisher.Version, keyed(columns, "name_key", "name"))
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to patch publisher")
+		return err
+	}
+
+	return nil
+}
+
+func (repository *PublisherRepository) Delete(db *gorm.DB, id int, version uint) error {
+	err := deleteVersioned(db, &publisher.Publisher{}, id, version)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
 			Msgf("Failed to delete publisher")
-		return result.Error
+		return err
 	}
 
 	return nil
 }
 
-func (repository *PublisherRepository) FindAll(db *gorm.DB, params pagination.Request) ([]publisher.Publisher, int64, error) {
-	var publishers []publisher.Publisher
+func (repository *PublisherRepository) CountBooks(db *gorm.DB, id int) (int64, error) {
+	count, err := countBooks(db, "publisher_id", id)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to count books of publisher")
+	}
+
+	return count, err
+}
+
+func (repository *PublisherRepository) DeleteBooks(db *gorm.DB, id int) ([]uint, error) {
+	books, err := deleteBooks(db, "publisher_id", id)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to delete books of publisher")
+	}
+
+	return books, err
+}
+
+func (repository *PublisherRepository) ReassignBooks(db *gorm.DB, id int, target uint) ([]uint, error) {
+	books, err := reassignBooks(db, "publisher_id", id, target)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to reassign books of publisher")
+	}
+
+	return books, err
+}
+
+// NameTaken reports whether a live publisher other than exclude goes by key.
+func (repository *PublisherRepository) NameTaken(db *gorm.DB, key string, exclude uint) (bool, error) {
 	var count int64
+	err := db.Model(&publisher.Publisher{}).
+		Where("name_key = ? AND id <> ?", key, exclude).
+		Count(&count).Error
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to look up publisher name")
+	}
 
-	db.Model(&publishers).Count(&count)
+	return count > 0, err
+}
 
-	result := db.
-		Debug().
-		Order(fmt.Sprintf("%s %s", params.OrderBy, params.SortBy)).
-		Limit(params.Limit).
-		Offset((params.Page - 1) * params.Limit).
-		Find(&publishers)
+func (repository *PublisherRepository) FindAll(db *gorm.DB, params pagination.Request, view projection.Request) ([]publisher.Publisher, pagination.Meta, error) {
+	var publishers []publisher.Publisher
 
-	if result.Error != nil {
-		log.Error().
-			Err(result.Error).
+	meta, err := paginate(project(db.Model(&publisher.Publisher{}), view), "publishers", params, &publishers)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
 			Msgf("Failed to find all publishers")
 	}
 
-	return publishers, count, result.Error
+	return publishers, meta, err
 }
 
-func (repository *PublisherRepository) FindByID(db *gorm.DB, id int) (publisher.Publisher, error) {
+func (repository *PublisherRepository) FindByID(db *gorm.DB, id int, view projection.Request) (publisher.Publisher, error) {
 	var publisher publisher.Publisher
-	result := db.First(&publisher, id)
+	result := project(db, view).First(&publisher, id)
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
 			Msgf("Failed to find publisher")
 		return publisher, result.Error
@@ -85,3 +141,26 @@ func (repository *PublisherRepository) FindByID(db *gorm.DB, id int) (publisher.
 
 	return publisher, nil
 }
+
+func (repository *PublisherRepository) Duplicates(db *gorm.DB, request merge.DuplicatesRequest) ([]merge.Candidate, error) {
+	candidates, err := findDuplicates(db, "publishers", nameKey, "%[1]s.name", request)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to find duplicate publishers")
+	}
+
+	return candidates, err
+}
+
+func (repository *PublisherRepository) FindByKey(db *gorm.DB, key string) (publisher.Publisher, error) {
+	var publisher publisher.Publisher
+	err := db.Where("name_key = ?", key).Order("id").First(&publisher).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to find publisher by key")
+	}
+
+	return publisher, err
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"starter/internal/core/ratelimit"
+	"time"
+)
+
+// RateLimitStore shares sliding windows and lockouts between instances through
+// the rate_limit_hits and lockouts tables.
+type RateLimitStore struct {
+	db *gorm.DB
+}
+
+func NewRateLimitStore(db *gorm.DB) ratelimit.Store {
+	return &RateLimitStore{
+		db: db,
+	}
+}
+
+// Take serialises hits on the same key with a transaction scoped advisory
+// lock, so concurrent requests can't both take the last slot.
+func (store *RateLimitStore) Take(ctx context.Context, key string, now time.Time, limit ratelimit.Limit) (ratelimit.Window, error) {
+	var window ratelimit.Window
+
+	err := store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error
+		if err != nil {
+			return err
+		}
+
+		since := now.Add(-limit.Window)
+		err = tx.Where("key = ? AND hit_at <= ?", key, since).Delete(&ratelimit.Hit{}).Error
+		if err != nil {
+			return err
+		}
+
+		var oldest *time.Time
+		row := tx.Model(&ratelimit.Hit{}).
+			Select("COUNT(*), MIN(hit_at)").
+			Where("key = ? AND hit_at > ?", key, since).
+			Row()
+		if err = row.Scan(&window.Count, &oldest); err != nil {
+			return err
+		}
+		if oldest != nil {
+			window.Oldest = *oldest
+		}
+
+		if window.Count >= limit.Requests {
+			return nil
+		}
+		if window.Count == 0 {
+			window.Oldest = now
+		}
+		window.Count++
+		window.Allowed = true
+
+		return tx.Create(&ratelimit.Hit{Key: key, HitAt: now}).Error
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().
+			Err(err).
+			Msgf("Failed to count rate limit hit")
+		return ratelimit.Window{}, err
+	}
+
+	return window, nil
+}
+
+func (store *RateLimitStore) Lockout(ctx context.Context, key string) (ratelimit.Lockout, error) {
+	lockout := ratelimit.Lockout{Key: key}
+
+	result := store.db.WithContext(ctx).Where("key = ?", key).First(&lockout)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		log.Ctx(ctx).Error().
+			Err(result.Error).
+			Msgf("Failed to find lockout")
+		return lockout, result.Error
+	}
+
+	return lockout, nil
+}
+
+// Fail bumps the failure count in a single upsert, starting over when the
+// last failure is older than backoff.Forget, then sets the lock it earned.
+func (store *RateLimitStore) Fail(ctx context.Context, key string, now time.Time, backoff ratelimit.Backoff) (ratelimit.Lockout, error) {
+	lockout := ratelimit.Lockout{Key: key, Failures: 1, UpdatedAt: now}
+	forget := time.Time{}
+	if backoff.Forget > 0 {
+		forget = now.Add(-backoff.Forget)
+	}
+
+	err := store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(
+			clause.OnConflict{
+				Columns: []clause.Column{{Name: "key"}},
+				DoUpdates: clause.Set{
+					{Column: clause.Column{Name: "failures"}, Value: gorm.Expr("CASE WHEN lockouts.updated_at < ? THEN 1 ELSE lockouts.failures + 1 END", forget)},
+					{Column: clause.Column{Name: "updated_at"}, Value: now},
+				},
+			},
+			clause.Returning{Columns: []clause.Column{{Name: "failures"}, {Name: "locked_until"}}},
+		).Create(&lockout).Error
+		if err != nil {
+			return err
+		}
+
+		if duration := backoff.Duration(lockout.Failures); duration > 0 {
+			lockout.LockedUntil = now.Add(duration)
+			return tx.Model(&ratelimit.Lockout{}).
+				Where("key = ?", key).
+				UpdateColumn("locked_until", lockout.LockedUntil).Error
+		}
+		return nil
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().
+			Err(err).
+			Msgf("Failed to record failure")
+		return ratelimit.Lockout{}, err
+	}
+
+	return lockout, nil
+}
+
+func (store *RateLimitStore) Reset(ctx context.Context, key string) error {
+	result := store.db.WithContext(ctx).Where("key = ?", key).Delete(&ratelimit.Lockout{})
+	if result.Error != nil {
+		log.Ctx(ctx).Error().
+			Err(result.Error).
+			Msgf("Failed to reset lockout")
+		return result.Error
+	}
+
+	return nil
+}
@@ -0,0 +1,250 @@
+package database
+
+import (
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"starter/internal/core/book"
+	"starter/internal/core/filter"
+	"starter/internal/core/pagination"
+	"starter/internal/core/reading"
+)
+
+type ReadingRepository struct {
+}
+
+func NewReadingRepository() reading.Repository {
+	return &ReadingRepository{}
+}
+
+func (repository *ReadingRepository) Save(db *gorm.DB, progress *reading.Progress) error {
+	result := db.Omit(clause.Associations).Create(progress)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to save reading progress")
+		return result.Error
+	}
+
+	return nil
+}
+
+func (repository *ReadingRepository) Update(db *gorm.DB, progress *reading.Progress) error {
+	err := patchVersioned(db, progress, &progress.Version, []string{"current_page", "started_at", "finished_at"})
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to update reading progress")
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ReadingRepository) Delete(db *gorm.DB, id uint, version uint) error {
+	err := deleteVersioned(db, &reading.Progress{}, int(id), version)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to delete reading progress")
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ReadingRepository) FindAll(db *gorm.DB, userID uint, params pagination.Request, filter filter.ReadingFilter) ([]reading.Progress, pagination.Meta, error) {
+	var progresses []reading.Progress
+
+	query := db.Model(&reading.Progress{}).
+		Preload("Book").
+		Where("reading_progress.user_id = ?", userID)
+	switch filter.Status {
+	case "reading":
+		query = query.Where("reading_progress.finished_at IS NULL")
+	case "finished":
+		query = query.Where("reading_progress.finished_at IS NOT NULL")
+	}
+
+	meta, err := paginate(query, "reading_progress", params, &progresses)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to find all reading progress")
+	}
+
+	return progresses, meta, err
+}
+
+func (repository *ReadingRepository) FindByBook(db *gorm.DB, userID uint, bookID int) (reading.Progress, error) {
+	var progress reading.Progress
+	result := db.
+		Preload("Book").
+		Preload("Sessions", func(db *gorm.DB) *gorm.DB {
+			return db.Order("reading_sessions.read_on, reading_sessions.id")
+		}).
+		Where("user_id = ? AND book_id = ?", userID, bookID).
+		First(&progress)
+	if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find reading progress")
+	}
+
+	return progress, result.Error
+}
+
+func (repository *ReadingRepository) FindBook(db *gorm.DB, id int) (book.Book, error) {
+	var book book.Book
+	result := db.First(&book, id)
+	if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find book")
+	}
+
+	return book, result.Error
+}
+
+func (repository *ReadingRepository) SaveSession(db *gorm.DB, session *reading.Session) error {
+	result := db.Create(session)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to save reading session")
+		return result.Error
+	}
+
+	return nil
+}
+
+func (repository *ReadingRepository) FindGoals(db *gorm.DB, userID uint) ([]reading.Goal, error) {
+	var goals []reading.Goal
+	result := db.Where("user_id = ?", userID).Order("year DESC").Find(&goals)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find reading goals")
+	}
+
+	return goals, result.Error
+}
+
+func (repository *ReadingRepository) FindGoal(db *gorm.DB, userID uint, year int) (reading.Goal, error) {
+	var goal reading.Goal
+	result := db.Where("user_id = ? AND year = ?", userID, year).First(&goal)
+	if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find reading goal")
+	}
+
+	return goal, result.Error
+}
+
+// SaveGoal creates the goal of its year or overwrites the targets of the one
+// already there.
+func (repository *ReadingRepository) SaveGoal(db *gorm.DB, goal *reading.Goal) error {
+	result := db.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "year"}},
+		DoUpdates: clause.AssignmentColumns([]string{"books", "pages", "updated_at"}),
+	}).Create(goal)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to save reading goal")
+		return result.Error
+	}
+
+	return nil
+}
+
+func (repository *ReadingRepository) DeleteGoal(db *gorm.DB, userID uint, year int) error {
+	result := db.Where("user_id = ? AND year = ?", userID, year).Delete(&reading.Goal{})
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to delete reading goal")
+	}
+
+	return result.Error
+}
+
+// sessionsOf selects the reading sessions of userID in year.
+func sessionsOf(db *gorm.DB, userID uint, year int) *gorm.DB {
+	return db.Table("reading_sessions").
+		Joins("JOIN reading_progress ON reading_progress.id = reading_sessions.progress_id").
+		Where("reading_progress.user_id = ?", userID).
+		Where("reading_sessions.read_on >= make_date(?, 1, 1) AND reading_sessions.read_on < make_date(?, 1, 1)", year, year+1)
+}
+
+func (repository *ReadingRepository) PagesRead(db *gorm.DB, userID uint, year int) (int, error) {
+	var pages int
+	result := sessionsOf(db, userID, year).
+		Select("coalesce(sum(reading_sessions.pages), 0)").
+		Scan(&pages)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to sum pages read")
+	}
+
+	return pages, result.Error
+}
+
+// PagesPerWeek sums the pages read in year by the week they fell in, weeks
+// start on Monday and are named by it.
+func (repository *ReadingRepository) PagesPerWeek(db *gorm.DB, userID uint, year int) ([]reading.Week, error) {
+	var weeks []reading.Week
+	result := sessionsOf(db, userID, year).
+		Select("date_trunc('week', reading_sessions.read_on) AS week, sum(reading_sessions.pages) AS pages").
+		Group("week").
+		Order("week").
+		Scan(&weeks)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to sum pages per week")
+	}
+
+	return weeks, result.Error
+}
+
+func (repository *ReadingRepository) BooksPerYear(db *gorm.DB, userID uint) ([]reading.Year, error) {
+	var years []reading.Year
+	result := db.Model(&reading.Progress{}).
+		Select("extract(year FROM finished_at)::int AS year, count(*) AS books").
+		Where("user_id = ? AND finished_at IS NOT NULL", userID).
+		Group("year").
+		Order("year").
+		Scan(&years)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to count books per year")
+	}
+
+	return years, result.Error
+}
+
+// FavouriteCategories ranks the categories by how many of the books the user
+// tracks are in them, finished books break ties.
+func (repository *ReadingRepository) FavouriteCategories(db *gorm.DB, userID uint, limit int) ([]reading.Category, error) {
+	var categories []reading.Category
+	result := db.Table("reading_progress").
+		Select("categories.id, categories.name, count(*) AS books").
+		Joins("JOIN book_category ON book_category.book_id = reading_progress.book_id").
+		Joins("JOIN categories ON categories.id = book_category.category_id AND categories.deleted_at IS NULL").
+		Where("reading_progress.user_id = ?", userID).
+		Group("categories.id, categories.name").
+		Order("books DESC, count(reading_progress.finished_at) DESC, categories.name").
+		Limit(limit).
+		Scan(&categories)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to rank categories")
+	}
+
+	return categories, result.Error
+}
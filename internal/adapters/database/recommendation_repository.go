@@ -0,0 +1,227 @@
+package database
+
+import (
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"starter/internal/core/book"
+	"starter/internal/core/recommendation"
+	"time"
+)
+
+// bookOwners lists which users have which books, on any of their live shelves
+// or in their reading progress.
+const bookOwners = `
+	SELECT shelves.user_id, shelf_items.book_id FROM shelf_items
+	JOIN shelves ON shelves.id = shelf_items.shelf_id AND shelves.deleted_at IS NULL
+	UNION
+	SELECT reading_progress.user_id, reading_progress.book_id FROM reading_progress`
+
+type RecommendationRepository struct {
+}
+
+func NewRecommendationRepository() recommendation.Repository {
+	return &RecommendationRepository{}
+}
+
+// Refresh scores every pair of live books sharing a category, an author or a
+// reader and keeps the best neighbours of each. The advisory lock makes
+// concurrent refreshes (several instances, a manual one during the job) skip
+// instead of computing the same table twice.
+func (repository *RecommendationRepository) Refresh(db *gorm.DB, weights recommendation.Weights, neighbours int, now time.Time) (int64, bool, error) {
+	var locked bool
+	result := db.Raw("SELECT pg_try_advisory_xact_lock(hashtext('book_similarities'))").Scan(&locked)
+	if result.Error != nil || !locked {
+		if result.Error != nil {
+			log.Ctx(db.Statement.Context).Error().
+				Err(result.Error).
+				Msgf("Failed to lock book similarities")
+		}
+		return 0, false, result.Error
+	}
+
+	result = db.Exec("DELETE FROM book_similarities")
+	if result.Error == nil {
+		result = db.Exec(`
+			WITH active AS (
+				SELECT id, author_id FROM books WHERE deleted_at IS NULL
+			),
+			categorised AS (
+				SELECT book_category.book_id, book_category.category_id FROM book_category
+				JOIN active ON active.id = book_category.book_id
+			),
+			category_counts AS (
+				SELECT book_id, count(*) AS total FROM categorised GROUP BY book_id
+			),
+			shared_categories AS (
+				SELECT a.book_id, b.book_id AS similar_id, count(*) AS shared
+				FROM categorised a
+				JOIN categorised b ON b.category_id = a.category_id AND b.book_id <> a.book_id
+				GROUP BY a.book_id, b.book_id
+			),
+			owned AS (
+				SELECT owners.user_id, owners.book_id FROM (`+bookOwners+`) owners
+				JOIN active ON active.id = owners.book_id
+			),
+			owner_counts AS (
+				SELECT book_id, count(*) AS total FROM owned GROUP BY book_id
+			),
+			co_readers AS (
+				SELECT a.book_id, b.book_id AS similar_id, count(*) AS shared
+				FROM owned a
+				JOIN owned b ON b.user_id = a.user_id AND b.book_id <> a.book_id
+				GROUP BY a.book_id, b.book_id
+			),
+			same_author AS (
+				SELECT a.id AS book_id, b.id AS similar_id
+				FROM active a
+				JOIN active b ON b.author_id = a.author_id AND b.id <> a.id
+			),
+			pairs AS (
+				SELECT book_id, similar_id FROM shared_categories
+				UNION SELECT book_id, similar_id FROM co_readers
+				UNION SELECT book_id, similar_id FROM same_author
+			),
+			scored AS (
+				SELECT
+					pairs.book_id,
+					pairs.similar_id,
+					coalesce(shared_categories.shared, 0) AS shared_categories,
+					same_author.book_id IS NOT NULL AS same_author,
+					coalesce(co_readers.shared, 0) AS co_readers,
+					@categories::float8 * coalesce(shared_categories.shared::float8 / nullif(a_categories.total + b_categories.total - shared_categories.shared, 0), 0)
+					+ @author::float8 * (same_author.book_id IS NOT NULL)::int
+					+ @readers::float8 * coalesce(co_readers.shared / sqrt(a_owners.total * b_owners.total), 0) AS score
+				FROM pairs
+				LEFT JOIN shared_categories ON shared_categories.book_id = pairs.book_id AND shared_categories.similar_id = pairs.similar_id
+				LEFT JOIN co_readers ON co_readers.book_id = pairs.book_id AND co_readers.similar_id = pairs.similar_id
+				LEFT JOIN same_author ON same_author.book_id = pairs.book_id AND same_author.similar_id = pairs.similar_id
+				LEFT JOIN category_counts a_categories ON a_categories.book_id = pairs.book_id
+				LEFT JOIN category_counts b_categories ON b_categories.book_id = pairs.similar_id
+				LEFT JOIN owner_counts a_owners ON a_owners.book_id = pairs.book_id
+				LEFT JOIN owner_counts b_owners ON b_owners.book_id = pairs.similar_id
+			),
+			ranked AS (
+				SELECT scored.*, row_number() OVER (PARTITION BY book_id ORDER BY score DESC, similar_id) AS rank
+				FROM scored
+			)
+			INSERT INTO book_similarities (book_id, similar_id, score, shared_categories, same_author, co_readers, computed_at)
+			SELECT book_id, similar_id, score, shared_categories, same_author, co_readers, @now::timestamptz
+			FROM ranked
+			WHERE rank <= @neighbours`,
+			map[string]any{
+				"categories": weights.Categories,
+				"author":     weights.Author,
+				"readers":    weights.Readers,
+				"neighbours": neighbours,
+				"now":        now,
+			},
+		)
+	}
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to refresh book similarities")
+		return 0, true, result.Error
+	}
+
+	return result.RowsAffected, true, nil
+}
+
+func (repository *RecommendationRepository) BookExists(db *gorm.DB, id int) (bool, error) {
+	var count int64
+	result := db.Table("books").
+		Where("id = ? AND deleted_at IS NULL", id).
+		Count(&count)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to check book")
+	}
+
+	return count > 0, result.Error
+}
+
+// FindSimilar lists the neighbours of bookID that userID doesn't have yet.
+func (repository *RecommendationRepository) FindSimilar(db *gorm.DB, bookID int, userID uint, limit int) ([]recommendation.Neighbour, error) {
+	var neighbours []recommendation.Neighbour
+	result := db.Table("book_similarities").
+		Select("book_similarities.similar_id AS book_id, book_similarities.score, book_similarities.shared_categories, book_similarities.same_author, book_similarities.co_readers").
+		Joins("JOIN books ON books.id = book_similarities.similar_id AND books.deleted_at IS NULL").
+		Where("book_similarities.book_id = ?", bookID).
+		Where("book_similarities.similar_id NOT IN (SELECT owners.book_id FROM ("+bookOwners+") owners WHERE owners.user_id = ?)", userID).
+		Order("book_similarities.score DESC, book_similarities.similar_id").
+		Limit(limit).
+		Scan(&neighbours)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find similar books")
+	}
+
+	return neighbours, result.Error
+}
+
+// FindRecommended adds up the similarities of every book userID has to the
+// books they don't.
+func (repository *RecommendationRepository) FindRecommended(db *gorm.DB, userID uint, limit int) ([]recommendation.Neighbour, error) {
+	var neighbours []recommendation.Neighbour
+	result := db.Raw(`
+		WITH owned AS (
+			SELECT owners.book_id FROM (`+bookOwners+`) owners WHERE owners.user_id = @user
+		)
+		SELECT book_similarities.similar_id AS book_id, sum(book_similarities.score) AS score, count(*) AS matches
+		FROM book_similarities
+		JOIN owned ON owned.book_id = book_similarities.book_id
+		JOIN books ON books.id = book_similarities.similar_id AND books.deleted_at IS NULL
+		WHERE book_similarities.similar_id NOT IN (SELECT book_id FROM owned)
+		GROUP BY book_similarities.similar_id
+		ORDER BY score DESC, book_id
+		LIMIT @limit`,
+		map[string]any{"user": userID, "limit": limit},
+	).Scan(&neighbours)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find recommended books")
+	}
+
+	return neighbours, result.Error
+}
+
+// FindPopular ranks the books userID doesn't have by how many users have
+// them, then by rating.
+func (repository *RecommendationRepository) FindPopular(db *gorm.DB, userID uint, limit int) ([]recommendation.Neighbour, error) {
+	var neighbours []recommendation.Neighbour
+	result := db.Raw(`
+		WITH owners AS (`+bookOwners+`)
+		SELECT books.id AS book_id, count(owners.user_id)::float8 AS score
+		FROM books
+		LEFT JOIN owners ON owners.book_id = books.id
+		WHERE books.deleted_at IS NULL
+			AND books.id NOT IN (SELECT book_id FROM owners WHERE user_id = @user)
+		GROUP BY books.id
+		ORDER BY score DESC, books.rating_average DESC, books.id
+		LIMIT @limit`,
+		map[string]any{"user": userID, "limit": limit},
+	).Scan(&neighbours)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find popular books")
+	}
+
+	return neighbours, result.Error
+}
+
+func (repository *RecommendationRepository) FindBooks(db *gorm.DB, ids []uint) ([]book.Book, error) {
+	var books []book.Book
+	result := db.Preload("Author").Preload("Publisher").Preload("Categories").
+		Find(&books, ids)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find books")
+	}
+
+	return books, result.Error
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"errors"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"starter/internal/core/merge"
+)
+
+type RedirectRepository struct {
+}
+
+func NewRedirectRepository() merge.RedirectRepository {
+	return &RedirectRepository{}
+}
+
+// Save keeps redirects one hop long: whatever pointed at the source now points
+// at the target, and a target that used to redirect elsewhere stops doing so.
+func (repository *RedirectRepository) Save(db *gorm.DB, redirect *merge.Redirect) error {
+	result := db.Model(&merge.Redirect{}).
+		Where("entity_type = ? AND target_id = ?", redirect.EntityType, redirect.SourceID).
+		Update("target_id", redirect.TargetID)
+	if result.Error == nil {
+		result = db.Where("entity_type = ? AND source_id = ?", redirect.EntityType, redirect.TargetID).
+			Delete(&merge.Redirect{})
+	}
+	if result.Error == nil {
+		result = db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "entity_type"}, {Name: "source_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"target_id"}),
+		}).Create(redirect)
+	}
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to save redirect")
+		return result.Error
+	}
+
+	return nil
+}
+
+func (repository *RedirectRepository) FindBySource(db *gorm.DB, entityType string, id int) (merge.Redirect, error) {
+	var redirect merge.Redirect
+	result := db.Where("entity_type = ? AND source_id = ?", entityType, id).
+		Take(&redirect)
+	if result.Error != nil {
+		// Most lookups miss, only records that were merged away have one.
+		if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			log.Ctx(db.Statement.Context).Error().
+				Err(result.Error).
+				Msgf("Failed to find redirect")
+		}
+		return redirect, result.Error
+	}
+
+	return redirect, nil
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"starter/internal/core/filter"
+	"starter/internal/core/pagination"
+	"starter/internal/core/review"
+)
+
+type ReviewRepository struct {
+}
+
+func NewReviewRepository() review.Repository {
+	return &ReviewRepository{}
+}
+
+func (repository *ReviewRepository) Save(db *gorm.DB, review *review.Review) error {
+	result := db.Omit(clause.Associations).Create(review)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to save review")
+		return result.Error
+	}
+
+	return nil
+}
+
+// Update writes the columns a review edit or moderation changes, so a body
+// can be cleared as well.
+func (repository *ReviewRepository) Update(db *gorm.DB, review *review.Review) error {
+	err := patchVersioned(db, review, &review.Version, []string{"rating", "body", "status"})
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to update review")
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ReviewRepository) Delete(db *gorm.DB, id int, version uint) error {
+	err := deleteVersioned(db, &review.Review{}, id, version)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to delete review")
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ReviewRepository) FindAll(db *gorm.DB, bookID int, params pagination.Request, filter filter.ReviewFilter) ([]review.Review, pagination.Meta, error) {
+	var reviews []review.Review
+
+	query := db.Model(&review.Review{}).Where("reviews.book_id = ?", bookID)
+	if filter.Status != "" {
+		query = query.Where("reviews.status = ?", filter.Status)
+	}
+	if filter.Rating != 0 {
+		query = query.Where("reviews.rating = ?", filter.Rating)
+	}
+
+	meta, err := paginate(query, "reviews", params, &reviews)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to find all reviews")
+	}
+
+	return reviews, meta, err
+}
+
+func (repository *ReviewRepository) FindByID(db *gorm.DB, id int) (review.Review, error) {
+	var review review.Review
+	result := db.First(&review, id)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find review")
+		return review, result.Error
+	}
+
+	return review, nil
+}
+
+func (repository *ReviewRepository) FindByBookAndUser(db *gorm.DB, bookID int, userID uint) (review.Review, error) {
+	var review review.Review
+	result := db.Where("book_id = ? AND user_id = ?", bookID, userID).First(&review)
+	if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find review of user")
+	}
+
+	return review, result.Error
+}
+
+func (repository *ReviewRepository) BookExists(db *gorm.DB, id int) (bool, error) {
+	var count int64
+	result := db.Table("books").
+		Where("id = ? AND deleted_at IS NULL", id).
+		Count(&count)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to check book")
+	}
+
+	return count > 0, result.Error
+}
+
+// AdjustRating adds count and sum to the rating of a book relative to what
+// is stored, so concurrent reviews don't overwrite each other. Postgres
+// evaluates every SET against the old row, hence the repeated deltas.
+func (repository *ReviewRepository) AdjustRating(db *gorm.DB, bookID uint, count int, sum int) error {
+	result := db.Exec(`
+		UPDATE books SET
+			rating_count = rating_count + @count,
+			rating_sum = rating_sum + @sum,
+			rating_average = coalesce(round((rating_sum + @sum)::numeric / nullif(rating_count + @count, 0), 2), 0)
+		WHERE id = @book`,
+		map[string]any{"book": bookID, "count": count, "sum": sum},
+	)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to adjust book rating")
+	}
+
+	return result.Error
+}
+
+// AddVote records vote and counts it on the review, unless the user already
+// voted for it.
+func (repository *ReviewRepository) AddVote(db *gorm.DB, vote *review.Vote) error {
+	result := db.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(vote)
+	if result.Error == nil && result.RowsAffected > 0 {
+		result = db.Exec("UPDATE reviews SET helpful_count = helpful_count + 1 WHERE id = ?", vote.ReviewID)
+	}
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to add review vote")
+	}
+
+	return result.Error
+}
+
+func (repository *ReviewRepository) RemoveVote(db *gorm.DB, reviewID uint, userID uint) error {
+	result := db.Where("review_id = ? AND user_id = ?", reviewID, userID).Delete(&review.Vote{})
+	if result.Error == nil && result.RowsAffected > 0 {
+		result = db.Exec("UPDATE reviews SET helpful_count = helpful_count - 1 WHERE id = ?", reviewID)
+	}
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to remove review vote")
+	}
+
+	return result.Error
+}
@@ -0,0 +1,205 @@
+package database
+
+import (
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"slices"
+	"starter/internal/core/author"
+	"starter/internal/core/book"
+	"starter/internal/core/category"
+	"starter/internal/core/publisher"
+	"starter/pkg/helper"
+	"time"
+)
+
+// dataMigration marks a one-off rewrite of stored data as done, so that it
+// runs once per database and not on every start.
+type dataMigration struct {
+	Name      string `gorm:"primaryKey;size:100"`
+	AppliedAt time.Time
+}
+
+func (dataMigration) TableName() string {
+	return "data_migrations"
+}
+
+// migrateOnce runs migrate in a transaction unless a migration called name
+// already went through.
+func migrateOnce(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) error {
+	err := db.AutoMigrate(&dataMigration{})
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&dataMigration{}).Where("name = ?", name).Count(&count).Error
+		if err != nil || count > 0 {
+			return err
+		}
+		if err = migrate(tx); err != nil {
+			return err
+		}
+		return tx.Create(&dataMigration{Name: name, AppliedAt: time.Now()}).Error
+	})
+}
+
+// migrateTitleCase gives the names and titles that were stored in capitals
+// a readable casing back. Values holding a lowercase letter are left alone.
+func migrateTitleCase(db *gorm.DB) error {
+	return migrateOnce(db, "title_case", func(tx *gorm.DB) error {
+		columns := []struct {
+			table  string
+			column string
+			title  bool
+		}{
+			{"books", "title", true},
+			{"authors", "first_name", false},
+			{"authors", "last_name", false},
+			{"publishers", "name", false},
+			{"categories", "name", true},
+			{"users", "name", false},
+		}
+		for _, column := range columns {
+			if err := titleCase(tx, column.table, column.column, column.title); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func titleCase(db *gorm.DB, table string, column string, title bool) error {
+	var rows []struct {
+		ID    uint
+		Value string
+	}
+	err := db.Table(table).
+		Select("id, " + column + " AS value").
+		Where(column + " = upper(" + column + ") AND " + column + " <> lower(" + column + ")").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		value := helper.TitleCase(row.Value, title)
+		if value == row.Value {
+			continue
+		}
+		err = db.Table(table).Where("id = ?", row.ID).UpdateColumn(column, value).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateSearchKeys fills the search keys of rows written without one, such
+// as those stored before the keys existed or inserted by the seeders.
+func migrateSearchKeys(db *gorm.DB) error {
+	err := backfillKeys(db, "title_key", func(book *book.Book) string {
+		book.SetKey()
+		return book.TitleKey
+	})
+	if err == nil {
+		err = backfillKeys(db, "name_key", func(author *author.Author) string {
+			author.SetKey()
+			return author.NameKey
+		})
+	}
+	if err == nil {
+		err = backfillKeys(db, "name_key", func(publisher *publisher.Publisher) string {
+			publisher.SetKey()
+			return publisher.NameKey
+		})
+	}
+	if err == nil {
+		err = backfillKeys(db, "name_key", func(category *category.Category) string {
+			category.SetKey()
+			return category.NameKey
+		})
+	}
+	return err
+}
+
+// nameIndexes keep live publishers, and live categories under the same
+// parent, from sharing a name key when two writes race past NameTaken.
+var nameIndexes = []struct {
+	name    string
+	table   string
+	columns string
+}{
+	{"idx_publishers_name_key", "publishers", "name_key"},
+	{"idx_categories_parent_name_key", "categories", "coalesce(parent_id, 0), name_key"},
+}
+
+// migrateNameIndexes creates the unique name indexes. Rows without a key yet,
+// such as those written by plain SQL, are left out until migrateSearchKeys
+// fills them in. A table that still holds live duplicates, stored before
+// names were checked, is skipped with a warning until they are merged, the
+// index follows on a later start.
+func migrateNameIndexes(db *gorm.DB) error {
+	for _, index := range nameIndexes {
+		var duplicates int64
+		err := db.Raw(fmt.Sprintf(`
+			SELECT count(*) FROM (
+				SELECT 1 FROM %s
+				WHERE deleted_at IS NULL AND name_key <> ''
+				GROUP BY %s
+				HAVING count(*) > 1
+			) duplicates`,
+			index.table, index.columns,
+		)).Scan(&duplicates).Error
+		if err != nil {
+			return err
+		}
+		if duplicates > 0 {
+			log.Warn().
+				Str("index", index.name).
+				Int64("duplicates", duplicates).
+				Msgf("%s share names, merge them to get the unique name index", index.table)
+			continue
+		}
+
+		err = db.Exec(fmt.Sprintf(
+			"CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (%s) WHERE deleted_at IS NULL AND name_key <> ''",
+			index.name, index.table, index.columns,
+		)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillKeys writes column of every row of T, deleted ones included, that
+// still has an empty key, from what key derives.
+func backfillKeys[T any](db *gorm.DB, column string, key func(*T) string) error {
+	var rows []T
+	return db.Unscoped().Where(column+" = ''").FindInBatches(&rows, 500, func(tx *gorm.DB, _ int) error {
+		for i := range rows {
+			value := key(&rows[i])
+			if value == "" {
+				continue
+			}
+			err := db.Unscoped().Model(&rows[i]).UpdateColumn(column, value).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// keyed adds key to the patched columns when one of the sources it is
+// derived from is among them.
+func keyed(columns []string, key string, sources ...string) []string {
+	for _, source := range sources {
+		if slices.Contains(columns, source) {
+			return append(append([]string{}, columns...), key)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,298 @@
+package database
+
+import (
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"starter/internal/core/filter"
+	"starter/internal/core/pagination"
+	"starter/internal/core/shelf"
+)
+
+type ShelfRepository struct {
+}
+
+func NewShelfRepository() shelf.Repository {
+	return &ShelfRepository{}
+}
+
+func (repository *ShelfRepository) Save(db *gorm.DB, shelf *shelf.Shelf) error {
+	result := db.Omit(clause.Associations).Create(shelf)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to save shelf")
+		return result.Error
+	}
+
+	return nil
+}
+
+func (repository *ShelfRepository) Update(db *gorm.DB, shelf *shelf.Shelf) error {
+	err := patchVersioned(db, shelf, &shelf.Version, []string{"name", "visibility"})
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to update shelf")
+		return err
+	}
+
+	return nil
+}
+
+func (repository *ShelfRepository) Delete(db *gorm.DB, id int, version uint) error {
+	err := deleteVersioned(db, &shelf.Shelf{}, id, version)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to delete shelf")
+		return err
+	}
+
+	return nil
+}
+
+// EnsureDefaults inserts the default shelves the user is missing. Conflicts
+// are skipped, so concurrent first requests and custom shelves that already
+// took a default name leave things as they are.
+func (repository *ShelfRepository) EnsureDefaults(db *gorm.DB, userID uint, shelves []shelf.Shelf) error {
+	var count int64
+	result := db.Model(&shelf.Shelf{}).
+		Where("user_id = ? AND kind <> ?", userID, shelf.KindCustom).
+		Count(&count)
+	if result.Error == nil && int(count) < len(shelves) {
+		result = db.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&shelves)
+	}
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to create default shelves")
+	}
+
+	return result.Error
+}
+
+func (repository *ShelfRepository) FindAll(db *gorm.DB, params pagination.Request, filter filter.ShelfFilter) ([]shelf.Shelf, pagination.Meta, error) {
+	var shelves []shelf.Shelf
+
+	query := db.Model(&shelf.Shelf{})
+	if filter.UserID != 0 {
+		query = query.Where("shelves.user_id = ?", filter.UserID)
+	}
+	if filter.Visibility != "" {
+		query = query.Where("shelves.visibility = ?", filter.Visibility)
+	}
+	if filter.Search != "" {
+		query = query.Where("shelves.name ILIKE ?", "%"+filter.Search+"%")
+	}
+
+	meta, err := paginate(query, "shelves", params, &shelves)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to find all shelves")
+	}
+
+	return shelves, meta, err
+}
+
+// withItems preloads the items of a shelf in order, leaving out books that
+// are in the trash.
+func withItems(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.
+				Joins("JOIN books ON books.id = shelf_items.book_id AND books.deleted_at IS NULL").
+				Order("shelf_items.position")
+		}).
+		Preload("Items.Book")
+}
+
+func (repository *ShelfRepository) FindByID(db *gorm.DB, id int) (shelf.Shelf, error) {
+	var shelf shelf.Shelf
+	result := withItems(db).First(&shelf, id)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find shelf")
+		return shelf, result.Error
+	}
+
+	return shelf, nil
+}
+
+func (repository *ShelfRepository) FindByToken(db *gorm.DB, token string) (shelf.Shelf, error) {
+	var shelf shelf.Shelf
+	result := withItems(db).Where("share_token = ?", token).First(&shelf)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find shared shelf")
+		return shelf, result.Error
+	}
+
+	return shelf, nil
+}
+
+func (repository *ShelfRepository) NameTaken(db *gorm.DB, userID uint, name string, except uint) (bool, error) {
+	var count int64
+	result := db.Model(&shelf.Shelf{}).
+		Where("user_id = ? AND name = ? AND id <> ?", userID, name, except).
+		Count(&count)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to check shelf name")
+	}
+
+	return count > 0, result.Error
+}
+
+func (repository *ShelfRepository) BookExists(db *gorm.DB, id int) (bool, error) {
+	var count int64
+	result := db.Table("books").
+		Where("id = ? AND deleted_at IS NULL", id).
+		Count(&count)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to check book")
+	}
+
+	return count > 0, result.Error
+}
+
+func (repository *ShelfRepository) FindItem(db *gorm.DB, shelfID uint, bookID uint) (shelf.Item, error) {
+	var item shelf.Item
+	result := db.Where("shelf_id = ? AND book_id = ?", shelfID, bookID).First(&item)
+	if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find shelf item")
+	}
+
+	return item, result.Error
+}
+
+// SaveItem inserts item at its position, moving the items from there on one
+// down. Positions past the end, and zero, append.
+func (repository *ShelfRepository) SaveItem(db *gorm.DB, item *shelf.Item) error {
+	err := lockShelf(db, item.ShelfID)
+	var last int
+	if err == nil {
+		last, err = lastPosition(db, item.ShelfID)
+	}
+	if err == nil {
+		if item.Position < 1 || item.Position > last {
+			item.Position = last + 1
+		}
+		err = db.Exec(
+			"UPDATE shelf_items SET position = position + 1 WHERE shelf_id = ? AND position >= ?",
+			item.ShelfID, item.Position,
+		).Error
+	}
+	if err == nil {
+		err = db.Omit(clause.Associations).Create(item).Error
+	}
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to save shelf item")
+	}
+
+	return err
+}
+
+// UpdateItem writes the note of item and moves it to position, shifting the
+// items in between. A zero position keeps it where it is.
+func (repository *ShelfRepository) UpdateItem(db *gorm.DB, item *shelf.Item, position int) error {
+	err := lockShelf(db, item.ShelfID)
+	if err == nil {
+		err = refreshPosition(db, item)
+	}
+	var last int
+	if err == nil {
+		last, err = lastPosition(db, item.ShelfID)
+	}
+	if err == nil && position != 0 && position != item.Position {
+		position = min(max(position, 1), last)
+		if position < item.Position {
+			err = db.Exec(
+				"UPDATE shelf_items SET position = position + 1 WHERE shelf_id = ? AND position >= ? AND position < ?",
+				item.ShelfID, position, item.Position,
+			).Error
+		} else {
+			err = db.Exec(
+				"UPDATE shelf_items SET position = position - 1 WHERE shelf_id = ? AND position > ? AND position <= ?",
+				item.ShelfID, item.Position, position,
+			).Error
+		}
+		item.Position = position
+	}
+	if err == nil {
+		err = db.Model(item).Select("note", "position").Updates(item).Error
+	}
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to update shelf item")
+	}
+
+	return err
+}
+
+func (repository *ShelfRepository) DeleteItem(db *gorm.DB, item *shelf.Item) error {
+	err := lockShelf(db, item.ShelfID)
+	if err == nil {
+		err = refreshPosition(db, item)
+	}
+	if err == nil {
+		err = db.Delete(item).Error
+	}
+	if err == nil {
+		err = db.Exec(
+			"UPDATE shelf_items SET position = position - 1 WHERE shelf_id = ? AND position > ?",
+			item.ShelfID, item.Position,
+		).Error
+	}
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to delete shelf item")
+	}
+
+	return err
+}
+
+// lockShelf takes the row lock of a shelf for the rest of the transaction.
+// Item writes hold it while they shift positions, so that concurrent ones
+// don't leave duplicates or gaps.
+func lockShelf(db *gorm.DB, shelfID uint) error {
+	var ids []uint
+	return db.Model(&shelf.Shelf{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", shelfID).
+		Pluck("id", &ids).Error
+}
+
+// refreshPosition reloads the position of item once the shelf is locked, the
+// one read before may have been shifted since.
+func refreshPosition(db *gorm.DB, item *shelf.Item) error {
+	result := db.Model(&shelf.Item{}).
+		Where("id = ?", item.ID).
+		Select("position").
+		Scan(&item.Position)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+func lastPosition(db *gorm.DB, shelfID uint) (int, error) {
+	var last int
+	err := db.Model(&shelf.Item{}).
+		Where("shelf_id = ?", shelfID).
+		Select("coalesce(max(position), 0)").
+		Scan(&last).Error
+	return last, err
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"starter/internal/core/tag"
+)
+
+// tagLinks is every book-tag pair, free-form tags and subject headings alike.
+const tagLinks = `(
+	SELECT book_id, tag_id FROM book_tag
+	UNION ALL
+	SELECT book_id, tag_id FROM book_subject
+) links`
+
+type TagRepository struct {
+}
+
+func NewTagRepository() tag.Repository {
+	return &TagRepository{}
+}
+
+func (repository *TagRepository) Save(db *gorm.DB, tag *tag.Tag) error {
+	result := db.Create(tag)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to save tag")
+	}
+
+	return result.Error
+}
+
+// Delete removes the tag, its links to books go along through the foreign
+// keys of the join tables.
+func (repository *TagRepository) Delete(db *gorm.DB, id uint) error {
+	result := db.Delete(&tag.Tag{}, id)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to delete tag")
+	}
+
+	return result.Error
+}
+
+func (repository *TagRepository) FindByID(db *gorm.DB, id uint) (tag.Tag, error) {
+	var tag tag.Tag
+	result := db.Take(&tag, id)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find tag")
+	}
+
+	return tag, result.Error
+}
+
+func (repository *TagRepository) FindBySlug(db *gorm.DB, kind string, slug string) (tag.Tag, error) {
+	var tag tag.Tag
+	result := db.Where("kind = ? AND slug = ?", kind, slug).Take(&tag)
+	if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find tag by slug")
+	}
+
+	return tag, result.Error
+}
+
+// Search completes prefix against the slugs of kind, any kind when empty.
+// Slugs hold nothing LIKE would read as a wildcard.
+func (repository *TagRepository) Search(db *gorm.DB, kind string, prefix string, limit int) ([]tag.Count, error) {
+	query := countTags(db)
+	if kind != "" {
+		query = query.Where("tags.kind = ?", kind)
+	}
+	if prefix != "" {
+		query = query.Where("tags.slug LIKE ?", prefix+"%")
+	}
+
+	var counts []tag.Count
+	result := query.Order("books DESC, tags.slug, tags.id").Limit(limit).Scan(&counts)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to search tags")
+	}
+
+	return counts, result.Error
+}
+
+// Cloud lists the tags of kind carried by at least one live book, the most
+// used first.
+func (repository *TagRepository) Cloud(db *gorm.DB, kind string, limit int) ([]tag.Count, error) {
+	var counts []tag.Count
+	result := countTags(db).
+		Where("tags.kind = ?", kind).
+		Having("count(books.id) > 0").
+		Order("books DESC, tags.slug, tags.id").
+		Limit(limit).
+		Scan(&counts)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to count tags")
+	}
+
+	return counts, result.Error
+}
+
+func countTags(db *gorm.DB) *gorm.DB {
+	return db.Model(&tag.Tag{}).
+		Select("tags.*, count(books.id) AS books").
+		Joins("LEFT JOIN " + tagLinks + " ON links.tag_id = tags.id").
+		Joins("LEFT JOIN books ON books.id = links.book_id AND books.deleted_at IS NULL").
+		Group("tags.id")
+}
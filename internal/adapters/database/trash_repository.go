@@ -0,0 +1,129 @@
+package database
+
+import (
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"starter/internal/core/pagination"
+	"starter/internal/core/trash"
+)
+
+// TrashRepository works on the tables described by trash.Kinds directly, the
+// entity models would hide the soft-deleted rows it is all about.
+type TrashRepository struct {
+}
+
+func NewTrashRepository() trash.Repository {
+	return &TrashRepository{}
+}
+
+func trashed(db *gorm.DB, kind trash.Kind) *gorm.DB {
+	return db.Table(kind.Table).
+		Select(fmt.Sprintf("%[1]s.id, %[2]s AS label, %[1]s.created_at, %[1]s.deleted_at", kind.Table, kind.Label)).
+		Where(kind.Table + ".deleted_at IS NOT NULL")
+}
+
+func (repository *TrashRepository) FindAll(db *gorm.DB, kind trash.Kind, params pagination.Request) ([]trash.Item, pagination.Meta, error) {
+	var items []trash.Item
+
+	meta, err := paginate(trashed(db, kind), kind.Table, params, &items)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to find trashed %s", kind.Table)
+	}
+
+	return items, meta, err
+}
+
+func (repository *TrashRepository) FindByID(db *gorm.DB, kind trash.Kind, id int) (trash.Item, error) {
+	var item trash.Item
+	result := trashed(db, kind).
+		Where(kind.Table+".id = ?", id).
+		Take(&item)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to find trashed %s", kind.Table)
+		return item, result.Error
+	}
+
+	return item, nil
+}
+
+func (repository *TrashRepository) CountReferences(db *gorm.DB, reference trash.Reference, id int) (int64, error) {
+	var count int64
+	result := db.Table(reference.Table).
+		Where(reference.Column+" = ?", id).
+		Count(&count)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to count references from %s", reference.Table)
+		return 0, result.Error
+	}
+
+	return count, nil
+}
+
+func (repository *TrashRepository) ParentTrashed(db *gorm.DB, kind trash.Kind, parent trash.Reference, id int) (bool, error) {
+	var count int64
+	result := db.Table(kind.Table).
+		Joins(fmt.Sprintf("JOIN %[1]s parent ON parent.id = %[2]s.%[3]s", parent.Table, kind.Table, parent.Column)).
+		Where(kind.Table+".id = ? AND parent.deleted_at IS NOT NULL", id).
+		Count(&count)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to check %s of %s", parent.Table, kind.Table)
+		return false, result.Error
+	}
+
+	return count > 0, nil
+}
+
+func (repository *TrashRepository) Restore(db *gorm.DB, kind trash.Kind, id int) error {
+	result := db.Table(kind.Table).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]any{
+			"deleted_at": nil,
+			"updated_at": gorm.Expr("now()"),
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to restore %s", kind.Table)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (repository *TrashRepository) Purge(db *gorm.DB, kind trash.Kind, id int) error {
+	for _, owned := range kind.Owned {
+		result := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", owned.Table, owned.Column), id)
+		if result.Error != nil {
+			log.Ctx(db.Statement.Context).Error().
+				Err(result.Error).
+				Msgf("Failed to purge %s of %s", owned.Table, kind.Table)
+			return result.Error
+		}
+	}
+
+	result := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = ? AND deleted_at IS NOT NULL", kind.Table), id)
+	if result.Error != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(result.Error).
+			Msgf("Failed to purge %s", kind.Table)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
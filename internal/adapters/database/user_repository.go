@@ -1,10 +1,10 @@
 package database
 
 import (
-	"fmt"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 	"starter/internal/core/pagination"
+	"starter/internal/core/projection"
 	"starter/internal/core/role"
 	"starter/internal/core/user"
 )
@@ -20,7 +20,7 @@ func (repository *UserRepository) Save(db *gorm.DB, user *user.User) error {
 	var role role.Role
 	result := db.FirstOrCreate(&role, user.Role)
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
 			Msgf("Failed to save role")
 		return result.Error
@@ -29,7 +29,7 @@ func (repository *UserRepository) Save(db *gorm.DB, user *user.User) error {
 
 	result = db.Create(user)
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
 			Msgf("Failed to save user")
 		return result.Error
@@ -39,56 +39,59 @@ func (repository *UserRepository) Save(db *gorm.DB, user *user.User) error {
 }
 
 func (repository *UserRepository) Update(db *gorm.DB, user *user.User) error {
-	result := db.Save(user)
-	if result.Error != nil {
-		log.Error().
-			Err(result.Error).
+	err := updateVersioned(db.Select("*"), user, &user.Version)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
 			Msgf("Failed to save user")
-		return result.Error
+		return err
 	}
 
 	return nil
 }
 
-func (repository *UserRepository) Delete(db *gorm.DB, id int) error {
-	result := db.Delete(&user.User{}, id)
-	if result.Error != nil {
-		log.Error().
-			Err(result.Error).
-			Msgf("Failed to delete user")
-		return result.Error
+func (repository *UserRepository) Patch(db *gorm.DB, user *user.User, columns []string) error {
+	err := patchVersioned(db, user, &user.Version, columns)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to patch user")
+		return err
 	}
 
 	return nil
 }
 
-func (repository *UserRepository) FindAll(db *gorm.DB, params pagination.Request) ([]user.User, int64, error) {
-	var users []user.User
-	var count int64
+func (repository *UserRepository) Delete(db *gorm.DB, id int, version uint) error {
+	err := deleteVersioned(db, &user.User{}, id, version)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
+			Msgf("Failed to delete user")
+		return err
+	}
 
-	db.Model(&users).Count(&count)
+	return nil
+}
 
-	result := db.
-		Debug().
-		Order(fmt.Sprintf("%s %s", params.OrderBy, params.SortBy)).
-		Limit(params.Limit).
-		Offset((params.Page - 1) * params.Limit).
-		Find(&users)
+func (repository *UserRepository) FindAll(db *gorm.DB, params pagination.Request, view projection.Request) ([]user.User, pagination.Meta, error) {
+	var users []user.User
 
-	if result.Error != nil {
-		log.Error().
-			Err(result.Error).
+	meta, err := paginate(project(db.Model(&user.User{}), view), "users", params, &users)
+	if err != nil {
+		log.Ctx(db.Statement.Context).Error().
+			Err(err).
 			Msgf("Failed to find all users")
 	}
 
-	return users, count, result.Error
+	return users, meta, err
 }
 
-func (repository *UserRepository) FindByID(db *gorm.DB, id int) (user.User, error) {
+func (repository *UserRepository) FindByID(db *gorm.DB, id int, view projection.Request) (user.User, error) {
 	var user user.User
-	result := db.Preload("Role").First(&user, id)
+	result := project(db, view, "Role").First(&user, id)
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
 			Msgf("Failed to find user")
 		return user, result.Error
@@ -97,11 +100,11 @@ func (repository *UserRepository) FindByID(db *gorm.DB, id int) (user.User, erro
 	return user, nil
 }
 
-func (repository *UserRepository) FindByEmail(db *gorm.DB, email string) (user.User, error) {
+func (repository *UserRepository) FindByEmail(db *gorm.DB, email string, view projection.Request) (user.User, error) {
 	var user user.User
-	result := db.Where("email = ?", email).Preload("Role").First(&user)
+	result := project(db.Where("email = ?", email), view, "Role").First(&user)
 	if result.Error != nil {
-		log.Error().
+		log.Ctx(db.Statement.Context).Error().
 			Err(result.Error).
 			Msgf("Failed to find user")
 		return user, result.Error
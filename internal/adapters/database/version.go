@@ -0,0 +1,51 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"starter/internal/core/concurrency"
+)
+
+// updateVersioned writes entity only while its row still carries the version
+// the caller read, and bumps that version in the same UPDATE. version must
+// point at the entity's Version field.
+func updateVersioned(db *gorm.DB, entity any, version *uint) error {
+	expected := *version
+	*version = expected + 1
+
+	result := db.Where("version = ?", expected).Updates(entity)
+	if result.Error != nil {
+		*version = expected
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		*version = expected
+		return concurrency.ErrVersionMismatch
+	}
+
+	return nil
+}
+
+// patchVersioned is updateVersioned restricted to columns, so zero values in
+// them are written as well. Associations are left to the caller.
+func patchVersioned(db *gorm.DB, entity any, version *uint, columns []string) error {
+	columns = append(append([]string{}, columns...), "version")
+	return updateVersioned(db.Model(entity).Select(columns), entity, version)
+}
+
+// deleteVersioned deletes the row with id, checking its version first when the
+// caller sent one. A zero version deletes unconditionally.
+func deleteVersioned(db *gorm.DB, model any, id int, version uint) error {
+	if version != 0 {
+		db = db.Where("version = ?", version)
+	}
+
+	result := db.Delete(model, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if version != 0 && result.RowsAffected == 0 {
+		return concurrency.ErrVersionMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"context"
+	"starter/internal/core/idempotency"
+	"sync"
+	"time"
+)
+
+// IdempotencyStore keeps records in process memory. It is meant for tests and
+// single instance setups, records are lost on restart.
+type IdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]idempotency.Record
+}
+
+func NewIdempotencyStore() idempotency.Store {
+	return &IdempotencyStore{
+		records: map[string]idempotency.Record{},
+	}
+}
+
+func (store *IdempotencyStore) Reserve(ctx context.Context, record idempotency.Record) (*idempotency.Record, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	for key, existing := range store.records {
+		if existing.ExpiresAt.Before(now) {
+			delete(store.records, key)
+		}
+	}
+
+	if existing, ok := store.records[record.Key]; ok {
+		return &existing, nil
+	}
+
+	record.CreatedAt = now
+	store.records[record.Key] = record
+	return nil, nil
+}
+
+func (store *IdempotencyStore) Complete(ctx context.Context, record idempotency.Record) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	record.Completed = true
+	store.records[record.Key] = record
+	return nil
+}
+
+func (store *IdempotencyStore) Release(ctx context.Context, key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.records, key)
+	return nil
+}
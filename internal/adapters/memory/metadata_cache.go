@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"golang.org/x/sync/singleflight"
+	"slices"
+	"starter/internal/core/book"
+	"sync"
+	"time"
+)
+
+// MetadataCache keeps what the provider it wraps answered for ttl, misses
+// included, so that looking the same ISBN up again stays in process. Errors
+// other than a miss are not kept. At most size ISBNs are kept, the least
+// recently used goes first, and concurrent lookups of an ISBN that isn't
+// cached share one call to the provider.
+type MetadataCache struct {
+	next  book.MetadataProvider
+	ttl   time.Duration
+	size  int
+	group singleflight.Group
+
+	mu sync.Mutex
+	// order holds the entries, the most recently used in front.
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type metadataEntry struct {
+	isbn string
+	// metadata is nil for an ISBN the provider doesn't know.
+	metadata  *book.Metadata
+	expiresAt time.Time
+}
+
+func NewMetadataCache(next book.MetadataProvider, ttl time.Duration, size int) book.MetadataProvider {
+	return &MetadataCache{
+		next:    next,
+		ttl:     ttl,
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func (cache *MetadataCache) Lookup(ctx context.Context, isbn string) (*book.Metadata, error) {
+	if entry, ok := cache.get(isbn); ok {
+		return entry.result()
+	}
+
+	// The call is shared with the lookups waiting on it, so it must not be
+	// cut short when the caller that started it goes away.
+	ctx = context.WithoutCancel(ctx)
+	found, err, _ := cache.group.Do(isbn, func() (any, error) {
+		metadata, err := cache.next.Lookup(ctx, isbn)
+		if err != nil && !errors.Is(err, book.ErrMetadataNotFound) {
+			return nil, err
+		}
+
+		entry := metadataEntry{
+			isbn:      isbn,
+			metadata:  metadata,
+			expiresAt: time.Now().Add(cache.ttl),
+		}
+		cache.put(entry)
+		return entry, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found.(metadataEntry).result()
+}
+
+// result hands out a copy, so that callers can't change what is cached.
+func (entry metadataEntry) result() (*book.Metadata, error) {
+	if entry.metadata == nil {
+		return nil, book.ErrMetadataNotFound
+	}
+	metadata := *entry.metadata
+	metadata.Authors = slices.Clone(metadata.Authors)
+	return &metadata, nil
+}
+
+// get returns the entry of isbn unless it expired, which is dropped then.
+func (cache *MetadataCache) get(isbn string) (metadataEntry, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	element, ok := cache.entries[isbn]
+	if !ok {
+		return metadataEntry{}, false
+	}
+	entry := element.Value.(metadataEntry)
+	if !entry.expiresAt.After(time.Now()) {
+		cache.order.Remove(element)
+		delete(cache.entries, isbn)
+		return metadataEntry{}, false
+	}
+
+	cache.order.MoveToFront(element)
+	return entry, true
+}
+
+// put stores entry and evicts the least recently used ones past size.
+func (cache *MetadataCache) put(entry metadataEntry) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if element, ok := cache.entries[entry.isbn]; ok {
+		element.Value = entry
+		cache.order.MoveToFront(element)
+	} else {
+		cache.entries[entry.isbn] = cache.order.PushFront(entry)
+	}
+
+	for cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(metadataEntry).isbn)
+	}
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"starter/internal/core/book"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingProvider answers from found and counts the lookups it gets. A
+// lookup waits for release when it is set.
+type countingProvider struct {
+	found   map[string]book.Metadata
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (provider *countingProvider) Lookup(ctx context.Context, isbn string) (*book.Metadata, error) {
+	provider.calls.Add(1)
+	if provider.release != nil {
+		<-provider.release
+	}
+	metadata, ok := provider.found[isbn]
+	if !ok {
+		return nil, book.ErrMetadataNotFound
+	}
+	return &metadata, nil
+}
+
+func TestMetadataCacheHit(t *testing.T) {
+	provider := &countingProvider{found: map[string]book.Metadata{
+		"9780140328721": {ISBN: "9780140328721", Title: "Fantastic Mr Fox", Authors: []string{"Roald Dahl"}},
+	}}
+	cache := NewMetadataCache(provider, time.Hour, 10)
+
+	first, err := cache.Lookup(context.Background(), "9780140328721")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	first.Authors[0] = "changed"
+
+	second, err := cache.Lookup(context.Background(), "9780140328721")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if second.Title != "Fantastic Mr Fox" || second.Authors[0] != "Roald Dahl" {
+		t.Errorf("Lookup() = %+v, want the cached metadata unchanged", *second)
+	}
+
+	for range 2 {
+		_, err = cache.Lookup(context.Background(), "9780000000000")
+		if !errors.Is(err, book.ErrMetadataNotFound) {
+			t.Errorf("Lookup() error = %v, want %v", err, book.ErrMetadataNotFound)
+		}
+	}
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("provider called %d times, want 2", calls)
+	}
+}
+
+func TestMetadataCacheExpiry(t *testing.T) {
+	provider := &countingProvider{found: map[string]book.Metadata{}}
+	cache := NewMetadataCache(provider, time.Millisecond, 10)
+
+	cache.Lookup(context.Background(), "9780140328721")
+	time.Sleep(5 * time.Millisecond)
+	cache.Lookup(context.Background(), "9780140328721")
+
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("provider called %d times, want 2", calls)
+	}
+}
+
+func TestMetadataCacheEviction(t *testing.T) {
+	provider := &countingProvider{found: map[string]book.Metadata{}}
+	cache := NewMetadataCache(provider, time.Hour, 2)
+
+	for _, isbn := range []string{"1", "2", "1", "3", "1", "2"} {
+		cache.Lookup(context.Background(), isbn)
+	}
+
+	// 2 is the least recently used when 3 comes in, so only it is looked up
+	// again.
+	if calls := provider.calls.Load(); calls != 4 {
+		t.Errorf("provider called %d times, want 4", calls)
+	}
+}
+
+func TestMetadataCacheSharesMisses(t *testing.T) {
+	provider := &countingProvider{found: map[string]book.Metadata{}, release: make(chan struct{})}
+	cache := NewMetadataCache(provider, time.Hour, 10)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Lookup(context.Background(), "9780140328721")
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Errorf("provider called %d times, want 1", calls)
+	}
+}
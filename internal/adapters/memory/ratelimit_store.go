@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"starter/internal/core/ratelimit"
+	"sync"
+	"time"
+)
+
+// RateLimitStore keeps sliding windows and lockouts in process memory, so
+// limits are per instance and reset on restart.
+type RateLimitStore struct {
+	mu        sync.Mutex
+	hits      map[string][]time.Time
+	lockouts  map[string]ratelimit.Lockout
+	lastSweep time.Time
+}
+
+func NewRateLimitStore() ratelimit.Store {
+	return &RateLimitStore{
+		hits:     map[string][]time.Time{},
+		lockouts: map[string]ratelimit.Lockout{},
+	}
+}
+
+func (store *RateLimitStore) Take(ctx context.Context, key string, now time.Time, limit ratelimit.Limit) (ratelimit.Window, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.sweep(now, limit.Window)
+
+	var window ratelimit.Window
+	hits := prune(store.hits[key], now.Add(-limit.Window))
+	if len(hits) < limit.Requests {
+		hits = append(hits, now)
+		window.Allowed = true
+	}
+	store.hits[key] = hits
+
+	window.Count = len(hits)
+	if len(hits) > 0 {
+		window.Oldest = hits[0]
+	}
+	return window, nil
+}
+
+func (store *RateLimitStore) Lockout(ctx context.Context, key string) (ratelimit.Lockout, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	lockout, ok := store.lockouts[key]
+	if !ok {
+		lockout.Key = key
+	}
+	return lockout, nil
+}
+
+func (store *RateLimitStore) Fail(ctx context.Context, key string, now time.Time, backoff ratelimit.Backoff) (ratelimit.Lockout, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	lockout, ok := store.lockouts[key]
+	if !ok || (backoff.Forget > 0 && lockout.UpdatedAt.Before(now.Add(-backoff.Forget))) {
+		lockout = ratelimit.Lockout{Key: key}
+	}
+
+	lockout.Failures++
+	lockout.UpdatedAt = now
+	if duration := backoff.Duration(lockout.Failures); duration > 0 {
+		lockout.LockedUntil = now.Add(duration)
+	}
+	store.lockouts[key] = lockout
+
+	return lockout, nil
+}
+
+func (store *RateLimitStore) Reset(ctx context.Context, key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.lockouts, key)
+	return nil
+}
+
+// sweep drops keys whose hits all left the window, at most once per window so
+// clients that never come back don't pile up.
+func (store *RateLimitStore) sweep(now time.Time, window time.Duration) {
+	if now.Sub(store.lastSweep) < window {
+		return
+	}
+	store.lastSweep = now
+
+	for key, hits := range store.hits {
+		if hits = prune(hits, now.Add(-window)); len(hits) == 0 {
+			delete(store.hits, key)
+		} else {
+			store.hits[key] = hits
+		}
+	}
+}
+
+func prune(hits []time.Time, since time.Time) []time.Time {
+	for len(hits) > 0 && !hits[0].After(since) {
+		hits = hits[1:]
+	}
+	return hits
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"starter/internal/core/ratelimit"
+	"testing"
+	"time"
+)
+
+func TestRateLimitStoreTake(t *testing.T) {
+	store := NewRateLimitStore()
+	limit := ratelimit.Limit{Requests: 2, Window: time.Minute}
+	start := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		at          time.Duration
+		wantAllowed bool
+		wantCount   int
+	}{
+		{0, true, 1},
+		{10 * time.Second, true, 2},
+		{20 * time.Second, false, 2},
+		// The first hit leaves the window, so there is room for one more.
+		{61 * time.Second, true, 2},
+		{62 * time.Second, false, 2},
+	}
+
+	for _, test := range tests {
+		window, err := store.Take(context.Background(), "login:1.2.3.4", start.Add(test.at), limit)
+		if err != nil {
+			t.Fatalf("Take() error = %v", err)
+		}
+		if window.Allowed != test.wantAllowed || window.Count != test.wantCount {
+			t.Errorf("Take() at %s = %+v, want allowed %v and count %d", test.at, window, test.wantAllowed, test.wantCount)
+		}
+	}
+
+	window, _ := store.Take(context.Background(), "login:5.6.7.8", start, limit)
+	if !window.Allowed || window.Count != 1 {
+		t.Errorf("Take() for another key = %+v, want its own window", window)
+	}
+}
+
+func TestRateLimitStoreFail(t *testing.T) {
+	store := NewRateLimitStore()
+	backoff := ratelimit.Backoff{Threshold: 2, Base: time.Minute, Max: time.Hour, Forget: time.Hour}
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	lockout, _ := store.Fail(context.Background(), "user@example.com", now, backoff)
+	if _, locked := lockout.Locked(now); locked {
+		t.Errorf("Fail() locked after %d failures", lockout.Failures)
+	}
+
+	lockout, _ = store.Fail(context.Background(), "user@example.com", now, backoff)
+	if remaining, locked := lockout.Locked(now); !locked || remaining != time.Minute {
+		t.Errorf("Fail() = locked %v for %s, want locked for 1m", locked, remaining)
+	}
+
+	// Failures older than Forget start the count over.
+	later := now.Add(2 * time.Hour)
+	lockout, _ = store.Fail(context.Background(), "user@example.com", later, backoff)
+	if lockout.Failures != 1 {
+		t.Errorf("Fail() after Forget counted %d failures, want 1", lockout.Failures)
+	}
+
+	if err := store.Reset(context.Background(), "user@example.com"); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	lockout, _ = store.Lockout(context.Background(), "user@example.com")
+	if lockout.Failures != 0 {
+		t.Errorf("Lockout() after Reset = %+v, want no failures", lockout)
+	}
+}
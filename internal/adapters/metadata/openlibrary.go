@@ -0,0 +1,162 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"starter/internal/core/book"
+	"strings"
+	"time"
+)
+
+// publishDateLayouts are the shapes Open Library publish dates come in, the
+// less precise ones last.
+var publishDateLayouts = []string{
+	"2006-01-02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"January 2006",
+	"Jan 2006",
+	"2006",
+}
+
+// OpenLibrary looks editions up through the Open Library books API. The base
+// URL is configurable so that it can be pointed at a mirror or a stub.
+type OpenLibrary struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewOpenLibrary(baseURL string, timeout time.Duration) book.MetadataProvider {
+	return &OpenLibrary{
+		client:  &http.Client{Timeout: timeout},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+type openLibraryBook struct {
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle"`
+	Authors  []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+	Publishers []struct {
+		Name string `json:"name"`
+	} `json:"publishers"`
+	NumberOfPages int    `json:"number_of_pages"`
+	PublishDate   string `json:"publish_date"`
+	// Notes is either a string or a {"type", "value"} text object.
+	Notes    json.RawMessage `json:"notes"`
+	Excerpts []struct {
+		Text string `json:"text"`
+	} `json:"excerpts"`
+	Cover struct {
+		Small  string `json:"small"`
+		Medium string `json:"medium"`
+		Large  string `json:"large"`
+	} `json:"cover"`
+}
+
+func (provider *OpenLibrary) Lookup(ctx context.Context, isbn string) (*book.Metadata, error) {
+	key := "ISBN:" + isbn
+	query := url.Values{
+		"bibkeys": {key},
+		"format":  {"json"},
+		"jscmd":   {"data"},
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.baseURL+"/api/books?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := provider.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, book.ErrMetadataNotFound
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("open library answered with status %d", response.StatusCode)
+	}
+
+	var books map[string]openLibraryBook
+	if err = json.NewDecoder(response.Body).Decode(&books); err != nil {
+		return nil, fmt.Errorf("failed to decode the open library response: %w", err)
+	}
+	found, ok := books[key]
+	if !ok {
+		return nil, book.ErrMetadataNotFound
+	}
+
+	return found.toMetadata(isbn), nil
+}
+
+func (found openLibraryBook) toMetadata(isbn string) *book.Metadata {
+	metadata := &book.Metadata{
+		ISBN:            isbn,
+		Title:           found.Title,
+		PageCount:       found.NumberOfPages,
+		PublicationDate: parsePublishDate(found.PublishDate),
+	}
+	if found.Subtitle != "" {
+		metadata.Title += ": " + found.Subtitle
+	}
+	for _, author := range found.Authors {
+		metadata.Authors = append(metadata.Authors, author.Name)
+	}
+	if len(found.Publishers) > 0 {
+		metadata.Publisher = found.Publishers[0].Name
+	}
+
+	metadata.Description = text(found.Notes)
+	if metadata.Description == "" && len(found.Excerpts) > 0 {
+		metadata.Description = found.Excerpts[0].Text
+	}
+
+	for _, cover := range []string{found.Cover.Large, found.Cover.Medium, found.Cover.Small} {
+		if cover != "" {
+			metadata.Cover = cover
+			break
+		}
+	}
+
+	return metadata
+}
+
+// text reads an Open Library text field, which is a plain string or an
+// object holding it in value.
+func text(raw json.RawMessage) string {
+	var plain string
+	if json.Unmarshal(raw, &plain) == nil {
+		return strings.TrimSpace(plain)
+	}
+
+	var typed struct {
+		Value string `json:"value"`
+	}
+	if json.Unmarshal(raw, &typed) == nil {
+		return strings.TrimSpace(typed.Value)
+	}
+	return ""
+}
+
+// parsePublishDate turns a publish date into yyyy-mm-dd, or an empty string
+// when it is in none of the known layouts.
+func parsePublishDate(date string) string {
+	date = strings.TrimSpace(date)
+	for _, layout := range publishDateLayouts {
+		parsed, err := time.Parse(layout, date)
+		if err == nil {
+			return parsed.Format("2006-01-02")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"starter/internal/core/book"
+	"testing"
+	"time"
+)
+
+// newServer answers every books API request with status and body, and checks
+// that the request asks for isbn.
+func newServer(t *testing.T, isbn string, status int, body string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/api/books" || query.Get("bibkeys") != "ISBN:"+isbn || query.Get("jscmd") != "data" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenLibraryLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want book.Metadata
+	}{
+		{
+			name: "subtitle, notes as a string and the largest cover",
+			body: `{"ISBN:9780140328721": {
+				"title": "Fantastic Mr Fox",
+				"subtitle": "A Story",
+				"authors": [{"name": "Roald Dahl"}, {"name": "Quentin Blake"}],
+				"publishers": [{"name": "Puffin"}, {"name": "Penguin"}],
+				"number_of_pages": 96,
+				"publish_date": "October 1, 1988",
+				"notes": "  Illustrated.  ",
+				"cover": {"small": "s.jpg", "medium": "m.jpg", "large": "l.jpg"}
+			}}`,
+			want: book.Metadata{
+				ISBN:            "9780140328721",
+				Title:           "Fantastic Mr Fox: A Story",
+				Authors:         []string{"Roald Dahl", "Quentin Blake"},
+				Publisher:       "Puffin",
+				PageCount:       96,
+				Description:     "Illustrated.",
+				Cover:           "l.jpg",
+				PublicationDate: "1988-10-01",
+			},
+		},
+		{
+			name: "notes as an object and a smaller cover",
+			body: `{"ISBN:9780140328721": {
+				"title": "Fantastic Mr Fox",
+				"publish_date": "Oct 1988",
+				"notes": {"type": "/type/text", "value": "First edition."},
+				"cover": {"small": "s.jpg", "medium": "m.jpg"}
+			}}`,
+			want: book.Metadata{
+				ISBN:            "9780140328721",
+				Title:           "Fantastic Mr Fox",
+				Description:     "First edition.",
+				Cover:           "m.jpg",
+				PublicationDate: "1988-10-01",
+			},
+		},
+		{
+			name: "excerpt without notes and a year only",
+			body: `{"ISBN:9780140328721": {
+				"title": "Fantastic Mr Fox",
+				"publish_date": "1988",
+				"excerpts": [{"text": "Down in the valley"}]
+			}}`,
+			want: book.Metadata{
+				ISBN:            "9780140328721",
+				Title:           "Fantastic Mr Fox",
+				Description:     "Down in the valley",
+				PublicationDate: "1988-01-01",
+			},
+		},
+		{
+			name: "date in an unknown layout",
+			body: `{"ISBN:9780140328721": {
+				"title": "Fantastic Mr Fox",
+				"publish_date": "circa 1988"
+			}}`,
+			want: book.Metadata{
+				ISBN:  "9780140328721",
+				Title: "Fantastic Mr Fox",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newServer(t, "9780140328721", http.StatusOK, test.body)
+			provider := NewOpenLibrary(server.URL+"/", time.Second)
+
+			got, err := provider.Lookup(context.Background(), "9780140328721")
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, test.want) {
+				t.Errorf("Lookup() = %+v, want %+v", *got, test.want)
+			}
+		})
+	}
+}
+
+func TestOpenLibraryLookupNotFound(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"missing bibkey", http.StatusOK, `{}`},
+		{"not found status", http.StatusNotFound, ``},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newServer(t, "9780140328721", test.status, test.body)
+			provider := NewOpenLibrary(server.URL, time.Second)
+
+			_, err := provider.Lookup(context.Background(), "9780140328721")
+			if !errors.Is(err, book.ErrMetadataNotFound) {
+				t.Errorf("Lookup() error = %v, want %v", err, book.ErrMetadataNotFound)
+			}
+		})
+	}
+}
+
+func TestOpenLibraryLookupFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"server error", http.StatusInternalServerError, `{}`},
+		{"malformed body", http.StatusOK, `{"ISBN:9780140328721":`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newServer(t, "9780140328721", test.status, test.body)
+			provider := NewOpenLibrary(server.URL, time.Second)
+
+			_, err := provider.Lookup(context.Background(), "9780140328721")
+			if err == nil || errors.Is(err, book.ErrMetadataNotFound) {
+				t.Errorf("Lookup() error = %v, want a failure", err)
+			}
+		})
+	}
+}
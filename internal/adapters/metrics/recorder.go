@@ -0,0 +1,22 @@
+package metrics
+
+import "starter/internal/core/metrics"
+
+type PrometheusRecorder struct {
+}
+
+func NewRecorder() metrics.Recorder {
+	return &PrometheusRecorder{}
+}
+
+func (recorder *PrometheusRecorder) BookCreated() {
+	BooksCreated.Inc()
+}
+
+func (recorder *PrometheusRecorder) Login(succeeded bool) {
+	result := "failed"
+	if succeeded {
+		result = "succeeded"
+	}
+	Logins.WithLabelValues(result).Inc()
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"starter/internal/core/storage"
+	"time"
+)
+
+// InstrumentedStorage times every call to the storage it wraps. The storage
+// port reports failures as a nil response, which is what is counted as an
+// error here.
+type InstrumentedStorage struct {
+	next storage.Storage
+}
+
+func NewStorage(next storage.Storage) storage.Storage {
+	return &InstrumentedStorage{
+		next: next,
+	}
+}
+
+func (s *InstrumentedStorage) Upload(ctx context.Context, file storage.UploadRequest) *storage.Response {
+	start := time.Now()
+	response := s.next.Upload(ctx, file)
+	observe("upload", start, response == nil && file.Name != "")
+	return response
+}
+
+func (s *InstrumentedStorage) Download(ctx context.Context, file storage.DownloadRequest) *storage.Response {
+	if file.Name == "" {
+		return s.next.Download(ctx, file)
+	}
+
+	start := time.Now()
+	response := s.next.Download(ctx, file)
+	observe("download", start, response == nil)
+	return response
+}
+
+func (s *InstrumentedStorage) Delete(ctx context.Context, bucket string, name string) *storage.Response {
+	start := time.Now()
+	response := s.next.Delete(ctx, bucket, name)
+	observe("delete", start, response == nil)
+	return response
+}
+
+func (s *InstrumentedStorage) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := s.next.Ping(ctx)
+	observe("ping", start, err != nil)
+	return err
+}
+
+func observe(operation string, start time.Time, failed bool) {
+	StorageDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if failed {
+		StorageErrors.WithLabelValues(operation).Inc()
+	}
+}
@@ -20,6 +20,7 @@ type S3Storage struct {
 	client        *s3sdk.Client
 	presignClient *s3sdk.PresignClient
 	region        string
+	bucket        string
 }
 
 func NewStorage() storage.Storage {
@@ -46,6 +47,7 @@ func NewStorage() storage.Storage {
 		client:        client,
 		presignClient: presignClient,
 		region:        cfg.AppConfig.StorageRegion,
+		bucket:        cfg.AppConfig.StorageBucket,
 	}
 
 	return s3
@@ -64,7 +66,7 @@ func (s *S3Storage) Download(ctx context.Context, file storage.DownloadRequest)
 
 	presignedURL, err := s.presignClient.PresignGetObject(ctx, req, s3sdk.WithPresignExpires(30*time.Minute))
 	if err != nil {
-		log.Error().Err(err).Msg("failed to generate presigned URL")
+		log.Ctx(ctx).Error().Err(err).Msg("failed to generate presigned URL")
 		return nil
 	}
 
@@ -87,7 +89,7 @@ func (s *S3Storage) Upload(ctx context.Context, file storage.UploadRequest) *sto
 		ACL:    types.ObjectCannedACLPrivate,
 	})
 	if err != nil {
-		log.Error().
+		log.Ctx(ctx).Error().
 			Err(err).
 			Msg("failed to upload file")
 		return nil
@@ -104,7 +106,7 @@ func (s *S3Storage) Delete(ctx context.Context, bucket, name string) *storage.Re
 		Key:    aws.String(name),
 	})
 	if err != nil {
-		log.Error().
+		log.Ctx(ctx).Error().
 			Err(err).
 			Msg("failed to delete file")
 		return nil
@@ -114,3 +116,11 @@ func (s *S3Storage) Delete(ctx context.Context, bucket, name string) *storage.Re
 		Filename: name,
 	}
 }
+
+// Ping checks that the configured bucket is reachable.
+func (s *S3Storage) Ping(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3sdk.HeadBucketInput{
+		Bucket: aws.String(s.bucket),
+	})
+	return err
+}
@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"starter/internal/core/storage"
+)
+
+// TracedStorage puts a span around every call to the storage it wraps. A nil
+// response is how the storage port reports a failure.
+type TracedStorage struct {
+	next storage.Storage
+}
+
+func NewStorage(next storage.Storage) storage.Storage {
+	return &TracedStorage{
+		next: next,
+	}
+}
+
+func (s *TracedStorage) Upload(ctx context.Context, file storage.UploadRequest) *storage.Response {
+	ctx, span := start(ctx, "storage.Upload", file.Bucket, file.Name)
+	defer span.End()
+
+	response := s.next.Upload(ctx, file)
+	if response == nil && file.Name != "" {
+		span.SetStatus(codes.Error, "upload failed")
+	}
+	return response
+}
+
+func (s *TracedStorage) Download(ctx context.Context, file storage.DownloadRequest) *storage.Response {
+	ctx, span := start(ctx, "storage.Download", file.Bucket, file.Name)
+	defer span.End()
+
+	response := s.next.Download(ctx, file)
+	if response == nil && file.Name != "" {
+		span.SetStatus(codes.Error, "download failed")
+	}
+	return response
+}
+
+func (s *TracedStorage) Delete(ctx context.Context, bucket string, name string) *storage.Response {
+	ctx, span := start(ctx, "storage.Delete", bucket, name)
+	defer span.End()
+
+	response := s.next.Delete(ctx, bucket, name)
+	if response == nil {
+		span.SetStatus(codes.Error, "delete failed")
+	}
+	return response
+}
+
+func (s *TracedStorage) Ping(ctx context.Context) error {
+	ctx, span := start(ctx, "storage.Ping", "", "")
+	defer span.End()
+
+	err := s.next.Ping(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func start(ctx context.Context, name string, bucket string, object string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("storage.bucket", bucket),
+			attribute.String("storage.object", object),
+		),
+	)
+}
@@ -9,6 +9,8 @@ import (
 	enTranslation "github.com/go-playground/validator/v10/translations/en"
 	idTranslation "github.com/go-playground/validator/v10/translations/id"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/text/language"
+	"starter/internal/core/pagination"
 	ivalidator "starter/internal/core/validator"
 	"time"
 )
@@ -30,6 +32,18 @@ func (v *Validator) InitTranslation() {
 			Message: "must follow this format yyyy-mm-dd",
 			Field:   "publication_date",
 		},
+		{
+			Message: "must be a cursor returned by a previous page",
+			Field:   "cursor",
+		},
+		{
+			Message: "must be a BCP 47 language tag such as en or pt-BR",
+			Field:   "bcp47",
+		},
+		{
+			Message: "must be a valid ISBN-10 or ISBN-13",
+			Field:   "isbn",
+		},
 	}
 	v.message["id"] = []ivalidator.ValidationError{
 		{
@@ -126,6 +140,14 @@ func NewValidator() ivalidator.Validator {
 		_, err := time.Parse("2006-01-02", dateStr)
 		return err == nil
 	})
+	goValidator.RegisterValidation("cursor", func(fl validator.FieldLevel) bool {
+		_, err := pagination.DecodeCursor(fl.Field().String())
+		return err == nil
+	})
+	goValidator.RegisterValidation("bcp47", func(fl validator.FieldLevel) bool {
+		_, err := language.Parse(fl.Field().String())
+		return err == nil
+	})
 
 	validation := &Validator{
 		Instance: goValidator,
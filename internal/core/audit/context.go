@@ -0,0 +1,22 @@
+package audit
+
+import "context"
+
+type actorKey struct{}
+
+// Actor is who a write is attributed to. The HTTP layer puts it in the request
+// context, UserID stays 0 for anonymous requests such as registration.
+type Actor struct {
+	UserID    uint
+	IP        string
+	RequestID string
+}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"starter/internal/core/pagination"
+	"time"
+)
+
+var SortableFields = pagination.Sortable{
+	"id":         "audit_entries.id",
+	"created_at": "audit_entries.created_at",
+}
+
+// Change describes a write for Record. Before is nil for a create and After is
+// nil for a delete, both are usually the response DTO of the entity so that
+// secrets like password hashes never end up in the log.
+type Change struct {
+	Action     string
+	EntityType string
+	EntityID   uint
+	Before     any
+	After      any
+}
+
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+type Response struct {
+	Id         int             `json:"id"`
+	ActorID    uint            `json:"actor_id"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   uint            `json:"entity_id"`
+	Changes    json.RawMessage `json:"changes"`
+	IP         string          `json:"ip"`
+	RequestID  string          `json:"request_id"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+func ToResponse(entity *Entry) *Response {
+	return &Response{
+		Id:         int(entity.ID),
+		ActorID:    entity.ActorID,
+		Action:     entity.Action,
+		EntityType: entity.EntityType,
+		EntityID:   entity.EntityID,
+		Changes:    json.RawMessage(entity.Changes),
+		IP:         entity.IP,
+		RequestID:  entity.RequestID,
+		CreatedAt:  entity.CreatedAt,
+	}
+}
+
+// Diff compares the JSON form of before and after and returns the top level
+// fields that differ. A nil side counts as an object without any field.
+func Diff(before, after any) (map[string]FieldChange, error) {
+	from, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]FieldChange{}
+	for key, value := range from {
+		if other, ok := to[key]; !ok || !reflect.DeepEqual(value, other) {
+			changes[key] = FieldChange{Before: value, After: to[key]}
+		}
+	}
+	for key, value := range to {
+		if _, ok := from[key]; !ok {
+			changes[key] = FieldChange{After: value}
+		}
+	}
+
+	return changes, nil
+}
+
+func fields(value any) (map[string]any, error) {
+	var result map[string]any
+	if value == nil {
+		return result, nil
+	}
+
+	// A nil pointer marshals to null, which leaves result nil as well.
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(raw, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,31 @@
+package audit
+
+import "time"
+
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
+	ActionMerge   = "merge"
+)
+
+// Entry is one write made through a usecase. Changes holds the fields that
+// differ between the state before and after the write, keyed by response
+// field, as {"field": {"before": ..., "after": ...}}.
+type Entry struct {
+	ID         uint      `gorm:"primaryKey"`
+	ActorID    uint      `gorm:"index"`
+	Action     string    `gorm:"size:16;not null;index"`
+	EntityType string    `gorm:"size:32;not null;index:idx_audit_entries_entity,priority:1"`
+	EntityID   uint      `gorm:"not null;index:idx_audit_entries_entity,priority:2"`
+	Changes    string    `gorm:"type:jsonb;not null"`
+	IP         string    `gorm:"size:64"`
+	RequestID  string    `gorm:"size:128;index"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+func (Entry) TableName() string {
+	return "audit_entries"
+}
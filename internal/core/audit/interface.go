@@ -0,0 +1,24 @@
+package audit
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"starter/internal/core/filter"
+	"starter/internal/core/pagination"
+)
+
+type Repository interface {
+	Save(db *gorm.DB, entry *Entry) error
+	FindAll(db *gorm.DB, params pagination.Request, filter filter.AuditFilter) ([]Entry, pagination.Meta, error)
+}
+
+// Recorder writes an entry for a change through db, which should be the
+// transaction of the write itself so that both commit or roll back together.
+type Recorder interface {
+	Record(ctx context.Context, db *gorm.DB, change Change) error
+}
+
+type Usecase interface {
+	Recorder
+	FindAll(ctx context.Context, request *pagination.Request, filter *filter.AuditFilter) (pagination.Page[Response], error)
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+	"starter/internal/core/filter"
+	"starter/internal/core/pagination"
+	ivalidator "starter/internal/core/validator"
+)
+
+var tracer = otel.Tracer("starter/internal/core/audit")
+
+type UsecaseDependency struct {
+	DB              *gorm.DB
+	Validator       ivalidator.Validator
+	AuditRepository Repository
+}
+
+type UsecaseImpl struct {
+	UsecaseDependency
+}
+
+func NewUsecase(deps UsecaseDependency) Usecase {
+	return &UsecaseImpl{
+		deps,
+	}
+}
+
+// Record never opens a transaction of its own, the caller passes the one its
+// write runs in.
+func (usecase *UsecaseImpl) Record(ctx context.Context, db *gorm.DB, change Change) error {
+	ctx, span := tracer.Start(ctx, "audit.Usecase.Record")
+	defer span.End()
+
+	changes, err := Diff(change.Before, change.After)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to diff %s %d", change.EntityType, change.EntityID)
+		return err
+	}
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to encode changes of %s %d", change.EntityType, change.EntityID)
+		return err
+	}
+
+	actor := ActorFrom(ctx)
+	return usecase.AuditRepository.Save(db.WithContext(ctx), &Entry{
+		ActorID:    actor.UserID,
+		Action:     change.Action,
+		EntityType: change.EntityType,
+		EntityID:   change.EntityID,
+		Changes:    string(raw),
+		IP:         actor.IP,
+		RequestID:  actor.RequestID,
+	})
+}
+
+func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Request, query *filter.AuditFilter) (pagination.Page[Response], error) {
+	ctx, span := tracer.Start(ctx, "audit.Usecase.FindAll")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	// The log reads newest first unless asked otherwise.
+	if request.Sort == "" && request.OrderBy == "" && request.SortBy == "" {
+		request.Sort = "-created_at"
+	}
+	pagination.NewPagination(request)
+
+	validation := usecase.Validator.ValidateStruct(request)
+	validation = append(validation, request.ParseSort(SortableFields)...)
+	validation = append(validation, usecase.Validator.ValidateStruct(query)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return pagination.Page[Response]{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	entries, meta, err := usecase.AuditRepository.FindAll(tx, *request, *query)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return pagination.Page[Response]{}, pagination.CursorError()
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to fetch audit entries")
+		return pagination.Page[Response]{}, errors.New("something went wrong")
+	}
+
+	var response []Response
+	for _, entry := range entries {
+		response = append(response, *ToResponse(&entry))
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return pagination.Page[Response]{}, errors.New("something went wrong")
+	}
+
+	return *pagination.NewPage[Response](*request, meta, response), nil
+}
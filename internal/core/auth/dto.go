@@ -3,7 +3,6 @@ package auth
 import (
 	"starter/internal/core/user"
 	"strconv"
-	"strings"
 )
 
 type LoginRequest struct {
@@ -44,7 +43,7 @@ func ToResponse(entity *user.User) *CurrentAuthResponse {
 
 func (request *RegisterRequest) ToEntity() *user.User {
 	return &user.User{
-		Name:     strings.ToUpper(request.Name),
+		Name:     request.Name,
 		Email:    request.Email,
 		Password: request.Password,
 	}
@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// LockedError is returned by Login while an account is locked out after too
+// many failed attempts.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (err LockedError) Error() string {
+	return fmt.Sprintf("account locked, retry after %s", err.RetryAfter.Round(time.Second))
+}
@@ -4,18 +4,31 @@ import (
 	"context"
 	"errors"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
 	"gorm.io/gorm"
+	"starter/internal/core/audit"
+	"starter/internal/core/metrics"
+	"starter/internal/core/projection"
+	"starter/internal/core/ratelimit"
 	"starter/internal/core/role"
 	"starter/internal/core/user"
 	ivalidator "starter/internal/core/validator"
 	"starter/pkg/hasher"
+	"strings"
+	"time"
 )
 
+var tracer = otel.Tracer("starter/internal/core/auth")
+
 type UsecaseDependency struct {
 	Hasher         hasher.Hasher
 	TokenGenerator TokenGenerator
 	Validator      ivalidator.Validator
 	UserRepository user.Repository
+	Metrics        metrics.Recorder
+	RateLimitStore ratelimit.Store
+	Backoff        ratelimit.Backoff
+	Audit          audit.Recorder
 	DB             *gorm.DB
 }
 
@@ -30,20 +43,48 @@ func NewUsecase(deps UsecaseDependency) Usecase {
 }
 
 func (usecase *UsecaseImpl) Login(ctx context.Context, request LoginRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "auth.Usecase.Login")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	validation := usecase.Validator.ValidateStruct(request)
 	if validation != nil {
-		log.Error().Msgf("Validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
 		return &Response{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
 	}
 
-	user, err := usecase.UserRepository.FindByEmail(tx, request.Email)
+	key := "login:" + strings.ToLower(request.Email)
+	lockout, err := usecase.RateLimitStore.Lockout(ctx, key)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to check lockout")
+		return nil, errors.New("something went wrong")
+	}
+	if retryAfter, locked := lockout.Locked(time.Now()); locked {
+		usecase.Metrics.Login(false)
+		return nil, LockedError{RetryAfter: retryAfter}
+	}
+
+	user, err := usecase.UserRepository.FindByEmail(tx, request.Email, projection.Request{})
 	if !usecase.Hasher.Check(request.Password, user.Password) {
-		return nil, errors.New("invalid email or password")
+		usecase.Metrics.Login(false)
+		now := time.Now()
+		lockout, err = usecase.RateLimitStore.Fail(ctx, key, now, usecase.Backoff)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to record failed login")
+			return nil, errors.New("something went wrong")
+		}
+		if retryAfter, locked := lockout.Locked(now); locked {
+			return nil, LockedError{RetryAfter: retryAfter}
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if err = usecase.RateLimitStore.Reset(ctx, key); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to reset lockout")
 	}
 
 	token, err := usecase.TokenGenerator.Generate(AuthenticatedUser{
@@ -51,28 +92,33 @@ func (usecase *UsecaseImpl) Login(ctx context.Context, request LoginRequest) (*R
 		Role: user.Role.Name,
 	})
 	if err != nil {
-		log.Error().
+		log.Ctx(ctx).Error().
 			Err(err).
 			Msgf("failed to generate token")
 		return nil, errors.New("something went wrong")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return nil, errors.New("something went wrong")
 	}
+	usecase.Metrics.Login(true)
+
 	return &Response{
 		Token: token,
 	}, nil
 }
 
 func (usecase *UsecaseImpl) Register(ctx context.Context, request RegisterRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "auth.Usecase.Register")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	validation := usecase.Validator.ValidateStruct(request)
 	if validation != nil {
-		log.Error().Msgf("Validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
 		return &Response{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
@@ -81,7 +127,7 @@ func (usecase *UsecaseImpl) Register(ctx context.Context, request RegisterReques
 	user := request.ToEntity()
 	password, err := usecase.Hasher.Hash(user.Password)
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to secure password")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to secure password")
 		return nil, errors.New("something went wrong")
 	}
 
@@ -91,8 +137,24 @@ func (usecase *UsecaseImpl) Register(ctx context.Context, request RegisterReques
 	}
 
 	err = usecase.UserRepository.Save(tx, user)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save user")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionCreate,
+		EntityType: "user",
+		EntityID:   user.ID,
+		After:      ToResponse(user),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit user registration")
+		return nil, errors.New("something went wrong")
+	}
+
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return nil, errors.New("something went wrong")
 	}
 
@@ -100,19 +162,22 @@ func (usecase *UsecaseImpl) Register(ctx context.Context, request RegisterReques
 }
 
 func (usecase *UsecaseImpl) Current(ctx context.Context) (*CurrentAuthResponse, error) {
+	ctx, span := tracer.Start(ctx, "auth.Usecase.Current")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	claim := ctx.Value("user").(AuthenticatedUser)
 
-	user, err := usecase.UserRepository.FindByID(tx, int(claim.Id))
+	user, err := usecase.UserRepository.FindByID(tx, int(claim.Id), projection.Request{})
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to get current user")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to get current user")
 		return nil, errors.New("user not found")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return nil, errors.New("something went wrong")
 	}
 	return ToResponse(&user), err
@@ -2,9 +2,36 @@ package author
 
 import (
 	"gorm.io/gorm"
-	"strings"
+	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
 )
 
+// SortableFields lists what FindAll accepts in the sort parameter.
+var SortableFields = pagination.Sortable{
+	"id":         "authors.id",
+	"first_name": "authors.first_name",
+	"last_name":  "authors.last_name",
+	"created_at": "authors.created_at",
+	"updated_at": "authors.updated_at",
+}
+
+// ProjectableFields lists what read endpoints accept in fields and include.
+var ProjectableFields = projection.Projectable{
+	ID:      "authors.id",
+	Version: "authors.version",
+	Fields: map[string]string{
+		"first_name": "authors.first_name",
+		"last_name":  "authors.last_name",
+	},
+}
+
+// PatchableFields lists the keys PATCH accepts and the columns they write.
+var PatchableFields = patch.Fields{
+	"first_name": "first_name",
+	"last_name":  "last_name",
+}
+
 type CreateRequest struct {
 	FirstName string `json:"first_name" validate:"required,max=100"`
 	LastName  string `json:"last_name" validate:"required,max=100"`
@@ -14,33 +41,55 @@ type UpdateRequest struct {
 	Id        int    `json:"id" validate:"required"`
 	FirstName string `json:"first_name" validate:"max=100"`
 	LastName  string `json:"last_name" validate:"max=100"`
+	Version   uint   `json:"-"`
+}
+
+// PatchDocument is the author a merge patch is applied to. Its rules are the
+// ones a stored author has to satisfy, so anything optional can be cleared.
+type PatchDocument struct {
+	FirstName string `json:"first_name" validate:"required,max=100"`
+	LastName  string `json:"last_name" validate:"required,max=100"`
 }
 
 type Response struct {
 	Id        int    `json:"id"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
+	Version   uint   `json:"version"`
 }
 
 func (dto *CreateRequest) ToEntity() *Author {
 	return &Author{
-		FirstName: strings.ToUpper(dto.FirstName),
-		LastName:  strings.ToUpper(dto.LastName),
+		FirstName: dto.FirstName,
+		LastName:  dto.LastName,
 	}
 }
 
 func (dto *UpdateRequest) ToEntity() *Author {
 	return &Author{
 		Model:     gorm.Model{ID: uint(dto.Id)},
-		FirstName: strings.ToUpper(dto.FirstName),
-		LastName:  strings.ToUpper(dto.LastName),
+		FirstName: dto.FirstName,
+		LastName:  dto.LastName,
 	}
 }
 
+func ToPatchDocument(entity *Author) *PatchDocument {
+	return &PatchDocument{
+		FirstName: entity.FirstName,
+		LastName:  entity.LastName,
+	}
+}
+
+func (dto *PatchDocument) Apply(entity *Author) {
+	entity.FirstName = dto.FirstName
+	entity.LastName = dto.LastName
+}
+
 func ToResponse(entity *Author) *Response {
 	return &Response{
 		Id:        int(entity.ID),
-		FirstName: strings.ToUpper(entity.FirstName),
-		LastName:  strings.ToUpper(entity.LastName),
+		FirstName: entity.FirstName,
+		LastName:  entity.LastName,
+		Version:   entity.Version,
 	}
 }
@@ -1,9 +1,21 @@
 package author
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"starter/pkg/helper"
+)
 
 type Author struct {
 	FirstName string
 	LastName  string
+	// NameKey is the folded full name, lookups and duplicate reports go by
+	// it so that the names themselves keep their casing.
+	NameKey string `gorm:"not null;default:'';index"`
+	Version uint   `gorm:"not null;default:1"`
 	gorm.Model
 }
+
+// SetKey derives NameKey from the name, writes call it before saving.
+func (author *Author) SetKey() {
+	author.NameKey = helper.Fold(author.FirstName + " " + author.LastName)
+}
@@ -3,21 +3,35 @@ package author
 import (
 	"context"
 	"gorm.io/gorm"
+	"starter/internal/core/integrity"
+	"starter/internal/core/merge"
 	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
 )
 
 type Repository interface {
 	Save(db *gorm.DB, Author *Author) error
 	Update(db *gorm.DB, Author *Author) error
-	Delete(db *gorm.DB, id int) error
-	FindAll(db *gorm.DB, params pagination.Request) ([]Author, int64, error)
-	FindByID(db *gorm.DB, id int) (Author, error)
+	Patch(db *gorm.DB, Author *Author, columns []string) error
+	Delete(db *gorm.DB, id int, version uint) error
+	CountBooks(db *gorm.DB, id int) (int64, error)
+	DeleteBooks(db *gorm.DB, id int) ([]uint, error)
+	ReassignBooks(db *gorm.DB, id int, target uint) ([]uint, error)
+	Duplicates(db *gorm.DB, request merge.DuplicatesRequest) ([]merge.Candidate, error)
+	FindAll(db *gorm.DB, params pagination.Request, view projection.Request) ([]Author, pagination.Meta, error)
+	FindByID(db *gorm.DB, id int, view projection.Request) (Author, error)
+	// FindByKey returns the oldest author going by the search key.
+	FindByKey(db *gorm.DB, key string) (Author, error)
 }
 
 type Usecase interface {
 	Save(ctx context.Context, request CreateRequest) (Response, error)
 	Update(ctx context.Context, request UpdateRequest) (*Response, error)
-	Delete(ctx context.Context, id int) error
-	FindAll(ctx context.Context, request *pagination.Request) (pagination.Page[Response], error)
-	FindById(ctx context.Context, id int) (*Response, error)
+	Patch(ctx context.Context, request patch.Request) (*Response, error)
+	Delete(ctx context.Context, id int, version uint, request integrity.DeleteRequest) error
+	FindAll(ctx context.Context, request *pagination.Request, view *projection.Request) (pagination.Page[Response], error)
+	FindById(ctx context.Context, id int, view *projection.Request) (*Response, error)
+	Merge(ctx context.Context, request merge.Request) (*Response, error)
+	Duplicates(ctx context.Context, request *merge.DuplicatesRequest) ([]merge.CandidateResponse, error)
 }
@@ -3,17 +3,30 @@ package author
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
 	"gorm.io/gorm"
+	"starter/internal/core/audit"
+	"starter/internal/core/concurrency"
+	"starter/internal/core/integrity"
+	"starter/internal/core/merge"
 	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
 	ivalidator "starter/internal/core/validator"
 	"starter/pkg/helper"
 )
 
+var tracer = otel.Tracer("starter/internal/core/author")
+
 type UsecaseDependency struct {
-	DB               *gorm.DB
-	Validator        ivalidator.Validator
-	AuthorRepository Repository
+	DB                 *gorm.DB
+	Validator          ivalidator.Validator
+	Audit              audit.Recorder
+	AuthorRepository   Repository
+	RedirectRepository merge.RedirectRepository
+	BookRule           string
 }
 
 type UsecaseImpl struct {
@@ -27,12 +40,15 @@ func NewUsecase(deps UsecaseDependency) Usecase {
 }
 
 func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Response, error) {
+	ctx, span := tracer.Start(ctx, "author.Usecase.Save")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	validation := usecase.Validator.ValidateStruct(request)
 	if validation != nil {
-		log.Error().Msgf("Validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
 		return Response{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
@@ -40,8 +56,24 @@ func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Re
 
 	author := request.ToEntity()
 	err := usecase.AuthorRepository.Save(tx, author)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save author")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionCreate,
+		EntityType: "author",
+		EntityID:   author.ID,
+		After:      ToResponse(author),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit author creation")
+		return Response{}, errors.New("something went wrong")
+	}
+
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return Response{}, errors.New("something went wrong")
 	}
 
@@ -49,63 +81,192 @@ func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Re
 }
 
 func (usecase *UsecaseImpl) Update(ctx context.Context, request UpdateRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "author.Usecase.Update")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	validation := usecase.Validator.ValidateStruct(request)
 	if validation != nil {
-		log.Error().Msgf("Validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
 		return &Response{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
 	}
 
-	author, err := usecase.AuthorRepository.FindByID(tx, request.Id)
+	author, err := usecase.AuthorRepository.FindByID(tx, request.Id, projection.Request{})
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to find author by id: %+v", request.Id)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find author by id: %+v", request.Id)
 		return nil, errors.New("author not found")
 	}
 	updated := helper.Differ(author, *request.ToEntity()).(Author)
+	if request.Version != 0 {
+		updated.Version = request.Version
+	}
 
 	err = usecase.AuthorRepository.Update(tx, &updated)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update author")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "author",
+		EntityID:   updated.ID,
+		Before:     ToResponse(&author),
+		After:      ToResponse(&updated),
+	})
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to update author")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit author update")
 		return nil, errors.New("something went wrong")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return &Response{}, errors.New("something went wrong")
 	}
 	return ToResponse(&updated), nil
 }
 
-func (usecase *UsecaseImpl) Delete(ctx context.Context, id int) error {
+func (usecase *UsecaseImpl) Patch(ctx context.Context, request patch.Request) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "author.Usecase.Patch")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
-	err := usecase.AuthorRepository.Delete(tx, id)
+	author, err := usecase.AuthorRepository.FindByID(tx, request.Id, projection.Request{})
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to update author")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find author by id: %+v", request.Id)
+		return nil, errors.New("author not found")
+	}
+	before := ToResponse(&author)
+	if request.Version != 0 {
+		author.Version = request.Version
+	}
+
+	document := ToPatchDocument(&author)
+	keys, err := patch.Apply(document, request.Patch)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to apply patch to author")
+		return nil, err
+	}
+
+	columns, validation := PatchableFields.Columns(keys)
+	validation = append(validation, usecase.Validator.ValidateStruct(document)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+	document.Apply(&author)
+
+	err = usecase.AuthorRepository.Patch(tx, &author, columns)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to patch author")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "author",
+		EntityID:   author.ID,
+		Before:     before,
+		After:      ToResponse(&author),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit author patch")
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToResponse(&author), nil
+}
+
+func (usecase *UsecaseImpl) Delete(ctx context.Context, id int, version uint, request integrity.DeleteRequest) error {
+	ctx, span := tracer.Start(ctx, "author.Usecase.Delete")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	author, err := usecase.AuthorRepository.FindByID(tx, id, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find author by id: %+v", id)
+		return errors.New("author not found")
+	}
+
+	err = usecase.applyBookRule(ctx, tx, id, request)
+	if err != nil {
+		return err
+	}
+
+	err = usecase.AuthorRepository.Delete(tx, id, version)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update author")
+		return errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionDelete,
+		EntityType: "author",
+		EntityID:   author.ID,
+		Before:     ToResponse(&author),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit author deletion")
 		return errors.New("something went wrong")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return errors.New("something went wrong")
 	}
 
 	return nil
 }
 
-func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Request) (pagination.Page[Response], error) {
+func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Request, view *projection.Request) (pagination.Page[Response], error) {
+	ctx, span := tracer.Start(ctx, "author.Usecase.FindAll")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	pagination.NewPagination(request)
-	authors, count, err := usecase.AuthorRepository.FindAll(tx, *request)
+
+	validation := usecase.Validator.ValidateStruct(request)
+	validation = append(validation, request.ParseSort(SortableFields)...)
+	validation = append(validation, usecase.Validator.ValidateStruct(view)...)
+	validation = append(validation, view.Parse(ProjectableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return pagination.Page[Response]{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	authors, meta, err := usecase.AuthorRepository.FindAll(tx, *request, *view)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return pagination.Page[Response]{}, pagination.CursorError()
+	}
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to fetch authors")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to fetch authors")
 		return pagination.Page[Response]{}, errors.New("something went wrong")
 	}
 
@@ -115,26 +276,249 @@ func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Req
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return pagination.Page[Response]{}, errors.New("something went wrong")
 	}
 
-	return *pagination.NewPage[Response](*request, count, response), nil
+	return *pagination.NewPage[Response](*request, meta, response), nil
 }
 
-func (usecase *UsecaseImpl) FindById(ctx context.Context, id int) (*Response, error) {
+func (usecase *UsecaseImpl) FindById(ctx context.Context, id int, view *projection.Request) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "author.Usecase.FindById")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
-	author, err := usecase.AuthorRepository.FindByID(tx, id)
+	validation := usecase.Validator.ValidateStruct(view)
+	validation = append(validation, view.Parse(ProjectableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	author, err := usecase.AuthorRepository.FindByID(tx, id, *view)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		redirect, err := usecase.RedirectRepository.FindBySource(tx, "author", id)
+		if err == nil {
+			return nil, merge.MovedError{ID: redirect.TargetID}
+		}
+	}
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to find author with id: %d", id)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find author with id: %d", id)
 		return nil, errors.New("author not found")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return nil, errors.New("something went wrong")
 	}
 	return ToResponse(&author), nil
 }
+
+// applyBookRule deals with the books of the author about to be deleted: it
+// refuses while there are any, deletes them too or points them at another
+// author, depending on request and the configured BookRule.
+func (usecase *UsecaseImpl) applyBookRule(ctx context.Context, tx *gorm.DB, id int, request integrity.DeleteRequest) error {
+	integrity.NewDeleteRequest(&request, usecase.BookRule)
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation == nil && request.Books == integrity.RuleReassign {
+		_, err := usecase.AuthorRepository.FindByID(tx, int(request.ReassignTo), projection.Request{})
+		if err != nil || int(request.ReassignTo) == id {
+			validation = append(validation, ivalidator.ValidationError{
+				Field:   "reassign_to",
+				Message: "reassign_to must be another existing author",
+			})
+		}
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	var books []uint
+	var err error
+	action := audit.ActionDelete
+	before := map[string]any{"author_id": id}
+	var after map[string]any
+
+	switch request.Books {
+	case integrity.RuleRestrict:
+		count, err := usecase.AuthorRepository.CountBooks(tx, id)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to count books of author %d", id)
+			return errors.New("something went wrong")
+		}
+		if count > 0 {
+			return integrity.RestrictedError{Relation: "books", Count: count}
+		}
+		return nil
+	case integrity.RuleCascade:
+		books, err = usecase.AuthorRepository.DeleteBooks(tx, id)
+	case integrity.RuleReassign:
+		books, err = usecase.AuthorRepository.ReassignBooks(tx, id, request.ReassignTo)
+		action = audit.ActionUpdate
+		after = map[string]any{"author_id": request.ReassignTo}
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to %s books of author %d", request.Books, id)
+		return errors.New("something went wrong")
+	}
+
+	return usecase.auditBooks(ctx, tx, books, action, before, after)
+}
+
+func (usecase *UsecaseImpl) auditBooks(ctx context.Context, tx *gorm.DB, books []uint, action string, before, after map[string]any) error {
+	for _, book := range books {
+		err := usecase.Audit.Record(ctx, tx, audit.Change{
+			Action:     action,
+			EntityType: "book",
+			EntityID:   book,
+			Before:     before,
+			After:      after,
+		})
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to audit book %d", book)
+			return errors.New("something went wrong")
+		}
+	}
+
+	return nil
+}
+
+// Merge moves the books of every source over to the target, deletes the
+// sources and leaves a redirect behind for each of them.
+func (usecase *UsecaseImpl) Merge(ctx context.Context, request merge.Request) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "author.Usecase.Merge")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	target, err := usecase.AuthorRepository.FindByID(tx, request.TargetID, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find author by id: %+v", request.TargetID)
+		return nil, errors.New("author not found")
+	}
+
+	var sources []Author
+	seen := map[uint]bool{}
+	for _, id := range request.Sources {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		source, err := usecase.AuthorRepository.FindByID(tx, int(id), projection.Request{})
+		if err != nil || id == target.ID {
+			validation = append(validation, ivalidator.ValidationError{
+				Field:   "sources",
+				Message: fmt.Sprintf("sources must be existing authors other than the target, %d is not", id),
+			})
+			continue
+		}
+		sources = append(sources, source)
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	for _, source := range sources {
+		books, err := usecase.AuthorRepository.ReassignBooks(tx, int(source.ID), target.ID)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to move books of author %d", source.ID)
+			return nil, errors.New("something went wrong")
+		}
+		err = usecase.auditBooks(ctx, tx, books, audit.ActionUpdate,
+			map[string]any{"author_id": source.ID},
+			map[string]any{"author_id": target.ID},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		err = usecase.AuthorRepository.Delete(tx, int(source.ID), 0)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to delete merged author %d", source.ID)
+			return nil, errors.New("something went wrong")
+		}
+
+		err = usecase.RedirectRepository.Save(tx, &merge.Redirect{
+			EntityType: "author",
+			SourceID:   source.ID,
+			TargetID:   target.ID,
+		})
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to redirect merged author %d", source.ID)
+			return nil, errors.New("something went wrong")
+		}
+
+		err = usecase.Audit.Record(ctx, tx, audit.Change{
+			Action:     audit.ActionMerge,
+			EntityType: "author",
+			EntityID:   source.ID,
+			Before:     ToResponse(&source),
+			After:      map[string]any{"merged_into": target.ID},
+		})
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to audit author merge")
+			return nil, errors.New("something went wrong")
+		}
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToResponse(&target), nil
+}
+
+func (usecase *UsecaseImpl) Duplicates(ctx context.Context, request *merge.DuplicatesRequest) ([]merge.CandidateResponse, error) {
+	ctx, span := tracer.Start(ctx, "author.Usecase.Duplicates")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	merge.NewDuplicatesRequest(request)
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	candidates, err := usecase.AuthorRepository.Duplicates(tx, *request)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find duplicate authors")
+		return nil, errors.New("something went wrong")
+	}
+
+	response := make([]merge.CandidateResponse, 0, len(candidates))
+	for _, candidate := range candidates {
+		response = append(response, *merge.ToCandidateResponse(&candidate))
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return response, nil
+}
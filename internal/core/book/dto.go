@@ -1,33 +1,132 @@
 package book
 
 import (
+	"encoding/json"
+	"errors"
 	"gorm.io/gorm"
+	"maps"
+	"slices"
+	"starter/internal/core/audit"
 	"starter/internal/core/category"
-	"strings"
+	"starter/internal/core/locale"
+	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
+	"starter/internal/core/tag"
+	ivalidator "starter/internal/core/validator"
 	"time"
 )
 
+// SortableFields lists what FindAll accepts in the sort parameter. Author and
+// publisher fields are served by the joins FindAll already makes.
+var SortableFields = pagination.Sortable{
+	"id":                "books.id",
+	"title":             "books.title",
+	"page_count":        "books.page_count",
+	"publication_date":  "books.publication_date",
+	"created_at":        "books.created_at",
+	"updated_at":        "books.updated_at",
+	"avg_rating":        "books.rating_average",
+	"rating_count":      "books.rating_count",
+	"author.first_name": "authors.first_name",
+	"author.last_name":  "authors.last_name",
+	"publisher.name":    "publishers.name",
+}
+
+// RevisionSortableFields lists what the revision list accepts in sort.
+var RevisionSortableFields = pagination.Sortable{
+	"number":     "book_revisions.number",
+	"created_at": "book_revisions.created_at",
+}
+
+// ProjectableFields lists what read endpoints accept in fields and include.
+// Without include a book comes with all of its relations, as it always has.
+var ProjectableFields = projection.Projectable{
+	ID:      "books.id",
+	Version: "books.version",
+	Fields: map[string]string{
+		"title":            "books.title",
+		"cover":            "books.cover",
+		"description":      "books.description",
+		"page_count":       "books.page_count",
+		"publication_date": "books.publication_date",
+		"avg_rating":       "books.rating_average",
+		"rating_count":     "books.rating_count",
+		"language":         "books.language",
+		"original_id":      "books.original_id",
+		// The caller's shelves and the translations are looked up by the
+		// book id. The locale is the language of the variant served.
+		"shelves":      "books.id",
+		"translations": "books.id",
+		"locale":       "books.language",
+	},
+	Relations: map[string]projection.Relation{
+		"author":        {Preload: "Author", Columns: []string{"books.author_id"}},
+		"publisher":     {Preload: "Publisher", Columns: []string{"books.publisher_id"}},
+		"categories":    {Preload: "Categories"},
+		"tags":          {Preload: "Tags"},
+		"subjects":      {Preload: "Subjects"},
+		"localizations": {Preload: "Localizations"},
+	},
+	Defaults: []string{"author", "publisher", "categories", "tags", "subjects", "localizations"},
+}
+
+// PatchableFields lists the keys PATCH accepts and the columns they write.
+var PatchableFields = patch.Fields{
+	"title":            "title",
+	"cover":            "cover",
+	"description":      "description",
+	"page_count":       "page_count",
+	"author_id":        "author_id",
+	"publisher_id":     "publisher_id",
+	"publication_date": "publication_date",
+	"categories":       "Categories",
+	"tags":             "Tags",
+	"subjects":         "Subjects",
+	"language":         "language",
+	"original_id":      "original_id",
+	"localizations":    "Localizations",
+}
+
 type CreateRequest struct {
-	Title           string `json:"title" validate:"required,max=100"`
-	Cover           string `json:"cover" validate:"required"`
-	Description     string `json:"description" validate:"required,max=500"`
-	PageCount       int    `json:"page_count" validate:"required,min=1,max=10000"`
-	AuthorId        int    `json:"author_id" validate:"required"`
-	Categories      []int  `json:"categories" validate:"required,dive,min=1"`
-	PublisherId     int    `json:"publisher_id" validate:"required"`
-	PublicationDate string `json:"publication_date" validate:"required,publication_date"`
+	Title           string   `json:"title" validate:"required,max=100"`
+	Cover           string   `json:"cover" validate:"required"`
+	Description     string   `json:"description" validate:"required,max=500"`
+	PageCount       int      `json:"page_count" validate:"required,min=1,max=10000"`
+	AuthorId        int      `json:"author_id" validate:"required"`
+	Categories      []int    `json:"categories" validate:"required,dive,min=1"`
+	Tags            []string `json:"tags" validate:"max=20,dive,required,max=200"`
+	Subjects        []string `json:"subjects" validate:"max=20,dive,required,max=200"`
+	PublisherId     int      `json:"publisher_id" validate:"required"`
+	PublicationDate string   `json:"publication_date" validate:"required,publication_date"`
+	Language        string   `json:"language" validate:"omitempty,bcp47"`
+	OriginalId      uint     `json:"original_id"`
+	// Localizations are keyed by their BCP 47 locale.
+	Localizations map[string]LocalizationRequest `json:"localizations" validate:"max=20,dive,keys,bcp47,endkeys"`
+}
+
+type LocalizationRequest struct {
+	Title       string `json:"title" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=500"`
 }
 
 type UpdateRequest struct {
-	Id              int    `json:"id" validate:"required"`
-	Title           string `json:"title" validate:"max=100"`
-	Cover           string `json:"cover"`
-	Description     string `json:"description" validate:"max=500"`
-	PageCount       int    `json:"page_count,omitempty" validate:"omitempty,min=1,max=10000"`
-	AuthorId        int    `json:"author_id"`
-	Categories      []int  `json:"categories,omitempty" validate:"omitempty,dive,min=1"`
-	PublisherId     int    `json:"publisher_id" validate:"required"`
-	PublicationDate string `json:"publication_date,omitempty" validate:"omitempty,publication_date"`
+	Id              int      `json:"id" validate:"required"`
+	Title           string   `json:"title" validate:"max=100"`
+	Cover           string   `json:"cover"`
+	Description     string   `json:"description" validate:"max=500"`
+	PageCount       int      `json:"page_count,omitempty" validate:"omitempty,min=1,max=10000"`
+	AuthorId        int      `json:"author_id"`
+	Categories      []int    `json:"categories,omitempty" validate:"omitempty,dive,min=1"`
+	Tags            []string `json:"tags,omitempty" validate:"max=20,dive,required,max=200"`
+	Subjects        []string `json:"subjects,omitempty" validate:"max=20,dive,required,max=200"`
+	PublisherId     int      `json:"publisher_id" validate:"required"`
+	PublicationDate string   `json:"publication_date,omitempty" validate:"omitempty,publication_date"`
+	Language        string   `json:"language,omitempty" validate:"omitempty,bcp47"`
+	OriginalId      uint     `json:"original_id,omitempty"`
+	// Localizations replace the stored ones when sent, keyed by locale.
+	Localizations map[string]LocalizationRequest `json:"localizations,omitempty" validate:"max=20,dive,keys,bcp47,endkeys"`
+	Version       uint                           `json:"-"`
 }
 
 type AuthorResponse struct {
@@ -41,21 +140,79 @@ type CategoryResponse struct {
 	Name string `json:"name"`
 }
 
+// TranslationResponse is a book translated from the one in the response.
+type TranslationResponse struct {
+	Id       int    `json:"id"`
+	Title    string `json:"title"`
+	Language string `json:"language"`
+}
+
+// TagResponse is a free-form tag or a subject heading of the book.
+type TagResponse struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
 type PublisherResponse struct {
 	Id   int    `json:"id"`
 	Name string `json:"name"`
 }
 
+// ShelfResponse is one of the caller's shelves the book is on.
+type ShelfResponse struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// PatchDocument is the book a merge patch is applied to. Its rules are the
+// ones a stored book has to satisfy, so anything optional can be cleared.
+type PatchDocument struct {
+	Title           string   `json:"title" validate:"required,max=100"`
+	Cover           string   `json:"cover"`
+	Description     string   `json:"description" validate:"max=500"`
+	PageCount       int      `json:"page_count" validate:"omitempty,min=1,max=10000"`
+	AuthorId        int      `json:"author_id" validate:"required"`
+	Categories      []int    `json:"categories" validate:"dive,min=1"`
+	Tags            []string `json:"tags" validate:"max=20,dive,required,max=200"`
+	Subjects        []string `json:"subjects" validate:"max=20,dive,required,max=200"`
+	PublisherId     int      `json:"publisher_id" validate:"required"`
+	PublicationDate string   `json:"publication_date" validate:"required,publication_date"`
+	Language        string   `json:"language" validate:"omitempty,bcp47"`
+	OriginalId      *uint    `json:"original_id"`
+	// A merge patch can add, change or, with null, drop a single locale.
+	Localizations map[string]LocalizationRequest `json:"localizations" validate:"max=20,dive,keys,bcp47,endkeys"`
+}
+
+// LocalizationResponse is the title and description of the book in one
+// locale.
+type LocalizationResponse struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
 type Response struct {
-	Id              int                `json:"id"`
-	Title           string             `json:"title"`
-	Cover           string             `json:"cover"`
-	Description     string             `json:"description"`
-	PageCount       int                `json:"page_count"`
-	Author          AuthorResponse     `json:"author"`
-	Categories      []CategoryResponse `json:"categories"`
-	Publisher       PublisherResponse  `json:"publisher"`
-	PublicationDate string             `json:"publication_date"`
+	Id              int                             `json:"id"`
+	Title           string                          `json:"title"`
+	Cover           string                          `json:"cover"`
+	Description     string                          `json:"description"`
+	PageCount       int                             `json:"page_count"`
+	Author          AuthorResponse                  `json:"author"`
+	Categories      []CategoryResponse              `json:"categories"`
+	Tags            []TagResponse                   `json:"tags"`
+	Subjects        []TagResponse                   `json:"subjects"`
+	Publisher       PublisherResponse               `json:"publisher"`
+	PublicationDate string                          `json:"publication_date"`
+	AvgRating       float64                         `json:"avg_rating"`
+	RatingCount     int                             `json:"rating_count"`
+	Language        string                          `json:"language"`
+	Locale          string                          `json:"locale"`
+	OriginalId      *uint                           `json:"original_id"`
+	Localizations   map[string]LocalizationResponse `json:"localizations"`
+	Translations    []TranslationResponse           `json:"translations,omitempty"`
+	Shelves         []ShelfResponse                 `json:"shelves,omitempty"`
+	Version         uint                            `json:"version"`
 }
 
 func (dto *CreateRequest) ToEntity() *Book {
@@ -69,14 +226,19 @@ func (dto *CreateRequest) ToEntity() *Book {
 	publicationDate, _ := time.Parse("2006-01-02", dto.PublicationDate)
 
 	book := &Book{
-		Title:           strings.ToUpper(dto.Title),
+		Title:           dto.Title,
 		Description:     dto.Description,
 		Cover:           dto.Cover,
 		PageCount:       dto.PageCount,
 		AuthorId:        dto.AuthorId,
 		PublisherId:     dto.PublisherId,
 		Categories:      categories,
+		Tags:            toTags(tag.KindTag, dto.Tags),
+		Subjects:        toTags(tag.KindSubject, dto.Subjects),
 		PublicationDate: publicationDate,
+		Language:        locale.Canonical(dto.Language),
+		OriginalID:      toOriginalID(dto.OriginalId),
+		Localizations:   toLocalizations(dto.Localizations),
 	}
 	book.Author.ID = uint(dto.AuthorId)
 	book.Publisher.ID = uint(dto.PublisherId)
@@ -94,13 +256,18 @@ func (dto *UpdateRequest) ToEntity() *Book {
 	publicationDate, _ := time.Parse("2006-01-02", dto.PublicationDate)
 
 	book := &Book{
-		Title:           strings.ToUpper(dto.Title),
+		Title:           dto.Title,
 		Description:     dto.Description,
 		Cover:           dto.Cover,
 		PageCount:       dto.PageCount,
 		AuthorId:        dto.AuthorId,
 		Categories:      categories,
+		Tags:            toTags(tag.KindTag, dto.Tags),
+		Subjects:        toTags(tag.KindSubject, dto.Subjects),
 		PublicationDate: publicationDate,
+		Language:        locale.Canonical(dto.Language),
+		OriginalID:      toOriginalID(dto.OriginalId),
+		Localizations:   toLocalizations(dto.Localizations),
 	}
 	book.ID = uint(dto.Id)
 	book.Author.ID = uint(dto.AuthorId)
@@ -109,6 +276,61 @@ func (dto *UpdateRequest) ToEntity() *Book {
 	return book
 }
 
+func ToPatchDocument(entity *Book) *PatchDocument {
+	categories := make([]int, 0, len(entity.Categories))
+	for _, v := range entity.Categories {
+		categories = append(categories, int(v.ID))
+	}
+
+	publicationDate := ""
+	if !entity.PublicationDate.IsZero() {
+		publicationDate = entity.PublicationDate.Format("2006-01-02")
+	}
+
+	return &PatchDocument{
+		Title:           entity.Title,
+		Cover:           entity.Cover,
+		Description:     entity.Description,
+		PageCount:       entity.PageCount,
+		AuthorId:        entity.AuthorId,
+		Categories:      categories,
+		Tags:            tagNames(entity.Tags),
+		Subjects:        tagNames(entity.Subjects),
+		PublisherId:     entity.PublisherId,
+		PublicationDate: publicationDate,
+		Language:        entity.Language,
+		OriginalId:      entity.OriginalID,
+		Localizations:   toLocalizationRequests(entity.Localizations),
+	}
+}
+
+func (dto *PatchDocument) Apply(entity *Book) {
+	categories := make([]category.Category, 0, len(dto.Categories))
+	for _, v := range dto.Categories {
+		categories = append(categories, category.Category{
+			Model: gorm.Model{ID: uint(v)},
+		})
+	}
+	publicationDate, _ := time.Parse("2006-01-02", dto.PublicationDate)
+
+	entity.Title = dto.Title
+	entity.Cover = dto.Cover
+	entity.Description = dto.Description
+	entity.PageCount = dto.PageCount
+	entity.AuthorId = dto.AuthorId
+	entity.Categories = categories
+	entity.Tags = toTags(tag.KindTag, dto.Tags)
+	entity.Subjects = toTags(tag.KindSubject, dto.Subjects)
+	entity.PublisherId = dto.PublisherId
+	entity.PublicationDate = publicationDate
+	entity.Language = locale.Canonical(dto.Language)
+	entity.OriginalID = dto.OriginalId
+	entity.Localizations = toLocalizations(dto.Localizations)
+	if entity.Localizations == nil {
+		entity.Localizations = []Localization{}
+	}
+}
+
 func ToResponse(entity *Book) *Response {
 	categories := make([]CategoryResponse, 0, len(entity.Categories))
 	for _, v := range entity.Categories {
@@ -121,7 +343,7 @@ func ToResponse(entity *Book) *Response {
 
 	return &Response{
 		Id:          int(entity.ID),
-		Title:       strings.ToUpper(entity.Title),
+		Title:       entity.Title,
 		Cover:       entity.Cover,
 		Description: entity.Description,
 		PageCount:   entity.PageCount,
@@ -131,10 +353,182 @@ func ToResponse(entity *Book) *Response {
 			LastName:  entity.Author.LastName,
 		},
 		Categories: categories,
+		Tags:       toTagResponses(entity.Tags),
+		Subjects:   toTagResponses(entity.Subjects),
 		Publisher: PublisherResponse{
 			Id:   int(entity.Publisher.ID),
 			Name: entity.Publisher.Name,
 		},
 		PublicationDate: publicationDate,
+		AvgRating:       entity.RatingAverage,
+		RatingCount:     entity.RatingCount,
+		Language:        entity.Language,
+		Locale:          entity.Language,
+		OriginalId:      entity.OriginalID,
+		Localizations:   toLocalizationResponses(entity.Localizations),
+		Version:         entity.Version,
+	}
+}
+
+// toTags turns names into tags of kind, normalised and without repeats. Nil
+// stays nil so that an update leaves the tags alone when none were sent.
+func toTags(kind string, names []string) []tag.Tag {
+	if names == nil {
+		return nil
+	}
+
+	tags := make([]tag.Tag, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = tag.Normalize(kind, name)
+		slug := tag.Slug(name)
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		tags = append(tags, tag.Tag{Name: name, Slug: slug, Kind: kind})
+	}
+	return tags
+}
+
+func toOriginalID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// validateLocales rejects localizations keyed by two spellings of the same
+// locale, such as en-us and en-US, rather than keeping one of them.
+func validateLocales(localizations map[string]LocalizationRequest) []ivalidator.ValidationError {
+	seen := make(map[string]bool, len(localizations))
+	for _, key := range slices.Sorted(maps.Keys(localizations)) {
+		canonical := locale.Canonical(key)
+		if seen[canonical] {
+			return []ivalidator.ValidationError{{
+				Field:   "localizations",
+				Message: "localizations must not contain " + canonical + " more than once",
+			}}
+		}
+		seen[canonical] = true
+	}
+	return nil
+}
+
+// toLocalizations turns the variants keyed by locale into rows, in locale
+// order. Nil stays nil so that an update leaves the variants alone. The
+// locales are expected to have passed validateLocales.
+func toLocalizations(localizations map[string]LocalizationRequest) []Localization {
+	if localizations == nil {
+		return nil
+	}
+
+	rows := make([]Localization, 0, len(localizations))
+	for _, key := range slices.Sorted(maps.Keys(localizations)) {
+		rows = append(rows, Localization{
+			Locale:      locale.Canonical(key),
+			Title:       localizations[key].Title,
+			Description: localizations[key].Description,
+		})
+	}
+	return rows
+}
+
+func toLocalizationRequests(rows []Localization) map[string]LocalizationRequest {
+	localizations := make(map[string]LocalizationRequest, len(rows))
+	for _, row := range rows {
+		localizations[row.Locale] = LocalizationRequest{
+			Title:       row.Title,
+			Description: row.Description,
+		}
+	}
+	return localizations
+}
+
+func toLocalizationResponses(rows []Localization) map[string]LocalizationResponse {
+	localizations := make(map[string]LocalizationResponse, len(rows))
+	for _, row := range rows {
+		localizations[row.Locale] = LocalizationResponse{
+			Title:       row.Title,
+			Description: row.Description,
+		}
+	}
+	return localizations
+}
+
+func tagNames(tags []tag.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, v := range tags {
+		names = append(names, v.Name)
+	}
+	return names
+}
+
+func tagSlugs(tags []tag.Tag) []string {
+	slugs := make([]string, 0, len(tags))
+	for _, v := range tags {
+		slugs = append(slugs, v.Slug)
+	}
+	return slugs
+}
+
+func toTagResponses(tags []tag.Tag) []TagResponse {
+	response := make([]TagResponse, 0, len(tags))
+	for _, v := range tags {
+		response = append(response, TagResponse{
+			Id:   int(v.ID),
+			Name: v.Name,
+			Slug: v.Slug,
+		})
+	}
+	return response
+}
+
+const (
+	RevisionCreate = "create"
+	RevisionUpdate = "update"
+	RevisionRevert = "revert"
+
+	// RevisionBaseline is the state a book had when it was first written
+	// after revisions started being kept.
+	RevisionBaseline = "baseline"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+type RevertRequest struct {
+	Id      int
+	Number  int
+	Version uint
+}
+
+type RevisionResponse struct {
+	Number       uint          `json:"number"`
+	Version      uint          `json:"version"`
+	Action       string        `json:"action"`
+	RevertedFrom *uint         `json:"reverted_from,omitempty"`
+	ActorID      uint          `json:"actor_id"`
+	Snapshot     PatchDocument `json:"snapshot"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+type RevisionDiffResponse struct {
+	From    uint                         `json:"from"`
+	To      uint                         `json:"to"`
+	Changes map[string]audit.FieldChange `json:"changes"`
+}
+
+func ToRevisionResponse(entity *Revision) *RevisionResponse {
+	var snapshot PatchDocument
+	_ = json.Unmarshal([]byte(entity.Snapshot), &snapshot)
+
+	return &RevisionResponse{
+		Number:       entity.Number,
+		Version:      entity.Version,
+		Action:       entity.Action,
+		RevertedFrom: entity.RevertedFrom,
+		ActorID:      entity.ActorID,
+		Snapshot:     snapshot,
+		CreatedAt:    entity.CreatedAt,
 	}
 }
@@ -0,0 +1,48 @@
+package book
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateLocales(t *testing.T) {
+	tests := []struct {
+		name          string
+		localizations map[string]LocalizationRequest
+		wantErrors    int
+	}{
+		{"none", nil, 0},
+		{"distinct", map[string]LocalizationRequest{"en": {}, "en-GB": {}, "de": {}}, 0},
+		{"two spellings", map[string]LocalizationRequest{"en-gb": {}, "en-GB": {}}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := validateLocales(test.localizations)
+			if len(errs) != test.wantErrors {
+				t.Fatalf("validateLocales() = %v, want %d errors", errs, test.wantErrors)
+			}
+			if len(errs) > 0 && errs[0].Field != "localizations" {
+				t.Errorf("validateLocales() field = %s, want localizations", errs[0].Field)
+			}
+		})
+	}
+}
+
+func TestToLocalizations(t *testing.T) {
+	if got := toLocalizations(nil); got != nil {
+		t.Errorf("toLocalizations(nil) = %v, want nil", got)
+	}
+
+	got := toLocalizations(map[string]LocalizationRequest{
+		"fr":    {Title: "Coder proprement"},
+		"de-at": {Title: "Sauberer Code", Description: "Ein Handbuch"},
+	})
+	want := []Localization{
+		{Locale: "de-AT", Title: "Sauberer Code", Description: "Ein Handbuch"},
+		{Locale: "fr", Title: "Coder proprement"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toLocalizations() = %+v, want %+v", got, want)
+	}
+}
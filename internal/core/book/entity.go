@@ -5,19 +5,75 @@ import (
 	"starter/internal/core/author"
 	"starter/internal/core/category"
 	"starter/internal/core/publisher"
+	"starter/internal/core/tag"
+	"starter/pkg/helper"
 	"time"
 )
 
 type Book struct {
-	Title           string
+	Title string
+	// TitleKey is the folded title searches go by.
+	TitleKey        string `gorm:"not null;default:'';index"`
 	Cover           string
 	Description     string
 	PageCount       int
 	AuthorId        int
-	Author          author.Author
-	Categories      []category.Category `gorm:"many2many:book_category;"`
+	Author          author.Author       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Categories      []category.Category `gorm:"many2many:book_category;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Tags            []tag.Tag           `gorm:"many2many:book_tag;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Subjects        []tag.Tag           `gorm:"many2many:book_subject;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	PublisherId     int
-	Publisher       publisher.Publisher
+	Publisher       publisher.Publisher `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
 	PublicationDate time.Time
+	// Language is the BCP 47 tag of Title and Description. A translation
+	// points at the book it was translated from through OriginalID.
+	Language      string         `gorm:"size:35;not null;default:'';index"`
+	OriginalID    *uint          `gorm:"index"`
+	Original      *Book          `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Localizations []Localization `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// The rating columns are kept up to date by the review domain with
+	// relative updates, book writes never touch them.
+	RatingCount   int     `gorm:"<-:false;not null;default:0"`
+	RatingSum     int     `gorm:"<-:false;not null;default:0"`
+	RatingAverage float64 `gorm:"<-:false;type:numeric(3,2);not null;default:0"`
+	Version       uint    `gorm:"not null;default:1"`
 	gorm.Model
 }
+
+// SetKey derives TitleKey from the title, writes call it before saving.
+func (book *Book) SetKey() {
+	book.TitleKey = helper.Fold(book.Title)
+}
+
+// Localization is the title and description of a book in another locale,
+// served instead of the book's own when the client prefers that locale.
+type Localization struct {
+	ID          uint   `gorm:"primaryKey"`
+	BookID      uint   `gorm:"not null;uniqueIndex:idx_book_localizations_book_locale,priority:1"`
+	Locale      string `gorm:"size:35;not null;uniqueIndex:idx_book_localizations_book_locale,priority:2"`
+	Title       string `gorm:"size:100;not null"`
+	Description string
+}
+
+func (Localization) TableName() string {
+	return "book_localizations"
+}
+
+// Revision is the state of a book right after one of its writes. Snapshot is
+// the PatchDocument of the book as JSON, so the author, publisher and category
+// links are part of it.
+type Revision struct {
+	ID           uint   `gorm:"primaryKey"`
+	BookID       uint   `gorm:"not null;uniqueIndex:idx_book_revisions_book_number,priority:1"`
+	Number       uint   `gorm:"not null;uniqueIndex:idx_book_revisions_book_number,priority:2"`
+	Version      uint   `gorm:"not null"`
+	Action       string `gorm:"size:16;not null"`
+	RevertedFrom *uint
+	ActorID      uint
+	Snapshot     string `gorm:"type:jsonb;not null"`
+	CreatedAt    time.Time
+}
+
+func (Revision) TableName() string {
+	return "book_revisions"
+}
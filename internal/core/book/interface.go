@@ -5,20 +5,46 @@ import (
 	"gorm.io/gorm"
 	"starter/internal/core/filter"
 	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
+	"starter/internal/core/tag"
 )
 
 type Repository interface {
 	Save(db *gorm.DB, Book *Book) error
 	Update(db *gorm.DB, Book *Book) error
-	Delete(db *gorm.DB, id int) error
-	FindAll(db *gorm.DB, params pagination.Request, filter filter.BookFilter) ([]Book, int64, error)
-	FindByID(db *gorm.DB, id int) (Book, error)
+	Patch(db *gorm.DB, Book *Book, columns []string) error
+	Delete(db *gorm.DB, id int, version uint) error
+	FindAll(db *gorm.DB, params pagination.Request, filter filter.BookFilter, view projection.Request) ([]Book, pagination.Meta, error)
+	FindByID(db *gorm.DB, id int, view projection.Request) (Book, error)
+	FindShelves(db *gorm.DB, userID uint, ids []uint) (map[uint][]ShelfResponse, error)
+	FindLocalizations(db *gorm.DB, ids []uint) (map[uint][]Localization, error)
+	FindTranslations(db *gorm.DB, ids []uint) (map[uint][]TranslationResponse, error)
+	CountTranslations(db *gorm.DB, id uint) (int64, error)
+	SaveTags(db *gorm.DB, tags []tag.Tag) error
+	FindTags(db *gorm.DB, kind string, slugs []string) ([]tag.Tag, error)
+	LastRevisionNumber(db *gorm.DB, id uint) (uint, error)
+	SaveRevision(db *gorm.DB, revision *Revision) error
+	FindRevisions(db *gorm.DB, id int, params pagination.Request) ([]Revision, pagination.Meta, error)
+	FindRevision(db *gorm.DB, id int, number int) (Revision, error)
 }
 
 type Usecase interface {
 	Save(ctx context.Context, request CreateRequest) (Response, error)
 	Update(ctx context.Context, request UpdateRequest) (*Response, error)
-	Delete(ctx context.Context, id int) error
-	FindAll(ctx context.Context, request *pagination.Request, filter *filter.BookFilter) (pagination.Page[Response], error)
-	FindById(ctx context.Context, id int) (*Response, error)
+	Patch(ctx context.Context, request patch.Request) (*Response, error)
+	Delete(ctx context.Context, id int, version uint) error
+	FindAll(ctx context.Context, request *pagination.Request, filter *filter.BookFilter, view *projection.Request) (pagination.Page[Response], error)
+	FindById(ctx context.Context, id int, view *projection.Request) (*Response, error)
+	FindRevisions(ctx context.Context, id int, request *pagination.Request) (pagination.Page[RevisionResponse], error)
+	FindRevision(ctx context.Context, id int, number int) (*RevisionResponse, error)
+	DiffRevisions(ctx context.Context, id int, from int, to int) (*RevisionDiffResponse, error)
+	Revert(ctx context.Context, request RevertRequest) (*Response, error)
+	Lookup(ctx context.Context, request LookupRequest) (*LookupResponse, error)
+}
+
+// MetadataProvider looks an edition up by ISBN in an external bibliographic
+// source. It returns ErrMetadataNotFound when the source doesn't know it.
+type MetadataProvider interface {
+	Lookup(ctx context.Context, isbn string) (*Metadata, error)
 }
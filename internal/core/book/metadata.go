@@ -0,0 +1,54 @@
+package book
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrMetadataNotFound = errors.New("no metadata found for the isbn")
+	// ErrMetadataUnavailable is returned when the metadata source could not
+	// be reached or answered with something unexpected.
+	ErrMetadataUnavailable = errors.New("metadata source is unavailable")
+)
+
+type LookupRequest struct {
+	ISBN string `json:"isbn" validate:"required,isbn"`
+}
+
+// Normalize drops the hyphens and spaces ISBNs are usually printed with.
+func (request *LookupRequest) Normalize() {
+	request.ISBN = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(request.ISBN))
+}
+
+// Metadata is what a MetadataProvider knows about an edition. Anything the
+// source doesn't have is left empty, PublicationDate is yyyy-mm-dd with the
+// month and day defaulting to the first when the source is less precise.
+type Metadata struct {
+	ISBN            string   `json:"isbn"`
+	Title           string   `json:"title"`
+	Authors         []string `json:"authors"`
+	Publisher       string   `json:"publisher"`
+	PageCount       int      `json:"page_count"`
+	Description     string   `json:"description"`
+	Cover           string   `json:"cover"`
+	PublicationDate string   `json:"publication_date"`
+}
+
+// LookupResponse is a CreateRequest filled in from the metadata, which is
+// returned as well. The author and publisher ids are only set when one with
+// the same name is already stored.
+type LookupResponse struct {
+	CreateRequest
+	Source Metadata `json:"source"`
+}
+
+func (metadata *Metadata) ToCreateRequest() CreateRequest {
+	return CreateRequest{
+		Title:           metadata.Title,
+		Cover:           metadata.Cover,
+		Description:     metadata.Description,
+		PageCount:       metadata.PageCount,
+		PublicationDate: metadata.PublicationDate,
+	}
+}
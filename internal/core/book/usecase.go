@@ -2,21 +2,43 @@ package book
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
 	"gorm.io/gorm"
+	"slices"
+	"sort"
+	"starter/internal/core/audit"
+	"starter/internal/core/author"
+	"starter/internal/core/concurrency"
 	"starter/internal/core/filter"
+	"starter/internal/core/locale"
+	"starter/internal/core/metrics"
 	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
+	"starter/internal/core/publisher"
 	"starter/internal/core/storage"
+	"starter/internal/core/tag"
 	ivalidator "starter/internal/core/validator"
 	"starter/pkg/helper"
 )
 
+var tracer = otel.Tracer("starter/internal/core/book")
+
 type UsecaseDependency struct {
 	DB             *gorm.DB
 	Validator      ivalidator.Validator
 	Storage        storage.Storage
+	Metrics        metrics.Recorder
+	Audit          audit.Recorder
+	Metadata       MetadataProvider
 	BookRepository Repository
+	// AuthorRepository and PublisherRepository match the names of a
+	// metadata lookup against the stored ones.
+	AuthorRepository    author.Repository
+	PublisherRepository publisher.Repository
 }
 
 type UsecaseImpl struct {
@@ -30,78 +52,261 @@ func NewUsecase(deps UsecaseDependency) Usecase {
 }
 
 func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Response, error) {
+	ctx, span := tracer.Start(ctx, "book.Usecase.Save")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
+	var err error
+	book := request.ToEntity()
 	validation := usecase.Validator.ValidateStruct(request)
+	validation = append(validation, validateLocales(request.Localizations)...)
+	if validation == nil {
+		validation, err = usecase.resolve(ctx, tx, book)
+		if err != nil {
+			return Response{}, err
+		}
+	}
 	if validation != nil {
-		log.Error().Msgf("Validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
 		return Response{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
 	}
 
-	book := request.ToEntity()
-	err := usecase.BookRepository.Save(tx, book)
+	err = usecase.BookRepository.Save(tx, book)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save book")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionCreate,
+		EntityType: "book",
+		EntityID:   book.ID,
+		After:      ToResponse(book),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit book creation")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	err = usecase.revise(ctx, tx, nil, book, RevisionCreate, nil)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save book revision")
+		return Response{}, errors.New("something went wrong")
+	}
+
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return Response{}, errors.New("something went wrong")
 	}
+	usecase.Metrics.BookCreated()
 
 	return *ToResponse(book), nil
 }
 
 func (usecase *UsecaseImpl) Update(ctx context.Context, request UpdateRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "book.Usecase.Update")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
+	var err error
+	entity := request.ToEntity()
 	validation := usecase.Validator.ValidateStruct(request)
+	validation = append(validation, validateLocales(request.Localizations)...)
+	if validation == nil {
+		validation, err = usecase.resolve(ctx, tx, entity)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if validation != nil {
-		log.Error().Msgf("Validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
 		return &Response{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
 	}
 
-	book, err := usecase.BookRepository.FindByID(tx, request.Id)
+	book, err := usecase.BookRepository.FindByID(tx, request.Id, projection.Request{})
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to find book by id: %+v", request.Id)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find book by id: %+v", request.Id)
 		return nil, errors.New("book not found")
 	}
-	updated := helper.Differ(book, *request.ToEntity()).(Book)
+	updated := helper.Differ(book, *entity).(Book)
+	if request.Version != 0 {
+		updated.Version = request.Version
+	}
 
 	err = usecase.BookRepository.Update(tx, &updated)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update book")
+		return nil, errors.New("something went wrong")
+	}
+
+	current, err := usecase.BookRepository.FindByID(tx, request.Id, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to reload book with id: %d", request.Id)
+		return nil, errors.New("something went wrong")
+	}
+	err = usecase.revise(ctx, tx, &book, &current, RevisionUpdate, nil)
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to update book")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save book revision")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "book",
+		EntityID:   updated.ID,
+		Before:     ToResponse(&book),
+		After:      ToResponse(&updated),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit book update")
 		return nil, errors.New("something went wrong")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return &Response{}, errors.New("something went wrong")
 	}
 	return ToResponse(&updated), nil
 }
 
-func (usecase *UsecaseImpl) Delete(ctx context.Context, id int) error {
+func (usecase *UsecaseImpl) Patch(ctx context.Context, request patch.Request) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "book.Usecase.Patch")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
-	err := usecase.BookRepository.Delete(tx, id)
+	book, err := usecase.BookRepository.FindByID(tx, request.Id, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find book by id: %+v", request.Id)
+		return nil, errors.New("book not found")
+	}
+	original := book
+	before := ToResponse(&book)
+	if request.Version != 0 {
+		book.Version = request.Version
+	}
+
+	document := ToPatchDocument(&book)
+	keys, err := patch.Apply(document, request.Patch)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to apply patch to book")
+		return nil, err
+	}
+
+	columns, validation := PatchableFields.Columns(keys)
+	validation = append(validation, usecase.Validator.ValidateStruct(document)...)
+	validation = append(validation, validateLocales(document.Localizations)...)
+	if validation == nil {
+		document.Apply(&book)
+		validation, err = usecase.resolve(ctx, tx, &book)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	err = usecase.BookRepository.Patch(tx, &book, columns)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to patch book")
+		return nil, errors.New("something went wrong")
+	}
+
+	book, err = usecase.BookRepository.FindByID(tx, request.Id, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to reload book with id: %d", request.Id)
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.revise(ctx, tx, &original, &book, RevisionUpdate, nil)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save book revision")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "book",
+		EntityID:   book.ID,
+		Before:     before,
+		After:      ToResponse(&book),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit book patch")
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToResponse(&book), nil
+}
+
+func (usecase *UsecaseImpl) Delete(ctx context.Context, id int, version uint) error {
+	ctx, span := tracer.Start(ctx, "book.Usecase.Delete")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	book, err := usecase.BookRepository.FindByID(tx, id, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find book by id: %+v", id)
+		return errors.New("book not found")
+	}
+
+	err = usecase.BookRepository.Delete(tx, id, version)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return err
+	}
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to update book")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update book")
+		return errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionDelete,
+		EntityType: "book",
+		EntityID:   book.ID,
+		Before:     ToResponse(&book),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit book deletion")
 		return errors.New("something went wrong")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return errors.New("something went wrong")
 	}
 
 	return nil
 }
 
-func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Request, query *filter.BookFilter) (pagination.Page[Response], error) {
+func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Request, query *filter.BookFilter, view *projection.Request) (pagination.Page[Response], error) {
+	ctx, span := tracer.Start(ctx, "book.Usecase.FindAll")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
@@ -109,24 +314,34 @@ func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Req
 	filter.NewBookFilter(query)
 
 	validation := usecase.Validator.ValidateStruct(request)
+	validation = append(validation, request.ParseSort(SortableFields)...)
 	if validation != nil {
-		log.Error().Msgf("validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
 		return pagination.Page[Response]{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
 	}
 
 	validation = usecase.Validator.ValidateStruct(query)
+	validation = append(validation, usecase.Validator.ValidateStruct(view)...)
+	validation = append(validation, view.Parse(ProjectableFields)...)
 	if validation != nil {
-		log.Error().Msgf("validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
 		return pagination.Page[Response]{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
 	}
 
-	books, count, err := usecase.BookRepository.FindAll(tx, *request, *query)
+	query.Tags = tag.Slugs(query.Tags)
+	query.Subjects = tag.Slugs(query.Subjects)
+	query.Language = locale.Canonical(query.Language)
+
+	books, meta, err := usecase.BookRepository.FindAll(tx, *request, *query, *view)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return pagination.Page[Response]{}, pagination.CursorError()
+	}
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to fetch books")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to fetch books")
 		return pagination.Page[Response]{}, errors.New("something went wrong")
 	}
 
@@ -144,21 +359,43 @@ func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Req
 		response = append(response, *ToResponse(&book))
 	}
 
+	if err = usecase.shelve(ctx, tx, *view, response); err != nil {
+		return pagination.Page[Response]{}, err
+	}
+	if err = usecase.translate(ctx, tx, *view, response); err != nil {
+		return pagination.Page[Response]{}, err
+	}
+	if err = usecase.localize(ctx, tx, response); err != nil {
+		return pagination.Page[Response]{}, err
+	}
+
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return pagination.Page[Response]{}, errors.New("something went wrong")
 	}
 
-	return *pagination.NewPage[Response](*request, count, response), nil
+	return *pagination.NewPage[Response](*request, meta, response), nil
 }
 
-func (usecase *UsecaseImpl) FindById(ctx context.Context, id int) (*Response, error) {
+func (usecase *UsecaseImpl) FindById(ctx context.Context, id int, view *projection.Request) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "book.Usecase.FindById")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
-	book, err := usecase.BookRepository.FindByID(tx, id)
+	validation := usecase.Validator.ValidateStruct(view)
+	validation = append(validation, view.Parse(ProjectableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	book, err := usecase.BookRepository.FindByID(tx, id, *view)
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to find book with id: %d", id)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find book with id: %d", id)
 		return nil, errors.New("book not found")
 	}
 
@@ -172,8 +409,523 @@ func (usecase *UsecaseImpl) FindById(ctx context.Context, id int) (*Response, er
 		book.Cover = cover.Link
 	}
 
+	response := []Response{*ToResponse(&book)}
+	if err = usecase.shelve(ctx, tx, *view, response); err != nil {
+		return nil, err
+	}
+	if err = usecase.translate(ctx, tx, *view, response); err != nil {
+		return nil, err
+	}
+	if err = usecase.localize(ctx, tx, response); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return &response[0], nil
+}
+
+// Lookup asks the metadata provider about an ISBN and turns the answer into
+// a CreateRequest, matching the first author and the publisher against the
+// stored ones. Nothing is written.
+func (usecase *UsecaseImpl) Lookup(ctx context.Context, request LookupRequest) (*LookupResponse, error) {
+	ctx, span := tracer.Start(ctx, "book.Usecase.Lookup")
+	defer span.End()
+
+	request.Normalize()
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	metadata, err := usecase.Metadata.Lookup(ctx, request.ISBN)
+	if errors.Is(err, ErrMetadataNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to look up isbn %s", request.ISBN)
+		return nil, ErrMetadataUnavailable
+	}
+
+	db := usecase.DB.WithContext(ctx)
+	response := LookupResponse{
+		CreateRequest: metadata.ToCreateRequest(),
+		Source:        *metadata,
+	}
+	if len(metadata.Authors) > 0 {
+		author, err := usecase.AuthorRepository.FindByKey(db, helper.Fold(metadata.Authors[0]))
+		if err == nil {
+			response.AuthorId = int(author.ID)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to match author %s", metadata.Authors[0])
+			return nil, errors.New("something went wrong")
+		}
+	}
+	if metadata.Publisher != "" {
+		publisher, err := usecase.PublisherRepository.FindByKey(db, helper.Fold(metadata.Publisher))
+		if err == nil {
+			response.PublisherId = int(publisher.ID)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to match publisher %s", metadata.Publisher)
+			return nil, errors.New("something went wrong")
+		}
+	}
+
+	return &response, nil
+}
+
+// shelve fills in which of the caller's shelves hold each book, unless the
+// view leaves shelves out.
+func (usecase *UsecaseImpl) shelve(ctx context.Context, tx *gorm.DB, view projection.Request, response []Response) error {
+	userID := audit.ActorFrom(ctx).UserID
+	if userID == 0 || !view.Has("shelves") || len(response) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(response))
+	for _, book := range response {
+		ids = append(ids, uint(book.Id))
+	}
+	shelves, err := usecase.BookRepository.FindShelves(tx, userID, ids)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find shelves of books")
+		return errors.New("something went wrong")
+	}
+
+	for i := range response {
+		response[i].Shelves = shelves[uint(response[i].Id)]
+	}
+	return nil
+}
+
+// translate fills in the translations of each book, unless the view leaves
+// them out.
+func (usecase *UsecaseImpl) translate(ctx context.Context, tx *gorm.DB, view projection.Request, response []Response) error {
+	if !view.Has("translations") || len(response) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(response))
+	for _, book := range response {
+		ids = append(ids, uint(book.Id))
+	}
+	translations, err := usecase.BookRepository.FindTranslations(tx, ids)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find translations of books")
+		return errors.New("something went wrong")
+	}
+
+	for i := range response {
+		response[i].Translations = translations[uint(response[i].Id)]
+	}
+	return nil
+}
+
+// localize serves each book's title and description in the variant that
+// suits the languages the caller prefers best, the book's own language
+// included. Locale tells which one was picked.
+func (usecase *UsecaseImpl) localize(ctx context.Context, tx *gorm.DB, response []Response) error {
+	preferred := locale.PreferredFrom(ctx)
+	if len(preferred) == 0 || len(response) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(response))
+	for _, book := range response {
+		ids = append(ids, uint(book.Id))
+	}
+	localizations, err := usecase.BookRepository.FindLocalizations(tx, ids)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find localizations of books")
+		return errors.New("something went wrong")
+	}
+
+	for i := range response {
+		variants := localizations[uint(response[i].Id)]
+		if len(variants) == 0 {
+			continue
+		}
+
+		candidates := []string{response[i].Language}
+		for _, variant := range variants {
+			candidates = append(candidates, variant.Locale)
+		}
+		index := locale.Match(preferred, candidates)
+		if index < 1 {
+			continue
+		}
+
+		variant := variants[index-1]
+		response[i].Title = variant.Title
+		if variant.Description != "" {
+			response[i].Description = variant.Description
+		}
+		response[i].Locale = variant.Locale
+	}
+	return nil
+}
+
+// resolve prepares book for a write: it stores its tags and checks the book
+// it is a translation of.
+func (usecase *UsecaseImpl) resolve(ctx context.Context, tx *gorm.DB, book *Book) ([]ivalidator.ValidationError, error) {
+	validation, err := usecase.label(ctx, tx, book)
+	if validation != nil || err != nil {
+		return validation, err
+	}
+	return usecase.validateOriginal(ctx, tx, book)
+}
+
+// validateOriginal checks that the book a translation points at exists and
+// is an original itself, so that translations hang off a single book.
+func (usecase *UsecaseImpl) validateOriginal(ctx context.Context, tx *gorm.DB, book *Book) ([]ivalidator.ValidationError, error) {
+	if book.OriginalID == nil {
+		return nil, nil
+	}
+	if *book.OriginalID == book.ID {
+		return []ivalidator.ValidationError{{
+			Field:   "original_id",
+			Message: "original_id cannot be the book itself",
+		}}, nil
+	}
+
+	original, err := usecase.BookRepository.FindByID(tx, int(*book.OriginalID), projection.Request{})
+	if err != nil {
+		return []ivalidator.ValidationError{{
+			Field:   "original_id",
+			Message: "original_id must be an existing book",
+		}}, nil
+	}
+	if original.OriginalID != nil {
+		return []ivalidator.ValidationError{{
+			Field:   "original_id",
+			Message: "original_id must be a book that is not a translation itself",
+		}}, nil
+	}
+
+	if book.ID != 0 {
+		translations, err := usecase.BookRepository.CountTranslations(tx, book.ID)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to count translations of book %d", book.ID)
+			return nil, errors.New("something went wrong")
+		}
+		if translations > 0 {
+			return []ivalidator.ValidationError{{
+				Field:   "original_id",
+				Message: "a book with translations cannot be a translation itself",
+			}}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// label swaps the tags and subjects of book for the stored ones. Free-form
+// tags are created on first use, subjects have to be headings of the
+// vocabulary already. Nil lists are left alone.
+func (usecase *UsecaseImpl) label(ctx context.Context, tx *gorm.DB, book *Book) ([]ivalidator.ValidationError, error) {
+	var validation []ivalidator.ValidationError
+	if slices.Contains(tagSlugs(book.Tags), "") {
+		validation = append(validation, ivalidator.ValidationError{
+			Field:   "tags",
+			Message: "tags must contain letters or digits",
+		})
+	}
+	if slices.Contains(tagSlugs(book.Subjects), "") {
+		validation = append(validation, ivalidator.ValidationError{
+			Field:   "subjects",
+			Message: "subjects must contain letters or digits",
+		})
+	}
+	if validation != nil {
+		return validation, nil
+	}
+
+	if book.Tags != nil {
+		err := usecase.BookRepository.SaveTags(tx, book.Tags)
+		if err == nil {
+			book.Tags, err = usecase.BookRepository.FindTags(tx, tag.KindTag, tagSlugs(book.Tags))
+		}
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to save tags of book")
+			return nil, errors.New("something went wrong")
+		}
+	}
+
+	if book.Subjects != nil {
+		subjects, err := usecase.BookRepository.FindTags(tx, tag.KindSubject, tagSlugs(book.Subjects))
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to find subjects of book")
+			return nil, errors.New("something went wrong")
+		}
+		if len(subjects) < len(book.Subjects) {
+			return []ivalidator.ValidationError{{
+				Field:   "subjects",
+				Message: "subjects must be existing subject headings",
+			}}, nil
+		}
+		book.Subjects = subjects
+	}
+
+	return nil, nil
+}
+
+// revise appends a revision holding the state of current. A book written
+// before revisions were kept first gets the state it had before this write,
+// previous, as a baseline so that it can be reverted to as well.
+func (usecase *UsecaseImpl) revise(ctx context.Context, tx *gorm.DB, previous *Book, current *Book, action string, revertedFrom *uint) error {
+	number, err := usecase.BookRepository.LastRevisionNumber(tx, current.ID)
+	if err != nil {
+		return err
+	}
+
+	if number == 0 && previous != nil {
+		number++
+		baseline, err := newRevision(previous, number, RevisionBaseline, nil, 0)
+		if err != nil {
+			return err
+		}
+		if err = usecase.BookRepository.SaveRevision(tx, baseline); err != nil {
+			return err
+		}
+	}
+
+	revision, err := newRevision(current, number+1, action, revertedFrom, audit.ActorFrom(ctx).UserID)
+	if err != nil {
+		return err
+	}
+	return usecase.BookRepository.SaveRevision(tx, revision)
+}
+
+func newRevision(book *Book, number uint, action string, revertedFrom *uint, actor uint) (*Revision, error) {
+	snapshot, err := json.Marshal(ToPatchDocument(book))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Revision{
+		BookID:       book.ID,
+		Number:       number,
+		Version:      book.Version,
+		Action:       action,
+		RevertedFrom: revertedFrom,
+		ActorID:      actor,
+		Snapshot:     string(snapshot),
+	}, nil
+}
+
+func (usecase *UsecaseImpl) FindRevisions(ctx context.Context, id int, request *pagination.Request) (pagination.Page[RevisionResponse], error) {
+	ctx, span := tracer.Start(ctx, "book.Usecase.FindRevisions")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	// The latest revision comes first unless asked otherwise.
+	if request.Sort == "" && request.OrderBy == "" && request.SortBy == "" {
+		request.Sort = "-number"
+	}
+	pagination.NewPagination(request)
+
+	validation := usecase.Validator.ValidateStruct(request)
+	validation = append(validation, request.ParseSort(RevisionSortableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return pagination.Page[RevisionResponse]{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	revisions, meta, err := usecase.BookRepository.FindRevisions(tx, id, *request)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return pagination.Page[RevisionResponse]{}, pagination.CursorError()
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to fetch revisions of book %d", id)
+		return pagination.Page[RevisionResponse]{}, errors.New("something went wrong")
+	}
+
+	var response []RevisionResponse
+	for _, revision := range revisions {
+		response = append(response, *ToRevisionResponse(&revision))
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return pagination.Page[RevisionResponse]{}, errors.New("something went wrong")
+	}
+
+	return *pagination.NewPage[RevisionResponse](*request, meta, response), nil
+}
+
+func (usecase *UsecaseImpl) FindRevision(ctx context.Context, id int, number int) (*RevisionResponse, error) {
+	ctx, span := tracer.Start(ctx, "book.Usecase.FindRevision")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	revision, err := usecase.findRevision(ctx, tx, id, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToRevisionResponse(&revision), nil
+}
+
+func (usecase *UsecaseImpl) findRevision(ctx context.Context, tx *gorm.DB, id int, number int) (Revision, error) {
+	revision, err := usecase.BookRepository.FindRevision(tx, id, number)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return revision, ErrRevisionNotFound
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find revision %d of book %d", number, id)
+		return revision, errors.New("something went wrong")
+	}
+	return revision, nil
+}
+
+func (usecase *UsecaseImpl) DiffRevisions(ctx context.Context, id int, from int, to int) (*RevisionDiffResponse, error) {
+	ctx, span := tracer.Start(ctx, "book.Usecase.DiffRevisions")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	var validation []ivalidator.ValidationError
+	for field, number := range map[string]int{"from": from, "to": to} {
+		if number < 1 {
+			validation = append(validation, ivalidator.ValidationError{
+				Field:   field,
+				Message: field + " must be a revision number",
+			})
+		}
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	older, err := usecase.findRevision(ctx, tx, id, from)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := usecase.findRevision(ctx, tx, id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := audit.Diff(ToRevisionResponse(&older).Snapshot, ToRevisionResponse(&newer).Snapshot)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to diff revisions of book %d", id)
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return &RevisionDiffResponse{
+		From:    older.Number,
+		To:      newer.Number,
+		Changes: changes,
+	}, nil
+}
+
+// Revert writes the snapshot of an earlier revision back to the book. The
+// result is recorded as a new revision, history is never rewritten.
+func (usecase *UsecaseImpl) Revert(ctx context.Context, request RevertRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "book.Usecase.Revert")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	book, err := usecase.BookRepository.FindByID(tx, request.Id, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find book by id: %+v", request.Id)
+		return nil, errors.New("book not found")
+	}
+	revision, err := usecase.findRevision(ctx, tx, request.Id, request.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	original := book
+	before := ToResponse(&book)
+	if request.Version != 0 {
+		book.Version = request.Version
+	}
+
+	document := ToRevisionResponse(&revision).Snapshot
+	validation := usecase.Validator.ValidateStruct(document)
+	if validation == nil {
+		document.Apply(&book)
+		validation, err = usecase.resolve(ctx, tx, &book)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	keys := make([]string, 0, len(PatchableFields))
+	for key := range PatchableFields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	columns, _ := PatchableFields.Columns(keys)
+
+	err = usecase.BookRepository.Patch(tx, &book, columns)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to revert book")
+		return nil, errors.New("something went wrong")
+	}
+
+	book, err = usecase.BookRepository.FindByID(tx, request.Id, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to reload book with id: %d", request.Id)
+		return nil, errors.New("something went wrong")
+	}
+
+	number := revision.Number
+	err = usecase.revise(ctx, tx, &original, &book, RevisionRevert, &number)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save book revision")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "book",
+		EntityID:   book.ID,
+		Before:     before,
+		After:      ToResponse(&book),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit book revert to revision %d", request.Number)
+		return nil, errors.New("something went wrong")
+	}
+
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return nil, errors.New("something went wrong")
 	}
 	return ToResponse(&book), nil
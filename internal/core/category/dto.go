@@ -2,27 +2,103 @@ package category
 
 import (
 	"gorm.io/gorm"
-	"strings"
+	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
 )
 
+// SortableFields lists what FindAll accepts in the sort parameter.
+var SortableFields = pagination.Sortable{
+	"id":         "categories.id",
+	"name":       "categories.name",
+	"created_at": "categories.created_at",
+	"updated_at": "categories.updated_at",
+}
+
+// ProjectableFields lists what read endpoints accept in fields and include.
+var ProjectableFields = projection.Projectable{
+	ID:      "categories.id",
+	Version: "categories.version",
+	Fields: map[string]string{
+		"name":      "categories.name",
+		"parent_id": "categories.parent_id",
+		// The breadcrumbs are looked up by the category id.
+		"breadcrumbs": "categories.id",
+	},
+}
+
+// PatchableFields lists the keys PATCH accepts and the columns they write.
+var PatchableFields = patch.Fields{
+	"name": "name",
+}
+
 type CreateRequest struct {
-	Name string `json:"name" validate:"required,max=100"`
+	Name     string `json:"name" validate:"required,max=100"`
+	ParentId uint   `json:"parent_id"`
 }
 
 type UpdateRequest struct {
-	Id   int    `json:"id" validate:"required"`
-	Name string `json:"name" validate:"max=100"`
+	Id      int    `json:"id" validate:"required"`
+	Name    string `json:"name" validate:"max=100"`
+	Version uint   `json:"-"`
 }
 
-type Response struct {
+// PatchDocument is the category a merge patch is applied to. Its rules are the
+// ones a stored category has to satisfy, so anything optional can be cleared.
+type PatchDocument struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// MoveRequest puts a category, with its whole subtree, under another parent.
+// A null parent_id makes it a root.
+type MoveRequest struct {
+	Id       int   `json:"-"`
+	ParentId *uint `json:"parent_id"`
+	Version  uint  `json:"-"`
+}
+
+// TreeRequest picks the subtree to return, the whole forest without Root.
+// Depth limits how many levels below the top are included.
+type TreeRequest struct {
+	Root  uint `json:"root"`
+	Depth int  `json:"depth" validate:"omitempty,min=1,max=20"`
+}
+
+// BreadcrumbResponse is one step of the path from the root to a category.
+type BreadcrumbResponse struct {
 	Id   int    `json:"id"`
 	Name string `json:"name"`
 }
 
+type Response struct {
+	Id          int                  `json:"id"`
+	Name        string               `json:"name"`
+	ParentId    *uint                `json:"parent_id"`
+	Breadcrumbs []BreadcrumbResponse `json:"breadcrumbs,omitempty"`
+	Version     uint                 `json:"version"`
+}
+
+type TreeResponse struct {
+	Id       int            `json:"id"`
+	Name     string         `json:"name"`
+	Children []TreeResponse `json:"children"`
+}
+
+// Node is a category of a subtree with its depth below the subtree root.
+type Node struct {
+	Category
+	Depth int
+}
+
 func (dto *CreateRequest) ToEntity() *Category {
-	return &Category{
-		Name: strings.ToUpper(dto.Name),
+	category := &Category{
+		Name: dto.Name,
 	}
+	if dto.ParentId != 0 {
+		category.ParentID = &dto.ParentId
+	}
+
+	return category
 }
 
 func (dto *UpdateRequest) ToEntity() *Category {
@@ -32,9 +108,55 @@ func (dto *UpdateRequest) ToEntity() *Category {
 	}
 }
 
+func ToPatchDocument(entity *Category) *PatchDocument {
+	return &PatchDocument{
+		Name: entity.Name,
+	}
+}
+
+func (dto *PatchDocument) Apply(entity *Category) {
+	entity.Name = dto.Name
+}
+
 func ToResponse(entity *Category) *Response {
 	return &Response{
-		Id:   int(entity.ID),
-		Name: entity.Name,
+		Id:       int(entity.ID),
+		Name:     entity.Name,
+		ParentId: entity.ParentID,
+		Version:  entity.Version,
+	}
+}
+
+// ToTree nests nodes under their parents. Nodes whose parent isn't among
+// them are the tops of the result, in the order they came in.
+func ToTree(nodes []Node) []TreeResponse {
+	children := make(map[uint][]Node)
+	present := make(map[uint]bool, len(nodes))
+	for _, node := range nodes {
+		present[node.ID] = true
 	}
+
+	var tops []Node
+	for _, node := range nodes {
+		if node.ParentID != nil && present[*node.ParentID] {
+			children[*node.ParentID] = append(children[*node.ParentID], node)
+			continue
+		}
+		tops = append(tops, node)
+	}
+
+	var build func(nodes []Node) []TreeResponse
+	build = func(nodes []Node) []TreeResponse {
+		tree := make([]TreeResponse, 0, len(nodes))
+		for _, node := range nodes {
+			tree = append(tree, TreeResponse{
+				Id:       int(node.ID),
+				Name:     node.Name,
+				Children: build(children[node.ID]),
+			})
+		}
+		return tree
+	}
+
+	return build(tops)
 }
@@ -0,0 +1,68 @@
+package category
+
+import (
+	"gorm.io/gorm"
+	"reflect"
+	"testing"
+)
+
+func node(id uint, parent uint, name string) Node {
+	category := Category{Model: gorm.Model{ID: id}, Name: name}
+	if parent != 0 {
+		category.ParentID = &parent
+	}
+	return Node{Category: category}
+}
+
+func TestToTree(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []Node
+		want  []TreeResponse
+	}{
+		{
+			name:  "empty",
+			nodes: nil,
+			want:  []TreeResponse{},
+		},
+		{
+			name: "nests children in order",
+			nodes: []Node{
+				node(1, 0, "Programming"),
+				node(2, 1, "Go"),
+				node(3, 1, "Rust"),
+				node(4, 2, "Concurrency"),
+			},
+			want: []TreeResponse{{
+				Id: 1, Name: "Programming", Children: []TreeResponse{
+					{Id: 2, Name: "Go", Children: []TreeResponse{
+						{Id: 4, Name: "Concurrency", Children: []TreeResponse{}},
+					}},
+					{Id: 3, Name: "Rust", Children: []TreeResponse{}},
+				},
+			}},
+		},
+		{
+			name: "subtree without its parent",
+			nodes: []Node{
+				node(2, 1, "Go"),
+				node(4, 2, "Concurrency"),
+				node(5, 0, "Design"),
+			},
+			want: []TreeResponse{
+				{Id: 2, Name: "Go", Children: []TreeResponse{
+					{Id: 4, Name: "Concurrency", Children: []TreeResponse{}},
+				}},
+				{Id: 5, Name: "Design", Children: []TreeResponse{}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ToTree(test.nodes); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ToTree() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -2,9 +2,38 @@ package category
 
 import (
 	"gorm.io/gorm"
+	"starter/pkg/helper"
 )
 
+// Category is a node of the category tree, roots have no parent. The tree is
+// also kept as a closure table so subtrees and breadcrumbs take one query.
 type Category struct {
 	Name string
+	// NameKey is the folded name. New names are checked against the live
+	// children of the parent, the unique index only exists once older
+	// duplicates are merged.
+	NameKey  string    `gorm:"not null;default:'';index"`
+	ParentID *uint     `gorm:"index"`
+	Parent   *Category `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Version  uint      `gorm:"not null;default:1"`
 	gorm.Model
 }
+
+// SetKey derives NameKey from the name, writes call it before saving.
+func (category *Category) SetKey() {
+	category.NameKey = helper.Fold(category.Name)
+}
+
+// Closure links every category to itself (depth 0) and to each of its
+// descendants, depth being how many levels down they are.
+type Closure struct {
+	AncestorID   uint     `gorm:"primaryKey"`
+	Ancestor     Category `gorm:"foreignKey:AncestorID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	DescendantID uint     `gorm:"primaryKey;index"`
+	Descendant   Category `gorm:"foreignKey:DescendantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Depth        int      `gorm:"not null"`
+}
+
+func (Closure) TableName() string {
+	return "category_closure"
+}
@@ -3,21 +3,42 @@ package category
 import (
 	"context"
 	"gorm.io/gorm"
+	"starter/internal/core/integrity"
 	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
 )
 
 type Repository interface {
 	Save(db *gorm.DB, Category *Category) error
 	Update(db *gorm.DB, Category *Category) error
-	Delete(db *gorm.DB, id int) error
-	FindAll(db *gorm.DB, params pagination.Request) ([]Category, int64, error)
-	FindByID(db *gorm.DB, id int) (Category, error)
+	Patch(db *gorm.DB, Category *Category, columns []string) error
+	Delete(db *gorm.DB, id int, version uint) error
+	CountBooks(db *gorm.DB, id int) (int64, error)
+	UnlinkBooks(db *gorm.DB, id int) ([]uint, error)
+	ReassignBooks(db *gorm.DB, id int, target uint) ([]uint, error)
+	NameTaken(db *gorm.DB, parentID *uint, key string, exclude uint) (bool, error)
+	FindAll(db *gorm.DB, params pagination.Request, view projection.Request) ([]Category, pagination.Meta, error)
+	FindByID(db *gorm.DB, id int, view projection.Request) (Category, error)
+	// Link adds a new category to the closure table below its parent, Move
+	// writes its new parent and reattaches its whole subtree there.
+	Link(db *gorm.DB, category *Category) error
+	Move(db *gorm.DB, category *Category) error
+	// LockTree keeps other tree writes out until the transaction ends.
+	LockTree(db *gorm.DB) error
+	IsDescendant(db *gorm.DB, id uint, ancestor uint) (bool, error)
+	CountChildren(db *gorm.DB, id int) (int64, error)
+	FindTree(db *gorm.DB, root uint, depth int) ([]Node, error)
+	FindBreadcrumbs(db *gorm.DB, ids []uint) (map[uint][]BreadcrumbResponse, error)
 }
 
 type Usecase interface {
 	Save(ctx context.Context, request CreateRequest) (Response, error)
 	Update(ctx context.Context, request UpdateRequest) (*Response, error)
-	Delete(ctx context.Context, id int) error
-	FindAll(ctx context.Context, request *pagination.Request) (pagination.Page[Response], error)
-	FindById(ctx context.Context, id int) (*Response, error)
+	Patch(ctx context.Context, request patch.Request) (*Response, error)
+	Delete(ctx context.Context, id int, version uint, request integrity.DeleteRequest) error
+	FindAll(ctx context.Context, request *pagination.Request, view *projection.Request) (pagination.Page[Response], error)
+	FindById(ctx context.Context, id int, view *projection.Request) (*Response, error)
+	Move(ctx context.Context, request MoveRequest) (*Response, error)
+	FindTree(ctx context.Context, request TreeRequest) ([]TreeResponse, error)
 }
@@ -4,16 +4,26 @@ import (
 	"context"
 	"errors"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
 	"gorm.io/gorm"
+	"starter/internal/core/audit"
+	"starter/internal/core/concurrency"
+	"starter/internal/core/integrity"
 	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
 	ivalidator "starter/internal/core/validator"
 	"starter/pkg/helper"
 )
 
+var tracer = otel.Tracer("starter/internal/core/category")
+
 type UsecaseDependency struct {
 	DB                 *gorm.DB
 	Validator          ivalidator.Validator
+	Audit              audit.Recorder
 	CategoryRepository Repository
+	BookRule           string
 }
 
 type UsecaseImpl struct {
@@ -27,85 +37,305 @@ func NewUsecase(deps UsecaseDependency) Usecase {
 }
 
 func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Response, error) {
+	ctx, span := tracer.Start(ctx, "category.Usecase.Save")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	validation := usecase.Validator.ValidateStruct(request)
+	if validation == nil && request.ParentId != 0 {
+		if err := usecase.CategoryRepository.LockTree(tx); err != nil {
+			return Response{}, errors.New("something went wrong")
+		}
+		_, err := usecase.CategoryRepository.FindByID(tx, int(request.ParentId), projection.Request{})
+		if err != nil {
+			validation = append(validation, ivalidator.ValidationError{
+				Field:   "parent_id",
+				Message: "parent_id must be an existing category",
+			})
+		}
+	}
 	if validation != nil {
-		log.Error().Msgf("Validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
 		return Response{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
 	}
 
 	category := request.ToEntity()
-	err := usecase.CategoryRepository.Save(tx, category)
+	validation, err := usecase.validateName(ctx, tx, category, nil)
+	if err != nil {
+		return Response{}, err
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
+		return Response{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	err = usecase.CategoryRepository.Save(tx, category)
+	if err == nil {
+		err = usecase.CategoryRepository.Link(tx, category)
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return Response{}, ivalidator.ValidationErrors{Errors: nameTaken()}
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save category")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionCreate,
+		EntityType: "category",
+		EntityID:   category.ID,
+		After:      ToResponse(category),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit category creation")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	response := []Response{*ToResponse(category)}
+	if err = usecase.crumbs(ctx, tx, projection.Request{}, response); err != nil {
+		return Response{}, err
+	}
+
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return Response{}, errors.New("something went wrong")
 	}
 
-	return *ToResponse(category), nil
+	return response[0], nil
 }
 
 func (usecase *UsecaseImpl) Update(ctx context.Context, request UpdateRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "category.Usecase.Update")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	validation := usecase.Validator.ValidateStruct(request)
 	if validation != nil {
-		log.Error().Msgf("Validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
 		return &Response{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
 	}
 
-	category, err := usecase.CategoryRepository.FindByID(tx, request.Id)
+	category, err := usecase.CategoryRepository.FindByID(tx, request.Id, projection.Request{})
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to find category by id: %+v", request.Id)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find category by id: %+v", request.Id)
 		return nil, errors.New("category not found")
 	}
 	updated := helper.Differ(category, *request.ToEntity()).(Category)
+	if request.Version != 0 {
+		updated.Version = request.Version
+	}
+
+	validation, err = usecase.validateName(ctx, tx, &updated, &category)
+	if err != nil {
+		return nil, err
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
 
 	err = usecase.CategoryRepository.Update(tx, &updated)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ivalidator.ValidationErrors{Errors: nameTaken()}
+	}
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to update category")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update category")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "category",
+		EntityID:   updated.ID,
+		Before:     ToResponse(&category),
+		After:      ToResponse(&updated),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit category update")
 		return nil, errors.New("something went wrong")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return &Response{}, errors.New("something went wrong")
 	}
 	return ToResponse(&updated), nil
 }
 
-func (usecase *UsecaseImpl) Delete(ctx context.Context, id int) error {
+func (usecase *UsecaseImpl) Patch(ctx context.Context, request patch.Request) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "category.Usecase.Patch")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	category, err := usecase.CategoryRepository.FindByID(tx, request.Id, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find category by id: %+v", request.Id)
+		return nil, errors.New("category not found")
+	}
+	before := ToResponse(&category)
+	previous := category
+	if request.Version != 0 {
+		category.Version = request.Version
+	}
+
+	document := ToPatchDocument(&category)
+	keys, err := patch.Apply(document, request.Patch)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to apply patch to category")
+		return nil, err
+	}
+
+	columns, validation := PatchableFields.Columns(keys)
+	validation = append(validation, usecase.Validator.ValidateStruct(document)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+	document.Apply(&category)
+
+	validation, err = usecase.validateName(ctx, tx, &category, &previous)
+	if err != nil {
+		return nil, err
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	err = usecase.CategoryRepository.Patch(tx, &category, columns)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ivalidator.ValidationErrors{Errors: nameTaken()}
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to patch category")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "category",
+		EntityID:   category.ID,
+		Before:     before,
+		After:      ToResponse(&category),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit category patch")
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToResponse(&category), nil
+}
+
+func (usecase *UsecaseImpl) Delete(ctx context.Context, id int, version uint, request integrity.DeleteRequest) error {
+	ctx, span := tracer.Start(ctx, "category.Usecase.Delete")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
-	err := usecase.CategoryRepository.Delete(tx, id)
+	category, err := usecase.CategoryRepository.FindByID(tx, id, projection.Request{})
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to update category")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find category by id: %+v", id)
+		return errors.New("category not found")
+	}
+
+	children, err := usecase.CategoryRepository.CountChildren(tx, id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to count children of category %d", id)
+		return errors.New("something went wrong")
+	}
+	if children > 0 {
+		return integrity.RestrictedError{Relation: "categories", Count: children}
+	}
+
+	err = usecase.applyBookRule(ctx, tx, id, request)
+	if err != nil {
+		return err
+	}
+
+	err = usecase.CategoryRepository.Delete(tx, id, version)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update category")
+		return errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionDelete,
+		EntityType: "category",
+		EntityID:   category.ID,
+		Before:     ToResponse(&category),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit category deletion")
 		return errors.New("something went wrong")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return errors.New("something went wrong")
 	}
 
 	return nil
 }
 
-func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Request) (pagination.Page[Response], error) {
+func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Request, view *projection.Request) (pagination.Page[Response], error) {
+	ctx, span := tracer.Start(ctx, "category.Usecase.FindAll")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	pagination.NewPagination(request)
-	categorys, count, err := usecase.CategoryRepository.FindAll(tx, *request)
+
+	validation := usecase.Validator.ValidateStruct(request)
+	validation = append(validation, request.ParseSort(SortableFields)...)
+	validation = append(validation, usecase.Validator.ValidateStruct(view)...)
+	validation = append(validation, view.Parse(ProjectableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return pagination.Page[Response]{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	categorys, meta, err := usecase.CategoryRepository.FindAll(tx, *request, *view)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return pagination.Page[Response]{}, pagination.CursorError()
+	}
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to fetch categories")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to fetch categories")
 		return pagination.Page[Response]{}, errors.New("something went wrong")
 	}
 
@@ -113,28 +343,327 @@ func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Req
 	for _, category := range categorys {
 		response = append(response, *ToResponse(&category))
 	}
+	if err = usecase.crumbs(ctx, tx, *view, response); err != nil {
+		return pagination.Page[Response]{}, err
+	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return pagination.Page[Response]{}, errors.New("something went wrong")
 	}
 
-	return *pagination.NewPage[Response](*request, count, response), nil
+	return *pagination.NewPage[Response](*request, meta, response), nil
 }
 
-func (usecase *UsecaseImpl) FindById(ctx context.Context, id int) (*Response, error) {
+func (usecase *UsecaseImpl) FindById(ctx context.Context, id int, view *projection.Request) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "category.Usecase.FindById")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
-	category, err := usecase.CategoryRepository.FindByID(tx, id)
+	validation := usecase.Validator.ValidateStruct(view)
+	validation = append(validation, view.Parse(ProjectableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	category, err := usecase.CategoryRepository.FindByID(tx, id, *view)
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to find category with id: %d", id)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find category with id: %d", id)
 		return nil, errors.New("category not found")
 	}
+	response := []Response{*ToResponse(&category)}
+	if err = usecase.crumbs(ctx, tx, *view, response); err != nil {
+		return nil, err
+	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return nil, errors.New("something went wrong")
 	}
-	return ToResponse(&category), nil
+	return &response[0], nil
+}
+
+// Move puts the category under request.ParentId, its descendants move along.
+// A category can't be moved below itself.
+func (usecase *UsecaseImpl) Move(ctx context.Context, request MoveRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "category.Usecase.Move")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	// Taken before anything is read, so that the cycle check below sees
+	// every move committed before this one.
+	if err := usecase.CategoryRepository.LockTree(tx); err != nil {
+		return nil, errors.New("something went wrong")
+	}
+
+	category, err := usecase.CategoryRepository.FindByID(tx, request.Id, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find category by id: %+v", request.Id)
+		return nil, errors.New("category not found")
+	}
+
+	var validation []ivalidator.ValidationError
+	if request.ParentId != nil {
+		validation, err = usecase.validateParent(ctx, tx, category.ID, *request.ParentId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	before := ToResponse(&category)
+	previous := category
+	if request.Version != 0 {
+		category.Version = request.Version
+	}
+	category.ParentID = request.ParentId
+
+	if validation == nil {
+		validation, err = usecase.validateName(ctx, tx, &category, &previous)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	err = usecase.CategoryRepository.Move(tx, &category)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ivalidator.ValidationErrors{Errors: nameTaken()}
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to move category")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "category",
+		EntityID:   category.ID,
+		Before:     before,
+		After:      ToResponse(&category),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit category move")
+		return nil, errors.New("something went wrong")
+	}
+
+	response := []Response{*ToResponse(&category)}
+	if err = usecase.crumbs(ctx, tx, projection.Request{}, response); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return &response[0], nil
+}
+
+func (usecase *UsecaseImpl) FindTree(ctx context.Context, request TreeRequest) ([]TreeResponse, error) {
+	ctx, span := tracer.Start(ctx, "category.Usecase.FindTree")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	if request.Root != 0 {
+		_, err := usecase.CategoryRepository.FindByID(tx, int(request.Root), projection.Request{})
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to find category with id: %d", request.Root)
+			return nil, errors.New("category not found")
+		}
+	}
+
+	nodes, err := usecase.CategoryRepository.FindTree(tx, request.Root, request.Depth)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to fetch category tree")
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToTree(nodes), nil
+}
+
+// validateParent checks that parent exists and isn't id or one of its
+// descendants, which would turn the tree into a cycle.
+func (usecase *UsecaseImpl) validateParent(ctx context.Context, tx *gorm.DB, id uint, parent uint) ([]ivalidator.ValidationError, error) {
+	_, err := usecase.CategoryRepository.FindByID(tx, int(parent), projection.Request{})
+	if err != nil {
+		return []ivalidator.ValidationError{{
+			Field:   "parent_id",
+			Message: "parent_id must be an existing category",
+		}}, nil
+	}
+
+	below := parent == id
+	if !below {
+		below, err = usecase.CategoryRepository.IsDescendant(tx, parent, id)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to check ancestors of category %d", parent)
+			return nil, errors.New("something went wrong")
+		}
+	}
+	if below {
+		return []ivalidator.ValidationError{{
+			Field:   "parent_id",
+			Message: "parent_id cannot be the category itself or one of its descendants",
+		}}, nil
+	}
+
+	return nil, nil
+}
+
+// validateName rejects a name a sibling of category already goes by. It is
+// only checked when the name or the parent changes from previous, nil for a
+// new category, so that duplicates stored before the check can be edited.
+func (usecase *UsecaseImpl) validateName(ctx context.Context, tx *gorm.DB, category *Category, previous *Category) ([]ivalidator.ValidationError, error) {
+	category.SetKey()
+	if previous != nil && category.NameKey == previous.NameKey && sameParent(category.ParentID, previous.ParentID) {
+		return nil, nil
+	}
+
+	taken, err := usecase.CategoryRepository.NameTaken(tx, category.ParentID, category.NameKey, category.ID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to look up category name")
+		return nil, errors.New("something went wrong")
+	}
+	if taken {
+		return nameTaken(), nil
+	}
+
+	return nil, nil
+}
+
+// nameTaken is also returned when the unique index on the name key catches
+// a write that raced past validateName.
+func nameTaken() []ivalidator.ValidationError {
+	return []ivalidator.ValidationError{{
+		Field:   "name",
+		Message: "name is already taken",
+	}}
+}
+
+func sameParent(a *uint, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// crumbs fills in the path from the root of each category in response, when
+// view asks for it.
+func (usecase *UsecaseImpl) crumbs(ctx context.Context, tx *gorm.DB, view projection.Request, response []Response) error {
+	if len(response) == 0 || !view.Has("breadcrumbs") {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(response))
+	for _, category := range response {
+		ids = append(ids, uint(category.Id))
+	}
+	breadcrumbs, err := usecase.CategoryRepository.FindBreadcrumbs(tx, ids)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find breadcrumbs of categories")
+		return errors.New("something went wrong")
+	}
+
+	for i, category := range response {
+		response[i].Breadcrumbs = breadcrumbs[uint(category.Id)]
+	}
+
+	return nil
+}
+
+// applyBookRule deals with the books of the category about to be deleted: it
+// refuses while there are any, unlinks them or links them to another category
+// instead, depending on request and the configured BookRule.
+func (usecase *UsecaseImpl) applyBookRule(ctx context.Context, tx *gorm.DB, id int, request integrity.DeleteRequest) error {
+	integrity.NewDeleteRequest(&request, usecase.BookRule)
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation == nil && request.Books == integrity.RuleReassign {
+		_, err := usecase.CategoryRepository.FindByID(tx, int(request.ReassignTo), projection.Request{})
+		if err != nil || int(request.ReassignTo) == id {
+			validation = append(validation, ivalidator.ValidationError{
+				Field:   "reassign_to",
+				Message: "reassign_to must be another existing category",
+			})
+		}
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	var books []uint
+	var err error
+	action := audit.ActionUpdate
+	before := map[string]any{"category_id": id}
+	var after map[string]any
+
+	switch request.Books {
+	case integrity.RuleRestrict:
+		count, err := usecase.CategoryRepository.CountBooks(tx, id)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to count books of category %d", id)
+			return errors.New("something went wrong")
+		}
+		if count > 0 {
+			return integrity.RestrictedError{Relation: "books", Count: count}
+		}
+		return nil
+	case integrity.RuleCascade:
+		books, err = usecase.CategoryRepository.UnlinkBooks(tx, id)
+	case integrity.RuleReassign:
+		books, err = usecase.CategoryRepository.ReassignBooks(tx, id, request.ReassignTo)
+		action = audit.ActionUpdate
+		after = map[string]any{"category_id": request.ReassignTo}
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to %s books of category %d", request.Books, id)
+		return errors.New("something went wrong")
+	}
+
+	for _, book := range books {
+		err = usecase.Audit.Record(ctx, tx, audit.Change{
+			Action:     action,
+			EntityType: "book",
+			EntityID:   book,
+			Before:     before,
+			After:      after,
+		})
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to audit book %d of category %d", book, id)
+			return errors.New("something went wrong")
+		}
+	}
+
+	return nil
 }
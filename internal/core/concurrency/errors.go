@@ -0,0 +1,7 @@
+package concurrency
+
+import "errors"
+
+// ErrVersionMismatch is returned when a write targets a version of a record
+// that is no longer the current one, i.e. somebody else changed it first.
+var ErrVersionMismatch = errors.New("version mismatch")
@@ -8,11 +8,25 @@ type Default struct {
 	EndDate   string `json:"end_date" validate:"omitempty,publication_date"`
 }
 
+const (
+	MatchAll = "all"
+	MatchAny = "any"
+)
+
+// BookFilter narrows books down. Categories match their descendants as well
+// unless Descendants is off. Tags and Subjects are slugs; TagMatch decides
+// whether a book needs all of them or any, within each of the two lists.
+// Language is a BCP 47 tag.
 type BookFilter struct {
 	Default
-	Categories []uint `json:"categories"`
-	From       string `json:"from" validate:"omitempty,publication_date"`
-	To         string `json:"to" validate:"omitempty,publication_date"`
+	Categories  []uint   `json:"categories"`
+	Descendants bool     `json:"descendants"`
+	Tags        []string `json:"tags" validate:"max=20,dive,max=200"`
+	Subjects    []string `json:"subjects" validate:"max=20,dive,max=200"`
+	TagMatch    string   `json:"tag_match" validate:"omitempty,oneof=all any"`
+	Language    string   `json:"language" validate:"omitempty,bcp47"`
+	From        string   `json:"from" validate:"omitempty,publication_date"`
+	To          string   `json:"to" validate:"omitempty,publication_date"`
 }
 
 func NewDefaultFilter(filter *Default) {
@@ -41,4 +55,40 @@ func NewBookFilter(filter *BookFilter) {
 	if filter.To == "" {
 		filter.To = defaultTo
 	}
+
+	if filter.TagMatch == "" {
+		filter.TagMatch = MatchAny
+	}
+}
+
+// AuditFilter narrows the audit log down. StartDate and EndDate bound the day
+// an entry was written, both inclusive.
+type AuditFilter struct {
+	ActorID    uint   `json:"actor_id"`
+	Action     string `json:"action" validate:"omitempty,oneof=create update delete restore purge merge"`
+	EntityType string `json:"entity_type" validate:"max=32"`
+	EntityID   uint   `json:"entity_id"`
+	RequestID  string `json:"request_id" validate:"max=128"`
+	StartDate  string `json:"start_date" validate:"omitempty,publication_date"`
+	EndDate    string `json:"end_date" validate:"omitempty,publication_date"`
+}
+
+// ReviewFilter narrows the reviews of a book down. Only moderators get to see
+// reviews that are not approved.
+type ReviewFilter struct {
+	Status string `json:"status" validate:"omitempty,oneof=pending approved rejected"`
+	Rating int    `json:"rating" validate:"omitempty,min=1,max=5"`
+}
+
+// ShelfFilter narrows shelves down to one owner and visibility. Search
+// matches the shelf name.
+type ShelfFilter struct {
+	UserID     uint   `json:"user_id"`
+	Visibility string `json:"visibility" validate:"omitempty,oneof=private link public"`
+	Search     string `json:"search" validate:"max=100"`
+}
+
+// ReadingFilter keeps the books still being read or the finished ones.
+type ReadingFilter struct {
+	Status string `json:"status" validate:"omitempty,oneof=reading finished"`
 }
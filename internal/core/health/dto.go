@@ -0,0 +1,16 @@
+package health
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+type CheckResponse struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration,omitempty"`
+}
+
+type Response struct {
+	Status string                   `json:"status"`
+	Checks map[string]CheckResponse `json:"checks,omitempty"`
+}
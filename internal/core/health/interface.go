@@ -0,0 +1,23 @@
+package health
+
+import "context"
+
+// Checker reports whether a dependency can serve requests.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a plain function to Checker.
+type CheckerFunc func(ctx context.Context) error
+
+func (check CheckerFunc) Check(ctx context.Context) error {
+	return check(ctx)
+}
+
+type Usecase interface {
+	Liveness(ctx context.Context) Response
+	Readiness(ctx context.Context) (Response, bool)
+	// Drain makes readiness fail from now on, so load balancers stop sending
+	// traffic while the server shuts down.
+	Drain()
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type UsecaseDependency struct {
+	Checks  map[string]Checker
+	Timeout time.Duration
+}
+
+type UsecaseImpl struct {
+	UsecaseDependency
+	draining atomic.Bool
+}
+
+func NewUsecase(deps UsecaseDependency) Usecase {
+	return &UsecaseImpl{
+		UsecaseDependency: deps,
+	}
+}
+
+func (usecase *UsecaseImpl) Liveness(ctx context.Context) Response {
+	return Response{
+		Status: StatusUp,
+	}
+}
+
+// Readiness runs every check concurrently, each bounded by Timeout. Only the
+// status of a failed check is returned, probes are unauthenticated and the
+// error is logged instead.
+func (usecase *UsecaseImpl) Readiness(ctx context.Context) (Response, bool) {
+	response := Response{
+		Status: StatusUp,
+		Checks: make(map[string]CheckResponse, len(usecase.Checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, checker := range usecase.Checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, usecase.Timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := checker.Check(checkCtx)
+			check := CheckResponse{
+				Status:   StatusUp,
+				Duration: time.Since(start).String(),
+			}
+			if err != nil {
+				log.Ctx(ctx).Error().Err(err).Msgf("readiness check %s failed", name)
+				check.Status = StatusDown
+			}
+
+			mu.Lock()
+			response.Checks[name] = check
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	ready := !usecase.draining.Load()
+	if !ready {
+		response.Checks["server"] = CheckResponse{
+			Status: StatusDown,
+		}
+	}
+	for _, check := range response.Checks {
+		if check.Status != StatusUp {
+			ready = false
+		}
+	}
+	if !ready {
+		response.Status = StatusDown
+	}
+
+	return response, ready
+}
+
+func (usecase *UsecaseImpl) Drain() {
+	usecase.draining.Store(true)
+}
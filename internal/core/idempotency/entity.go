@@ -0,0 +1,21 @@
+package idempotency
+
+import "time"
+
+// Record is what is kept for an Idempotency-Key. Key is a digest of the client
+// key and the route it was sent to, Fingerprint a digest of the request body.
+// A record without Completed is a request that is still being processed.
+type Record struct {
+	Key         string `gorm:"primaryKey;size:64"`
+	Fingerprint string `gorm:"size:64;not null"`
+	Completed   bool   `gorm:"not null;default:false"`
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time `gorm:"index;not null"`
+	CreatedAt   time.Time
+}
+
+func (Record) TableName() string {
+	return "idempotency_records"
+}
@@ -0,0 +1,13 @@
+package idempotency
+
+import "context"
+
+type Store interface {
+	// Reserve claims record.Key for a new request. When an unexpired record
+	// already holds the key it is returned instead and nothing is stored.
+	Reserve(ctx context.Context, record Record) (*Record, error)
+	// Complete stores the final response of a reserved key.
+	Complete(ctx context.Context, record Record) error
+	// Release drops a reservation so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,24 @@
+package integrity
+
+const (
+	RuleRestrict = "restrict"
+	RuleCascade  = "cascade"
+	RuleReassign = "reassign"
+)
+
+// DeleteRequest says what happens to the books of an author, publisher or
+// category being deleted. Restrict refuses while active books reference it,
+// cascade deletes the books (or, for categories, only the links to them) and
+// reassign points them at ReassignTo instead.
+type DeleteRequest struct {
+	Books      string `json:"books" validate:"oneof=restrict cascade reassign"`
+	ReassignTo uint   `json:"reassign_to" validate:"required_if=Books reassign"`
+}
+
+// NewDeleteRequest falls back to the configured rule of the relation when the
+// client didn't pick one.
+func NewDeleteRequest(request *DeleteRequest, rule string) {
+	if request.Books == "" {
+		request.Books = rule
+	}
+}
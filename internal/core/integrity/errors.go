@@ -0,0 +1,14 @@
+package integrity
+
+import "fmt"
+
+// RestrictedError refuses a delete while active rows of Relation still
+// reference the entity.
+type RestrictedError struct {
+	Relation string
+	Count    int64
+}
+
+func (err RestrictedError) Error() string {
+	return fmt.Sprintf("still referenced by %d %s", err.Count, err.Relation)
+}
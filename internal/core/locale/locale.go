@@ -0,0 +1,47 @@
+package locale
+
+import (
+	"context"
+	"golang.org/x/text/language"
+)
+
+type preferredKey struct{}
+
+// WithPreferred puts the languages the client asked for, the most preferred
+// first, in ctx. The HTTP layer takes them from Accept-Language.
+func WithPreferred(ctx context.Context, tags []language.Tag) context.Context {
+	return context.WithValue(ctx, preferredKey{}, tags)
+}
+
+func PreferredFrom(ctx context.Context) []language.Tag {
+	tags, _ := ctx.Value(preferredKey{}).([]language.Tag)
+	return tags
+}
+
+// Canonical spells a BCP 47 tag the way it is stored, "EN-gb" becomes
+// "en-GB". Tags that don't parse are returned as they are.
+func Canonical(tag string) string {
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return tag
+	}
+	return parsed.String()
+}
+
+// Match picks which of the candidates, BCP 47 tags, suits the preferred
+// languages best. It returns -1 when none of them does.
+func Match(preferred []language.Tag, candidates []string) int {
+	if len(preferred) == 0 || len(candidates) == 0 {
+		return -1
+	}
+
+	tags := make([]language.Tag, 0, len(candidates))
+	for _, candidate := range candidates {
+		tags = append(tags, language.Make(candidate))
+	}
+	_, index, confidence := language.NewMatcher(tags).Match(preferred...)
+	if confidence == language.No {
+		return -1
+	}
+	return index
+}
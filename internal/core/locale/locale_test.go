@@ -0,0 +1,50 @@
+package locale
+
+import (
+	"golang.org/x/text/language"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"en", "en"},
+		{"EN-gb", "en-GB"},
+		{"pt-br", "pt-BR"},
+		{"zh-hant-tw", "zh-Hant-TW"},
+		{"not a tag", "not a tag"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := Canonical(test.tag); got != test.want {
+			t.Errorf("Canonical(%q) = %q, want %q", test.tag, got, test.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		preferred  []language.Tag
+		candidates []string
+		want       int
+	}{
+		{"exact", []language.Tag{language.German}, []string{"en", "de"}, 1},
+		{"first preference wins", []language.Tag{language.French, language.German}, []string{"de", "fr"}, 1},
+		{"region falls back to the language", []language.Tag{language.MustParse("de-AT")}, []string{"en", "de"}, 1},
+		{"nothing suits", []language.Tag{language.Japanese}, []string{"en", "de"}, -1},
+		{"no preference", nil, []string{"en"}, -1},
+		{"no candidates", []language.Tag{language.English}, nil, -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Match(test.preferred, test.candidates); got != test.want {
+				t.Errorf("Match() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
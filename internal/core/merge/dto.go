@@ -0,0 +1,62 @@
+package merge
+
+import "fmt"
+
+// Request merges Sources into TargetID: their books move to the target, the
+// sources are deleted and redirect to it from then on.
+type Request struct {
+	TargetID int    `json:"-"`
+	Sources  []uint `json:"sources" validate:"required,min=1,max=50,dive,required"`
+}
+
+type DuplicatesRequest struct {
+	Threshold float64 `json:"threshold" validate:"gt=0,lte=1"`
+	Limit     int     `json:"limit" validate:"gte=1,lte=100"`
+}
+
+func NewDuplicatesRequest(request *DuplicatesRequest) {
+	if request.Threshold == 0 {
+		request.Threshold = 0.6
+	}
+	if request.Limit == 0 {
+		request.Limit = 20
+	}
+}
+
+// Candidate is a pair of records whose names look alike, First always has the
+// lower id.
+type Candidate struct {
+	FirstID    uint
+	FirstName  string
+	SecondID   uint
+	SecondName string
+	Similarity float64
+}
+
+type RecordResponse struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type CandidateResponse struct {
+	First      RecordResponse `json:"first"`
+	Second     RecordResponse `json:"second"`
+	Similarity float64        `json:"similarity"`
+}
+
+func ToCandidateResponse(candidate *Candidate) *CandidateResponse {
+	return &CandidateResponse{
+		First:      RecordResponse{Id: int(candidate.FirstID), Name: candidate.FirstName},
+		Second:     RecordResponse{Id: int(candidate.SecondID), Name: candidate.SecondName},
+		Similarity: candidate.Similarity,
+	}
+}
+
+// MovedError is returned when a record was merged away, ID is the survivor.
+type MovedError struct {
+	ID uint
+}
+
+func (err MovedError) Error() string {
+	return fmt.Sprintf("merged into %d", err.ID)
+}
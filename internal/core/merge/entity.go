@@ -0,0 +1,17 @@
+package merge
+
+import "time"
+
+// Redirect remembers that SourceID of EntityType was merged into TargetID, so
+// that links to the old record keep working.
+type Redirect struct {
+	ID         uint   `gorm:"primaryKey"`
+	EntityType string `gorm:"size:32;not null;uniqueIndex:idx_redirects_entity_source,priority:1"`
+	SourceID   uint   `gorm:"not null;uniqueIndex:idx_redirects_entity_source,priority:2"`
+	TargetID   uint   `gorm:"not null;index"`
+	CreatedAt  time.Time
+}
+
+func (Redirect) TableName() string {
+	return "redirects"
+}
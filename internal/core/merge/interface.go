@@ -0,0 +1,8 @@
+package merge
+
+import "gorm.io/gorm"
+
+type RedirectRepository interface {
+	Save(db *gorm.DB, redirect *Redirect) error
+	FindBySource(db *gorm.DB, entityType string, id int) (Redirect, error)
+}
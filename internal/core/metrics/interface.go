@@ -0,0 +1,7 @@
+package metrics
+
+// Recorder counts business events the usecases care about.
+type Recorder interface {
+	BookCreated()
+	Login(succeeded bool)
+}
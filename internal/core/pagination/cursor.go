@@ -0,0 +1,65 @@
+package pagination
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	ivalidator "starter/internal/core/validator"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorError is how usecases report ErrInvalidCursor: a cursor that decodes
+// but was issued for another ordering or was tampered with.
+func CursorError() ivalidator.ValidationErrors {
+	return ivalidator.ValidationErrors{
+		Errors: []ivalidator.ValidationError{{
+			Field:   "cursor",
+			Message: "cursor must be one returned by a previous page with the same sort",
+		}},
+	}
+}
+
+// Cursor points at a single row by its sort values and ID. Key records which
+// ordering the cursor was issued for so it can't be replayed against another.
+type Cursor struct {
+	Key    string `json:"k"`
+	Values []any  `json:"v"`
+	ID     uint   `json:"id"`
+}
+
+func EncodeCursor(cursor Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(token string) (Cursor, error) {
+	var cursor Cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err = decoder.Decode(&cursor); err != nil || cursor.ID == 0 {
+		return cursor, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// CursorKey identifies the ordering a cursor belongs to.
+func (request Request) CursorKey() string {
+	fields := make([]string, 0, len(request.Sorts))
+	for _, sort := range request.Sorts {
+		if sort.Descending {
+			fields = append(fields, "-"+sort.Field)
+			continue
+		}
+		fields = append(fields, sort.Field)
+	}
+	return strings.Join(fields, ",")
+}
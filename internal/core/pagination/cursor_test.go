@@ -0,0 +1,74 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := Cursor{
+		Key:    "-title,id",
+		Values: []any{"Refactoring", json.Number("448"), nil},
+		ID:     4,
+	}
+
+	decoded, err := DecodeCursor(EncodeCursor(cursor))
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, cursor) {
+		t.Errorf("DecodeCursor() = %#v, want %#v", decoded, cursor)
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "%%%"},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("title"))},
+		{"without id", base64.RawURLEncoding.EncodeToString([]byte(`{"k":"title","v":["a"]}`))},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"k":"","v":[],"id":1}`))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := DecodeCursor(test.token); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor(%q) error = %v, want %v", test.token, err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestParseSortAndCursorKey(t *testing.T) {
+	sortable := Sortable{"title": "books.title", "id": "books.id"}
+
+	tests := []struct {
+		sort       string
+		wantKey    string
+		wantErrors int
+	}{
+		{sort: "title", wantKey: "title"},
+		{sort: "-title, +id", wantKey: "-title,id"},
+		{sort: "TITLE", wantKey: "title"},
+		{sort: "", wantKey: ""},
+		{sort: "price", wantKey: "", wantErrors: 1},
+		{sort: "title,-title", wantKey: "title", wantErrors: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.sort, func(t *testing.T) {
+			request := Request{Sort: test.sort}
+			if errs := request.ParseSort(sortable); len(errs) != test.wantErrors {
+				t.Errorf("ParseSort(%q) = %v, want %d errors", test.sort, errs, test.wantErrors)
+			}
+			if key := request.CursorKey(); key != test.wantKey {
+				t.Errorf("CursorKey() = %q, want %q", key, test.wantKey)
+			}
+		})
+	}
+}
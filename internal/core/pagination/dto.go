@@ -1,22 +1,44 @@
 package pagination
 
-import "math"
+import (
+	"math"
+	"strings"
+)
 
 type Page[T any] struct {
-	Items       []T `json:"items"`
-	CurrentPage int `json:"current_page"`
-	PerPage     int `json:"per_page"`
-	TotalPages  int `json:"total_pages"`
-	TotalItems  int `json:"total_items"`
-	NextPage    int `json:"next_page"`
-	PrevPage    int `json:"prev_page"`
+	Items       []T    `json:"items"`
+	CurrentPage int    `json:"current_page,omitempty"`
+	PerPage     int    `json:"per_page"`
+	TotalPages  *int   `json:"total_pages,omitempty"`
+	TotalItems  *int   `json:"total_items,omitempty"`
+	NextPage    int    `json:"next_page,omitempty"`
+	PrevPage    int    `json:"prev_page,omitempty"`
+	NextCursor  string `json:"next_cursor,omitempty"`
+	PrevCursor  string `json:"prev_cursor,omitempty"`
 }
 
 type Request struct {
-	SortBy  string `json:"sort_by"`
-	OrderBy string `json:"order_by"`
-	Page    int    `json:"page" validation:"max=1000"`
-	Limit   int    `json:"limit" validation:"max=100"`
+	// Sort is a comma separated list of fields, a leading "-" sorts that
+	// field descending. SortBy and OrderBy are the older single field form
+	// and only apply when Sort is empty.
+	Sort      string `json:"sort" validate:"max=200"`
+	SortBy    string `json:"sort_by"`
+	OrderBy   string `json:"order_by"`
+	Page      int    `json:"page" validation:"max=1000"`
+	Limit     int    `json:"limit" validation:"max=100"`
+	After     string `json:"after" validate:"omitempty,cursor,excluded_with=Before"`
+	Before    string `json:"before" validate:"omitempty,cursor"`
+	WithTotal bool   `json:"with_total"`
+	Sorts     []Sort `json:"-"`
+}
+
+// Meta is what a repository knows about the page it just loaded. Total is
+// only set when the request asked for it.
+type Meta struct {
+	Total      *int64
+	HasMore    bool
+	NextCursor string
+	PrevCursor string
 }
 
 func NewPagination(request *Request) {
@@ -32,9 +54,24 @@ func NewPagination(request *Request) {
 	if request.SortBy == "" {
 		request.SortBy = "ASC"
 	}
+	if request.Sort == "" {
+		request.Sort = request.OrderBy
+		if strings.EqualFold(request.SortBy, "DESC") {
+			request.Sort = "-" + request.OrderBy
+		}
+	}
+}
+
+// IsCursor reports whether the request pages by keyset instead of offset.
+func (request Request) IsCursor() bool {
+	return request.After != "" || request.Before != ""
+}
+
+func (request Request) Offset() int {
+	return (request.Page - 1) * request.Limit
 }
 
-func NewPage[T any](req Request, totalItems int64, items []T) *Page[T] {
+func NewPage[T any](req Request, meta Meta, items []T) *Page[T] {
 	if req.Page < 1 {
 		req.Page = 1
 	}
@@ -42,25 +79,31 @@ func NewPage[T any](req Request, totalItems int64, items []T) *Page[T] {
 		req.Limit = 10
 	}
 
-	totalPages := int(math.Ceil(float64(totalItems) / float64(req.Limit)))
+	page := &Page[T]{
+		Items:      items,
+		PerPage:    req.Limit,
+		NextCursor: meta.NextCursor,
+		PrevCursor: meta.PrevCursor,
+	}
 
-	nextPage := 0
-	if req.Page < totalPages {
-		nextPage = req.Page + 1
+	if meta.Total != nil {
+		totalItems := int(*meta.Total)
+		totalPages := int(math.Ceil(float64(totalItems) / float64(req.Limit)))
+		page.TotalItems = &totalItems
+		page.TotalPages = &totalPages
 	}
 
-	prevPage := 0
-	if req.Page > 1 {
-		prevPage = req.Page - 1
+	if req.IsCursor() {
+		return page
 	}
 
-	return &Page[T]{
-		Items:       items,
-		CurrentPage: req.Page,
-		PerPage:     req.Limit,
-		TotalPages:  totalPages,
-		TotalItems:  int(totalItems),
-		NextPage:    nextPage,
-		PrevPage:    prevPage,
+	page.CurrentPage = req.Page
+	if meta.HasMore {
+		page.NextPage = req.Page + 1
 	}
+	if req.Page > 1 {
+		page.PrevPage = req.Page - 1
+	}
+
+	return page
 }
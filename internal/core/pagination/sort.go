@@ -0,0 +1,60 @@
+package pagination
+
+import (
+	"fmt"
+	ivalidator "starter/internal/core/validator"
+	"strings"
+)
+
+// Sortable maps the field names clients may sort by to the column that backs
+// them. Anything not listed here is rejected before it reaches SQL.
+type Sortable map[string]string
+
+type Sort struct {
+	Field      string
+	Column     string
+	Descending bool
+}
+
+// ParseSort resolves request.Sort against sortable and stores the result in
+// request.Sorts. Unknown or repeated fields are reported as validation errors.
+func (request *Request) ParseSort(sortable Sortable) []ivalidator.ValidationError {
+	var errors []ivalidator.ValidationError
+
+	request.Sorts = nil
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(request.Sort, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		descending := strings.HasPrefix(part, "-")
+		field := strings.ToLower(strings.TrimLeft(part, "+-"))
+
+		column, ok := sortable[field]
+		if !ok {
+			errors = append(errors, ivalidator.ValidationError{
+				Field:   "sort",
+				Message: fmt.Sprintf("sort cannot use unknown field %s", field),
+			})
+			continue
+		}
+		if seen[field] {
+			errors = append(errors, ivalidator.ValidationError{
+				Field:   "sort",
+				Message: fmt.Sprintf("sort lists %s more than once", field),
+			})
+			continue
+		}
+		seen[field] = true
+
+		request.Sorts = append(request.Sorts, Sort{
+			Field:      field,
+			Column:     column,
+			Descending: descending,
+		})
+	}
+
+	return errors
+}
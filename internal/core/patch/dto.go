@@ -0,0 +1,107 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	ivalidator "starter/internal/core/validator"
+)
+
+// ErrInvalidPatch is returned when the body is not a JSON object or doesn't
+// fit the document it is applied to.
+var ErrInvalidPatch = errors.New("invalid merge patch")
+
+// Request is an RFC 7396 JSON merge patch against the record with Id. Version
+// is the If-Match precondition, 0 when the client didn't send one.
+type Request struct {
+	Id      int
+	Version uint
+	Patch   json.RawMessage
+}
+
+// Fields maps the keys a merge patch may touch to the columns (or association
+// names) written when they are sent.
+type Fields map[string]string
+
+// Apply merges patch into document, a pointer to the struct describing the
+// record, and returns the top level keys the patch sent. Keys set to null are
+// removed from the document, which leaves their fields at the zero value.
+func Apply(document any, patch []byte) ([]string, error) {
+	var changes map[string]any
+	if err := json.Unmarshal(patch, &changes); err != nil || changes == nil {
+		return nil, fmt.Errorf("%w: body must be a JSON object", ErrInvalidPatch)
+	}
+
+	raw, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var target map[string]any
+	if err = json.Unmarshal(raw, &target); err != nil {
+		return nil, err
+	}
+
+	raw, err = json.Marshal(merge(target, changes))
+	if err != nil {
+		return nil, err
+	}
+
+	value := reflect.ValueOf(document).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	if err = json.Unmarshal(raw, document); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+	}
+
+	keys := make([]string, 0, len(changes))
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// Columns resolves the keys a patch sent into the columns to write, reporting
+// keys that can't be patched.
+func (fields Fields) Columns(keys []string) ([]string, []ivalidator.ValidationError) {
+	var columns []string
+	var errors []ivalidator.ValidationError
+
+	for _, key := range keys {
+		column, ok := fields[key]
+		if !ok {
+			errors = append(errors, ivalidator.ValidationError{
+				Field:   key,
+				Message: fmt.Sprintf("%s cannot be patched", key),
+			})
+			continue
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, errors
+}
+
+// merge is the MergePatch function from RFC 7396.
+func merge(target any, patch any) any {
+	changes, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	object, ok := target.(map[string]any)
+	if !ok {
+		object = map[string]any{}
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(object, key)
+			continue
+		}
+		object[key] = merge(object[key], value)
+	}
+
+	return object
+}
@@ -0,0 +1,96 @@
+package patch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type document struct {
+	Title    string            `json:"title"`
+	Pages    int               `json:"pages"`
+	Tags     []string          `json:"tags"`
+	Original *uint             `json:"original"`
+	Names    map[string]string `json:"names"`
+}
+
+func TestApply(t *testing.T) {
+	original := uint(3)
+
+	tests := []struct {
+		name     string
+		patch    string
+		want     document
+		wantKeys []string
+	}{
+		{
+			name:     "changes only the sent keys",
+			patch:    `{"title": "Refactoring"}`,
+			want:     document{Title: "Refactoring", Pages: 448, Tags: []string{"go"}, Original: &original, Names: map[string]string{"en": "Clean Code", "de": "Sauberer Code"}},
+			wantKeys: []string{"title"},
+		},
+		{
+			name:     "null clears a key",
+			patch:    `{"original": null, "pages": null}`,
+			want:     document{Title: "Clean Code", Tags: []string{"go"}, Names: map[string]string{"en": "Clean Code", "de": "Sauberer Code"}},
+			wantKeys: []string{"original", "pages"},
+		},
+		{
+			name:     "arrays are replaced",
+			patch:    `{"tags": ["craft", "design"]}`,
+			want:     document{Title: "Clean Code", Pages: 448, Tags: []string{"craft", "design"}, Original: &original, Names: map[string]string{"en": "Clean Code", "de": "Sauberer Code"}},
+			wantKeys: []string{"tags"},
+		},
+		{
+			name:     "objects are merged key by key",
+			patch:    `{"names": {"de": null, "fr": "Coder proprement"}}`,
+			want:     document{Title: "Clean Code", Pages: 448, Tags: []string{"go"}, Original: &original, Names: map[string]string{"en": "Clean Code", "fr": "Coder proprement"}},
+			wantKeys: []string{"names"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id := uint(3)
+			target := document{
+				Title:    "Clean Code",
+				Pages:    448,
+				Tags:     []string{"go"},
+				Original: &id,
+				Names:    map[string]string{"en": "Clean Code", "de": "Sauberer Code"},
+			}
+
+			keys, err := Apply(&target, []byte(test.patch))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !reflect.DeepEqual(target, test.want) {
+				t.Errorf("Apply() document = %+v, want %+v", target, test.want)
+			}
+			if !reflect.DeepEqual(keys, test.wantKeys) {
+				t.Errorf("Apply() keys = %v, want %v", keys, test.wantKeys)
+			}
+		})
+	}
+}
+
+func TestApplyInvalid(t *testing.T) {
+	for _, patch := range []string{`null`, `[]`, `"title"`, `{"pages": "many"}`, `{`} {
+		var target document
+		if _, err := Apply(&target, []byte(patch)); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("Apply(%s) error = %v, want %v", patch, err, ErrInvalidPatch)
+		}
+	}
+}
+
+func TestFieldsColumns(t *testing.T) {
+	fields := Fields{"title": "title", "categories": "Categories"}
+
+	columns, errs := fields.Columns([]string{"categories", "id", "title"})
+	if !reflect.DeepEqual(columns, []string{"Categories", "title"}) {
+		t.Errorf("Columns() = %v, want [Categories title]", columns)
+	}
+	if len(errs) != 1 || errs[0].Field != "id" {
+		t.Errorf("Columns() errors = %v, want one for id", errs)
+	}
+}
@@ -0,0 +1,129 @@
+package projection
+
+import (
+	"fmt"
+	ivalidator "starter/internal/core/validator"
+	"strings"
+)
+
+// Request is the sparse fieldset (fields) and relationship includes (include)
+// asked for on a read endpoint. Parse resolves it into the columns to select,
+// the associations to preload and the response keys to keep.
+type Request struct {
+	Fields  string `json:"fields" validate:"max=500"`
+	Include string `json:"include" validate:"max=200"`
+	// Included tells an empty include, which asks for no relations at all,
+	// apart from a missing one, which gets the defaults.
+	Included bool `json:"-"`
+
+	Columns  []string `json:"-"`
+	Preloads []string `json:"-"`
+	Keys     []string `json:"-"`
+}
+
+// Relation is an association that can be requested through include. Columns
+// are the foreign keys that must be selected for the preload to work.
+type Relation struct {
+	Preload string
+	Columns []string
+}
+
+// Projectable describes what an entity exposes to fields and include. Fields
+// maps response keys to the column backing them, Defaults are the relations
+// loaded when the client doesn't send include at all while an empty include
+// loads none. ID and Version are always selected since responses and ETags
+// can't do without them.
+type Projectable struct {
+	ID        string
+	Version   string
+	Fields    map[string]string
+	Relations map[string]Relation
+	Defaults  []string
+}
+
+func (request *Request) Parse(projectable Projectable) []ivalidator.ValidationError {
+	var errors []ivalidator.ValidationError
+
+	request.Columns = []string{projectable.ID}
+	request.Preloads = nil
+	request.Keys = []string{"id"}
+	if projectable.Version != "" {
+		request.Columns = append(request.Columns, projectable.Version)
+		request.Keys = append(request.Keys, "version")
+	}
+
+	fields := split(request.Fields)
+	if len(fields) == 0 {
+		for field := range projectable.Fields {
+			fields = append(fields, field)
+		}
+	}
+	for _, field := range fields {
+		column, ok := projectable.Fields[field]
+		if !ok {
+			errors = append(errors, ivalidator.ValidationError{
+				Field:   "fields",
+				Message: fmt.Sprintf("fields cannot use unknown field %s", field),
+			})
+			continue
+		}
+		request.Columns = appendUnique(request.Columns, column)
+		request.Keys = appendUnique(request.Keys, field)
+	}
+
+	includes := split(request.Include)
+	if !request.Included {
+		includes = projectable.Defaults
+	}
+	for _, include := range includes {
+		relation, ok := projectable.Relations[include]
+		if !ok {
+			errors = append(errors, ivalidator.ValidationError{
+				Field:   "include",
+				Message: fmt.Sprintf("include cannot use unknown relation %s", include),
+			})
+			continue
+		}
+		for _, column := range relation.Columns {
+			request.Columns = appendUnique(request.Columns, column)
+		}
+		request.Preloads = appendUnique(request.Preloads, relation.Preload)
+		request.Keys = appendUnique(request.Keys, include)
+	}
+
+	return errors
+}
+
+// Has reports whether key ends up in the response. An unparsed request keeps
+// everything.
+func (request Request) Has(key string) bool {
+	if request.Keys == nil {
+		return true
+	}
+	for _, k := range request.Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func split(value string) []string {
+	var result []string
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part != "" {
+			result = appendUnique(result, part)
+		}
+	}
+	return result
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -2,26 +2,58 @@ package publisher
 
 import (
 	"gorm.io/gorm"
-	"strings"
+	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
 )
 
+// SortableFields lists what FindAll accepts in the sort parameter.
+var SortableFields = pagination.Sortable{
+	"id":         "publishers.id",
+	"name":       "publishers.name",
+	"created_at": "publishers.created_at",
+	"updated_at": "publishers.updated_at",
+}
+
+// ProjectableFields lists what read endpoints accept in fields and include.
+var ProjectableFields = projection.Projectable{
+	ID:      "publishers.id",
+	Version: "publishers.version",
+	Fields: map[string]string{
+		"name": "publishers.name",
+	},
+}
+
+// PatchableFields lists the keys PATCH accepts and the columns they write.
+var PatchableFields = patch.Fields{
+	"name": "name",
+}
+
 type CreateRequest struct {
 	Name string `json:"name" validate:"required,max=100"`
 }
 
 type UpdateRequest struct {
-	Id   int    `json:"id" validate:"required"`
-	Name string `json:"name" validate:"max=100"`
+	Id      int    `json:"id" validate:"required"`
+	Name    string `json:"name" validate:"max=100"`
+	Version uint   `json:"-"`
+}
+
+// PatchDocument is the publisher a merge patch is applied to. Its rules are the
+// ones a stored publisher has to satisfy, so anything optional can be cleared.
+type PatchDocument struct {
+	Name string `json:"name" validate:"required,max=100"`
 }
 
 type Response struct {
-	Id   int    `json:"id"`
-	Name string `json:"name"`
+	Id      int    `json:"id"`
+	Name    string `json:"name"`
+	Version uint   `json:"version"`
 }
 
 func (dto *CreateRequest) ToEntity() *Publisher {
 	return &Publisher{
-		Name: strings.ToUpper(dto.Name),
+		Name: dto.Name,
 	}
 }
 
@@ -32,9 +64,20 @@ func (dto *UpdateRequest) ToEntity() *Publisher {
 	}
 }
 
+func ToPatchDocument(entity *Publisher) *PatchDocument {
+	return &PatchDocument{
+		Name: entity.Name,
+	}
+}
+
+func (dto *PatchDocument) Apply(entity *Publisher) {
+	entity.Name = dto.Name
+}
+
 func ToResponse(entity *Publisher) *Response {
 	return &Response{
-		Id:   int(entity.ID),
-		Name: entity.Name,
+		Id:      int(entity.ID),
+		Name:    entity.Name,
+		Version: entity.Version,
 	}
 }
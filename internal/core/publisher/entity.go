@@ -1,8 +1,21 @@
 package publisher
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"starter/pkg/helper"
+)
 
 type Publisher struct {
 	Name string
+	// NameKey is the folded name. New names are checked against the live
+	// publishers, the unique index only exists once older duplicates are
+	// merged.
+	NameKey string `gorm:"not null;default:'';index"`
+	Version uint   `gorm:"not null;default:1"`
 	gorm.Model
 }
+
+// SetKey derives NameKey from the name, writes call it before saving.
+func (publisher *Publisher) SetKey() {
+	publisher.NameKey = helper.Fold(publisher.Name)
+}
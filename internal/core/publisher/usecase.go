@@ -3,17 +3,30 @@ package publisher
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
 	"gorm.io/gorm"
+	"starter/internal/core/audit"
+	"starter/internal/core/concurrency"
+	"starter/internal/core/integrity"
+	"starter/internal/core/merge"
 	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
 	ivalidator "starter/internal/core/validator"
 	"starter/pkg/helper"
 )
 
+var tracer = otel.Tracer("starter/internal/core/publisher")
+
 type UsecaseDependency struct {
 	DB                  *gorm.DB
 	Validator           ivalidator.Validator
+	Audit               audit.Recorder
 	PublisherRepository Repository
+	RedirectRepository  merge.RedirectRepository
+	BookRule            string
 }
 
 type UsecaseImpl struct {
@@ -27,21 +40,54 @@ func NewUsecase(deps UsecaseDependency) Usecase {
 }
 
 func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Response, error) {
+	ctx, span := tracer.Start(ctx, "publisher.Usecase.Save")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	validation := usecase.Validator.ValidateStruct(request)
 	if validation != nil {
-		log.Error().Msgf("Validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
 		return Response{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
 	}
 
 	publisher := request.ToEntity()
-	err := usecase.PublisherRepository.Save(tx, publisher)
+	validation, err := usecase.validateName(ctx, tx, publisher, "")
+	if err != nil {
+		return Response{}, err
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
+		return Response{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	err = usecase.PublisherRepository.Save(tx, publisher)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return Response{}, ivalidator.ValidationErrors{Errors: nameTaken()}
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save publisher")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionCreate,
+		EntityType: "publisher",
+		EntityID:   publisher.ID,
+		After:      ToResponse(publisher),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit publisher creation")
+		return Response{}, errors.New("something went wrong")
+	}
+
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return Response{}, errors.New("something went wrong")
 	}
 
@@ -49,63 +95,221 @@ func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Re
 }
 
 func (usecase *UsecaseImpl) Update(ctx context.Context, request UpdateRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "publisher.Usecase.Update")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	validation := usecase.Validator.ValidateStruct(request)
 	if validation != nil {
-		log.Error().Msgf("Validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
 		return &Response{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
 	}
 
-	publisher, err := usecase.PublisherRepository.FindByID(tx, request.Id)
+	publisher, err := usecase.PublisherRepository.FindByID(tx, request.Id, projection.Request{})
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to find publisher by id: %+v", request.Id)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find publisher by id: %+v", request.Id)
 		return nil, errors.New("publisher not found")
 	}
 	updated := helper.Differ(publisher, *request.ToEntity()).(Publisher)
+	if request.Version != 0 {
+		updated.Version = request.Version
+	}
+
+	validation, err = usecase.validateName(ctx, tx, &updated, publisher.NameKey)
+	if err != nil {
+		return nil, err
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
 
 	err = usecase.PublisherRepository.Update(tx, &updated)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ivalidator.ValidationErrors{Errors: nameTaken()}
+	}
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to update publisher")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update publisher")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "publisher",
+		EntityID:   updated.ID,
+		Before:     ToResponse(&publisher),
+		After:      ToResponse(&updated),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit publisher update")
 		return nil, errors.New("something went wrong")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return &Response{}, errors.New("something went wrong")
 	}
 	return ToResponse(&updated), nil
 }
 
-func (usecase *UsecaseImpl) Delete(ctx context.Context, id int) error {
+func (usecase *UsecaseImpl) Patch(ctx context.Context, request patch.Request) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "publisher.Usecase.Patch")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
-	err := usecase.PublisherRepository.Delete(tx, id)
+	publisher, err := usecase.PublisherRepository.FindByID(tx, request.Id, projection.Request{})
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to update publisher")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find publisher by id: %+v", request.Id)
+		return nil, errors.New("publisher not found")
+	}
+	before := ToResponse(&publisher)
+	key := publisher.NameKey
+	if request.Version != 0 {
+		publisher.Version = request.Version
+	}
+
+	document := ToPatchDocument(&publisher)
+	keys, err := patch.Apply(document, request.Patch)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to apply patch to publisher")
+		return nil, err
+	}
+
+	columns, validation := PatchableFields.Columns(keys)
+	validation = append(validation, usecase.Validator.ValidateStruct(document)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+	document.Apply(&publisher)
+
+	validation, err = usecase.validateName(ctx, tx, &publisher, key)
+	if err != nil {
+		return nil, err
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	err = usecase.PublisherRepository.Patch(tx, &publisher, columns)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ivalidator.ValidationErrors{Errors: nameTaken()}
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to patch publisher")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "publisher",
+		EntityID:   publisher.ID,
+		Before:     before,
+		After:      ToResponse(&publisher),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit publisher patch")
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToResponse(&publisher), nil
+}
+
+func (usecase *UsecaseImpl) Delete(ctx context.Context, id int, version uint, request integrity.DeleteRequest) error {
+	ctx, span := tracer.Start(ctx, "publisher.Usecase.Delete")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	publisher, err := usecase.PublisherRepository.FindByID(tx, id, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find publisher by id: %+v", id)
+		return errors.New("publisher not found")
+	}
+
+	err = usecase.applyBookRule(ctx, tx, id, request)
+	if err != nil {
+		return err
+	}
+
+	err = usecase.PublisherRepository.Delete(tx, id, version)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update publisher")
+		return errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionDelete,
+		EntityType: "publisher",
+		EntityID:   publisher.ID,
+		Before:     ToResponse(&publisher),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit publisher deletion")
 		return errors.New("something went wrong")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return errors.New("something went wrong")
 	}
 
 	return nil
 }
 
-func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Request) (pagination.Page[Response], error) {
+func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Request, view *projection.Request) (pagination.Page[Response], error) {
+	ctx, span := tracer.Start(ctx, "publisher.Usecase.FindAll")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	pagination.NewPagination(request)
-	publishers, count, err := usecase.PublisherRepository.FindAll(tx, *request)
+
+	validation := usecase.Validator.ValidateStruct(request)
+	validation = append(validation, request.ParseSort(SortableFields)...)
+	validation = append(validation, usecase.Validator.ValidateStruct(view)...)
+	validation = append(validation, view.Parse(ProjectableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return pagination.Page[Response]{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	publishers, meta, err := usecase.PublisherRepository.FindAll(tx, *request, *view)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return pagination.Page[Response]{}, pagination.CursorError()
+	}
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to fetch publishers")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to fetch publishers")
 		return pagination.Page[Response]{}, errors.New("something went wrong")
 	}
 
@@ -115,26 +319,279 @@ func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Req
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return pagination.Page[Response]{}, errors.New("something went wrong")
 	}
 
-	return *pagination.NewPage[Response](*request, count, response), nil
+	return *pagination.NewPage[Response](*request, meta, response), nil
 }
 
-func (usecase *UsecaseImpl) FindById(ctx context.Context, id int) (*Response, error) {
+func (usecase *UsecaseImpl) FindById(ctx context.Context, id int, view *projection.Request) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "publisher.Usecase.FindById")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
-	publisher, err := usecase.PublisherRepository.FindByID(tx, id)
+	validation := usecase.Validator.ValidateStruct(view)
+	validation = append(validation, view.Parse(ProjectableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	publisher, err := usecase.PublisherRepository.FindByID(tx, id, *view)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		redirect, err := usecase.RedirectRepository.FindBySource(tx, "publisher", id)
+		if err == nil {
+			return nil, merge.MovedError{ID: redirect.TargetID}
+		}
+	}
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to find publisher with id: %d", id)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find publisher with id: %d", id)
 		return nil, errors.New("publisher not found")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return nil, errors.New("something went wrong")
 	}
 	return ToResponse(&publisher), nil
 }
+
+// applyBookRule deals with the books of the publisher about to be deleted: it
+// refuses while there are any, deletes them too or points them at another
+// publisher, depending on request and the configured BookRule.
+func (usecase *UsecaseImpl) applyBookRule(ctx context.Context, tx *gorm.DB, id int, request integrity.DeleteRequest) error {
+	integrity.NewDeleteRequest(&request, usecase.BookRule)
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation == nil && request.Books == integrity.RuleReassign {
+		_, err := usecase.PublisherRepository.FindByID(tx, int(request.ReassignTo), projection.Request{})
+		if err != nil || int(request.ReassignTo) == id {
+			validation = append(validation, ivalidator.ValidationError{
+				Field:   "reassign_to",
+				Message: "reassign_to must be another existing publisher",
+			})
+		}
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	var books []uint
+	var err error
+	action := audit.ActionDelete
+	before := map[string]any{"publisher_id": id}
+	var after map[string]any
+
+	switch request.Books {
+	case integrity.RuleRestrict:
+		count, err := usecase.PublisherRepository.CountBooks(tx, id)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to count books of publisher %d", id)
+			return errors.New("something went wrong")
+		}
+		if count > 0 {
+			return integrity.RestrictedError{Relation: "books", Count: count}
+		}
+		return nil
+	case integrity.RuleCascade:
+		books, err = usecase.PublisherRepository.DeleteBooks(tx, id)
+	case integrity.RuleReassign:
+		books, err = usecase.PublisherRepository.ReassignBooks(tx, id, request.ReassignTo)
+		action = audit.ActionUpdate
+		after = map[string]any{"publisher_id": request.ReassignTo}
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to %s books of publisher %d", request.Books, id)
+		return errors.New("something went wrong")
+	}
+
+	return usecase.auditBooks(ctx, tx, books, action, before, after)
+}
+
+func (usecase *UsecaseImpl) auditBooks(ctx context.Context, tx *gorm.DB, books []uint, action string, before, after map[string]any) error {
+	for _, book := range books {
+		err := usecase.Audit.Record(ctx, tx, audit.Change{
+			Action:     action,
+			EntityType: "book",
+			EntityID:   book,
+			Before:     before,
+			After:      after,
+		})
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to audit book %d", book)
+			return errors.New("something went wrong")
+		}
+	}
+
+	return nil
+}
+
+// Merge moves the books of every source over to the target, deletes the
+// sources and leaves a redirect behind for each of them.
+func (usecase *UsecaseImpl) Merge(ctx context.Context, request merge.Request) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "publisher.Usecase.Merge")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	target, err := usecase.PublisherRepository.FindByID(tx, request.TargetID, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find publisher by id: %+v", request.TargetID)
+		return nil, errors.New("publisher not found")
+	}
+
+	var sources []Publisher
+	seen := map[uint]bool{}
+	for _, id := range request.Sources {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		source, err := usecase.PublisherRepository.FindByID(tx, int(id), projection.Request{})
+		if err != nil || id == target.ID {
+			validation = append(validation, ivalidator.ValidationError{
+				Field:   "sources",
+				Message: fmt.Sprintf("sources must be existing publishers other than the target, %d is not", id),
+			})
+			continue
+		}
+		sources = append(sources, source)
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	for _, source := range sources {
+		books, err := usecase.PublisherRepository.ReassignBooks(tx, int(source.ID), target.ID)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to move books of publisher %d", source.ID)
+			return nil, errors.New("something went wrong")
+		}
+		err = usecase.auditBooks(ctx, tx, books, audit.ActionUpdate,
+			map[string]any{"publisher_id": source.ID},
+			map[string]any{"publisher_id": target.ID},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		err = usecase.PublisherRepository.Delete(tx, int(source.ID), 0)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to delete merged publisher %d", source.ID)
+			return nil, errors.New("something went wrong")
+		}
+
+		err = usecase.RedirectRepository.Save(tx, &merge.Redirect{
+			EntityType: "publisher",
+			SourceID:   source.ID,
+			TargetID:   target.ID,
+		})
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to redirect merged publisher %d", source.ID)
+			return nil, errors.New("something went wrong")
+		}
+
+		err = usecase.Audit.Record(ctx, tx, audit.Change{
+			Action:     audit.ActionMerge,
+			EntityType: "publisher",
+			EntityID:   source.ID,
+			Before:     ToResponse(&source),
+			After:      map[string]any{"merged_into": target.ID},
+		})
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to audit publisher merge")
+			return nil, errors.New("something went wrong")
+		}
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToResponse(&target), nil
+}
+
+func (usecase *UsecaseImpl) Duplicates(ctx context.Context, request *merge.DuplicatesRequest) ([]merge.CandidateResponse, error) {
+	ctx, span := tracer.Start(ctx, "publisher.Usecase.Duplicates")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	merge.NewDuplicatesRequest(request)
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	candidates, err := usecase.PublisherRepository.Duplicates(tx, *request)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find duplicate publishers")
+		return nil, errors.New("something went wrong")
+	}
+
+	response := make([]merge.CandidateResponse, 0, len(candidates))
+	for _, candidate := range candidates {
+		response = append(response, *merge.ToCandidateResponse(&candidate))
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return response, nil
+}
+
+// validateName rejects a name another live publisher already goes by. It is
+// only checked when the search key changes from previous, so that duplicates
+// stored before the check existed can still be edited.
+func (usecase *UsecaseImpl) validateName(ctx context.Context, tx *gorm.DB, publisher *Publisher, previous string) ([]ivalidator.ValidationError, error) {
+	publisher.SetKey()
+	if publisher.NameKey == previous {
+		return nil, nil
+	}
+
+	taken, err := usecase.PublisherRepository.NameTaken(tx, publisher.NameKey, publisher.ID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to look up publisher name")
+		return nil, errors.New("something went wrong")
+	}
+	if taken {
+		return nameTaken(), nil
+	}
+
+	return nil, nil
+}
+
+// nameTaken is also returned when the unique index on the name key catches
+// a write that raced past validateName.
+func nameTaken() []ivalidator.ValidationError {
+	return []ivalidator.ValidationError{{
+		Field:   "name",
+		Message: "name is already taken",
+	}}
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests hits per sliding Window. A zero Limit is disabled.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Window is the state of a sliding window after a hit was taken from it.
+// Rejected hits are not counted.
+type Window struct {
+	Count   int
+	Oldest  time.Time
+	Allowed bool
+}
+
+// Backoff locks a key once it reaches Threshold consecutive failures, for Base
+// and then twice as long on every further failure, up to Max. Failures older
+// than Forget no longer count.
+type Backoff struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	Forget    time.Duration
+}
+
+// ParseLimit reads limits written as "requests/window", e.g. "5/1m". An empty
+// string or "0" disables the limit.
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" {
+		return Limit{}, nil
+	}
+
+	requests, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q must look like requests/window", value)
+	}
+
+	var limit Limit
+	var err error
+	if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests < 0 {
+		return Limit{}, fmt.Errorf("rate limit %q has an invalid request count", value)
+	}
+	if limit.Window, err = time.ParseDuration(window); err != nil || limit.Window <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q has an invalid window", value)
+	}
+
+	return limit, nil
+}
+
+func (limit Limit) Enabled() bool {
+	return limit.Requests > 0 && limit.Window > 0
+}
+
+// Remaining is how many hits are left in window, never below zero.
+func (limit Limit) Remaining(window Window) int {
+	return max(limit.Requests-window.Count, 0)
+}
+
+// Reset is how long until the oldest hit in window stops counting.
+func (limit Limit) Reset(window Window, now time.Time) time.Duration {
+	if window.Count == 0 {
+		return 0
+	}
+	return max(window.Oldest.Add(limit.Window).Sub(now), 0)
+}
+
+// Duration is how long a key with failures consecutive failures stays locked.
+func (backoff Backoff) Duration(failures int) time.Duration {
+	if backoff.Threshold <= 0 || failures < backoff.Threshold {
+		return 0
+	}
+
+	duration := backoff.Base
+	for i := backoff.Threshold; i < failures; i++ {
+		duration *= 2
+		if backoff.Max > 0 && duration >= backoff.Max {
+			return backoff.Max
+		}
+	}
+	return duration
+}
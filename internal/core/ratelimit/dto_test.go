@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{value: "5/1m", want: Limit{Requests: 5, Window: time.Minute}},
+		{value: " 100/1h ", want: Limit{Requests: 100, Window: time.Hour}},
+		{value: "", want: Limit{}},
+		{value: "0", want: Limit{}},
+		{value: "5", wantErr: true},
+		{value: "five/1m", wantErr: true},
+		{value: "-1/1m", wantErr: true},
+		{value: "5/minute", wantErr: true},
+		{value: "5/0s", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := ParseLimit(test.value)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseLimit(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("ParseLimit(%q) = %+v, want %+v", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLimitRemainingAndReset(t *testing.T) {
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	limit := Limit{Requests: 5, Window: time.Minute}
+
+	tests := []struct {
+		name          string
+		window        Window
+		wantRemaining int
+		wantReset     time.Duration
+	}{
+		{"empty window", Window{}, 5, 0},
+		{"partly used", Window{Count: 2, Oldest: now.Add(-20 * time.Second)}, 3, 40 * time.Second},
+		{"used up", Window{Count: 5, Oldest: now.Add(-50 * time.Second)}, 0, 10 * time.Second},
+		{"past the window", Window{Count: 7, Oldest: now.Add(-2 * time.Minute)}, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := limit.Remaining(test.window); got != test.wantRemaining {
+				t.Errorf("Remaining() = %d, want %d", got, test.wantRemaining)
+			}
+			if got := limit.Reset(test.window, now); got != test.wantReset {
+				t.Errorf("Reset() = %s, want %s", got, test.wantReset)
+			}
+		})
+	}
+}
+
+func TestBackoffDuration(t *testing.T) {
+	backoff := Backoff{Threshold: 3, Base: time.Minute, Max: 10 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{6, 8 * time.Minute},
+		{7, 10 * time.Minute},
+		{20, 10 * time.Minute},
+	}
+
+	for _, test := range tests {
+		if got := backoff.Duration(test.failures); got != test.want {
+			t.Errorf("Duration(%d) = %s, want %s", test.failures, got, test.want)
+		}
+	}
+
+	if got := (Backoff{Base: time.Minute}).Duration(10); got != 0 {
+		t.Errorf("Duration() without a threshold = %s, want 0", got)
+	}
+}
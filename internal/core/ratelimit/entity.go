@@ -0,0 +1,34 @@
+package ratelimit
+
+import "time"
+
+// Hit is one request counted against a sliding window.
+type Hit struct {
+	ID    uint      `gorm:"primaryKey"`
+	Key   string    `gorm:"size:255;not null;index:idx_rate_limit_hits_key_hit_at,priority:1"`
+	HitAt time.Time `gorm:"not null;index:idx_rate_limit_hits_key_hit_at,priority:2"`
+}
+
+func (Hit) TableName() string {
+	return "rate_limit_hits"
+}
+
+// Lockout tracks consecutive failures for a key and how long it is locked.
+type Lockout struct {
+	Key         string `gorm:"primaryKey;size:255"`
+	Failures    int    `gorm:"not null;default:0"`
+	LockedUntil time.Time
+	UpdatedAt   time.Time
+}
+
+func (Lockout) TableName() string {
+	return "lockouts"
+}
+
+// Locked reports whether the key is still locked at now, and for how long.
+func (lockout Lockout) Locked(now time.Time) (time.Duration, bool) {
+	if lockout.LockedUntil.After(now) {
+		return lockout.LockedUntil.Sub(now), true
+	}
+	return 0, false
+}
@@ -0,0 +1,18 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+type Store interface {
+	// Take counts a hit for key at now if limit still allows one within the
+	// window ending at now, and returns that window. Older hits are dropped.
+	Take(ctx context.Context, key string, now time.Time, limit Limit) (Window, error)
+	// Lockout returns the failures recorded for key.
+	Lockout(ctx context.Context, key string) (Lockout, error)
+	// Fail records a failure for key and locks it for backoff.Duration.
+	Fail(ctx context.Context, key string, now time.Time, backoff Backoff) (Lockout, error)
+	// Reset clears the failures recorded for key.
+	Reset(ctx context.Context, key string) error
+}
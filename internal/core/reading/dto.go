@@ -0,0 +1,187 @@
+package reading
+
+import (
+	"math"
+	"starter/internal/core/pagination"
+	"time"
+)
+
+// SortableFields lists what FindAll accepts in the sort parameter.
+var SortableFields = pagination.Sortable{
+	"id":          "reading_progress.id",
+	"started_at":  "reading_progress.started_at",
+	"finished_at": "reading_progress.finished_at",
+	"updated_at":  "reading_progress.updated_at",
+}
+
+type CreateRequest struct {
+	BookId      int    `json:"book_id" validate:"required,min=1"`
+	CurrentPage int    `json:"current_page" validate:"min=0"`
+	StartedAt   string `json:"started_at" validate:"omitempty,publication_date"`
+}
+
+// UpdateRequest corrects a progress by hand. An empty FinishedAt marks the
+// book as not finished.
+type UpdateRequest struct {
+	BookId      int    `json:"-"`
+	CurrentPage int    `json:"current_page" validate:"min=0"`
+	StartedAt   string `json:"started_at" validate:"omitempty,publication_date"`
+	FinishedAt  string `json:"finished_at" validate:"omitempty,publication_date"`
+	Version     uint   `json:"-"`
+}
+
+// SessionRequest logs reading from the current page up to EndPage, on ReadOn
+// or today.
+type SessionRequest struct {
+	BookId  int    `json:"-"`
+	EndPage int    `json:"end_page" validate:"required,min=1"`
+	Minutes int    `json:"minutes" validate:"min=0,max=1440"`
+	ReadOn  string `json:"read_on" validate:"omitempty,publication_date"`
+}
+
+type GoalRequest struct {
+	Year  int `json:"-" validate:"min=1900,max=9999"`
+	Books int `json:"books" validate:"min=0,max=10000"`
+	Pages int `json:"pages" validate:"min=0,max=10000000"`
+}
+
+type SessionResponse struct {
+	Id        int    `json:"id"`
+	StartPage int    `json:"start_page"`
+	EndPage   int    `json:"end_page"`
+	Pages     int    `json:"pages"`
+	Minutes   int    `json:"minutes"`
+	ReadOn    string `json:"read_on"`
+}
+
+type Response struct {
+	BookId      int               `json:"book_id"`
+	Title       string            `json:"title"`
+	PageCount   int               `json:"page_count"`
+	CurrentPage int               `json:"current_page"`
+	Percent     float64           `json:"percent"`
+	StartedAt   string            `json:"started_at,omitempty"`
+	FinishedAt  string            `json:"finished_at,omitempty"`
+	Sessions    []SessionResponse `json:"sessions,omitempty"`
+	Version     uint              `json:"version"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// GoalResponse is a goal next to what was read towards it so far.
+type GoalResponse struct {
+	Year          int `json:"year"`
+	Books         int `json:"books"`
+	Pages         int `json:"pages"`
+	BooksFinished int `json:"books_finished"`
+	PagesRead     int `json:"pages_read"`
+}
+
+type WeekResponse struct {
+	Week  string `json:"week"`
+	Pages int    `json:"pages"`
+}
+
+type YearResponse struct {
+	Year  int `json:"year"`
+	Books int `json:"books"`
+}
+
+type CategoryResponse struct {
+	Id    int    `json:"id"`
+	Name  string `json:"name"`
+	Books int    `json:"books"`
+}
+
+// StatsResponse sums up the reading of one user. PagesPerWeek and the totals
+// cover Year only, BooksPerYear spans every year.
+type StatsResponse struct {
+	Year                int                `json:"year"`
+	PagesRead           int                `json:"pages_read"`
+	BooksFinished       int                `json:"books_finished"`
+	Goal                *GoalResponse      `json:"goal"`
+	PagesPerWeek        []WeekResponse     `json:"pages_per_week"`
+	BooksPerYear        []YearResponse     `json:"books_per_year"`
+	FavouriteCategories []CategoryResponse `json:"favourite_categories"`
+}
+
+// Week, Year and Category are the rows the statistics queries return.
+type Week struct {
+	Week  time.Time
+	Pages int
+}
+
+type Year struct {
+	Year  int
+	Books int
+}
+
+type Category struct {
+	ID    uint
+	Name  string
+	Books int
+}
+
+func parseDate(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	date, _ := time.Parse("2006-01-02", value)
+	return &date
+}
+
+func formatDate(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.Format("2006-01-02")
+}
+
+func (dto *CreateRequest) ToEntity(userID uint) *Progress {
+	return &Progress{
+		UserID:      userID,
+		BookID:      uint(dto.BookId),
+		CurrentPage: dto.CurrentPage,
+		StartedAt:   parseDate(dto.StartedAt),
+	}
+}
+
+func ToResponse(entity *Progress) *Response {
+	var sessions []SessionResponse
+	for _, session := range entity.Sessions {
+		sessions = append(sessions, SessionResponse{
+			Id:        int(session.ID),
+			StartPage: session.StartPage,
+			EndPage:   session.EndPage,
+			Pages:     session.Pages,
+			Minutes:   session.Minutes,
+			ReadOn:    session.ReadOn.Format("2006-01-02"),
+		})
+	}
+
+	percent := 0.0
+	if entity.Book.PageCount > 0 {
+		percent = math.Round(float64(entity.CurrentPage)*10000/float64(entity.Book.PageCount)) / 100
+	}
+
+	return &Response{
+		BookId:      int(entity.BookID),
+		Title:       entity.Book.Title,
+		PageCount:   entity.Book.PageCount,
+		CurrentPage: entity.CurrentPage,
+		Percent:     percent,
+		StartedAt:   formatDate(entity.StartedAt),
+		FinishedAt:  formatDate(entity.FinishedAt),
+		Sessions:    sessions,
+		Version:     entity.Version,
+		UpdatedAt:   entity.UpdatedAt,
+	}
+}
+
+// ToGoalResponse leaves what was read towards goal at zero.
+func ToGoalResponse(entity *Goal) *GoalResponse {
+	return &GoalResponse{
+		Year:  entity.Year,
+		Books: entity.Books,
+		Pages: entity.Pages,
+	}
+}
@@ -0,0 +1,60 @@
+package reading
+
+import (
+	"starter/internal/core/book"
+	"starter/internal/core/user"
+	"time"
+)
+
+// Progress is how far a user got with a book. It is finished once FinishedAt
+// is set, which logging the last page does on its own.
+type Progress struct {
+	ID          uint       `gorm:"primaryKey"`
+	UserID      uint       `gorm:"not null;uniqueIndex:idx_reading_progress_user_book"`
+	User        user.User  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	BookID      uint       `gorm:"not null;uniqueIndex:idx_reading_progress_user_book;index"`
+	Book        book.Book  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CurrentPage int        `gorm:"not null;default:0"`
+	StartedAt   *time.Time `gorm:"type:date"`
+	FinishedAt  *time.Time `gorm:"type:date;index"`
+	Sessions    []Session  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Version     uint       `gorm:"not null;default:1"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (Progress) TableName() string {
+	return "reading_progress"
+}
+
+// Session is one sitting with a book, from StartPage up to EndPage.
+type Session struct {
+	ID         uint      `gorm:"primaryKey"`
+	ProgressID uint      `gorm:"not null;index"`
+	StartPage  int       `gorm:"not null"`
+	EndPage    int       `gorm:"not null"`
+	Pages      int       `gorm:"not null"`
+	Minutes    int       `gorm:"not null;default:0"`
+	ReadOn     time.Time `gorm:"type:date;not null;index"`
+	CreatedAt  time.Time
+}
+
+func (Session) TableName() string {
+	return "reading_sessions"
+}
+
+// Goal is what a user wants to read in a year. Either target may be zero.
+type Goal struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_reading_goals_user_year"`
+	User      user.User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Year      int       `gorm:"not null;uniqueIndex:idx_reading_goals_user_year"`
+	Books     int       `gorm:"not null;default:0"`
+	Pages     int       `gorm:"not null;default:0"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (Goal) TableName() string {
+	return "reading_goals"
+}
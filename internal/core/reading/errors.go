@@ -0,0 +1,10 @@
+package reading
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("book is not being tracked")
+	ErrBookNotFound    = errors.New("book not found")
+	ErrAlreadyTracking = errors.New("book is already being tracked")
+	ErrGoalNotFound    = errors.New("no goal for that year")
+)
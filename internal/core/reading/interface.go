@@ -0,0 +1,40 @@
+package reading
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"starter/internal/core/book"
+	"starter/internal/core/filter"
+	"starter/internal/core/pagination"
+)
+
+type Repository interface {
+	Save(db *gorm.DB, progress *Progress) error
+	Update(db *gorm.DB, progress *Progress) error
+	Delete(db *gorm.DB, id uint, version uint) error
+	FindAll(db *gorm.DB, userID uint, params pagination.Request, filter filter.ReadingFilter) ([]Progress, pagination.Meta, error)
+	FindByBook(db *gorm.DB, userID uint, bookID int) (Progress, error)
+	FindBook(db *gorm.DB, id int) (book.Book, error)
+	SaveSession(db *gorm.DB, session *Session) error
+	FindGoals(db *gorm.DB, userID uint) ([]Goal, error)
+	FindGoal(db *gorm.DB, userID uint, year int) (Goal, error)
+	SaveGoal(db *gorm.DB, goal *Goal) error
+	DeleteGoal(db *gorm.DB, userID uint, year int) error
+	PagesRead(db *gorm.DB, userID uint, year int) (int, error)
+	PagesPerWeek(db *gorm.DB, userID uint, year int) ([]Week, error)
+	BooksPerYear(db *gorm.DB, userID uint) ([]Year, error)
+	FavouriteCategories(db *gorm.DB, userID uint, limit int) ([]Category, error)
+}
+
+type Usecase interface {
+	Save(ctx context.Context, request CreateRequest) (Response, error)
+	Update(ctx context.Context, request UpdateRequest) (*Response, error)
+	Delete(ctx context.Context, bookID int, version uint) error
+	FindAll(ctx context.Context, request *pagination.Request, filter *filter.ReadingFilter) (pagination.Page[Response], error)
+	FindByBook(ctx context.Context, bookID int) (*Response, error)
+	SaveSession(ctx context.Context, request SessionRequest) (*Response, error)
+	Stats(ctx context.Context, year int) (*StatsResponse, error)
+	FindGoals(ctx context.Context) ([]GoalResponse, error)
+	SaveGoal(ctx context.Context, request GoalRequest) (*GoalResponse, error)
+	DeleteGoal(ctx context.Context, year int) error
+}
@@ -0,0 +1,650 @@
+package reading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+	"starter/internal/core/audit"
+	"starter/internal/core/book"
+	"starter/internal/core/concurrency"
+	"starter/internal/core/filter"
+	"starter/internal/core/pagination"
+	ivalidator "starter/internal/core/validator"
+	"time"
+)
+
+var tracer = otel.Tracer("starter/internal/core/reading")
+
+// favouriteCategories is how many categories the statistics rank.
+const favouriteCategories = 5
+
+type UsecaseDependency struct {
+	DB                *gorm.DB
+	Validator         ivalidator.Validator
+	Audit             audit.Recorder
+	ReadingRepository Repository
+}
+
+type UsecaseImpl struct {
+	UsecaseDependency
+}
+
+func NewUsecase(deps UsecaseDependency) Usecase {
+	return &UsecaseImpl{
+		deps,
+	}
+}
+
+func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Response, error) {
+	ctx, span := tracer.Start(ctx, "reading.Usecase.Save")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return Response{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	book, err := usecase.findBook(ctx, tx, request.BookId)
+	if err != nil {
+		return Response{}, err
+	}
+	if validation = checkPage("current_page", request.CurrentPage, &book); validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return Response{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	userID := audit.ActorFrom(ctx).UserID
+	_, err = usecase.ReadingRepository.FindByBook(tx, userID, request.BookId)
+	if err == nil {
+		return Response{}, ErrAlreadyTracking
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find progress of book %d", request.BookId)
+		return Response{}, errors.New("something went wrong")
+	}
+
+	progress := request.ToEntity(userID)
+	if progress.StartedAt == nil {
+		today := today()
+		progress.StartedAt = &today
+	}
+	err = usecase.ReadingRepository.Save(tx, progress)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save progress")
+		return Response{}, errors.New("something went wrong")
+	}
+	progress.Book = book
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionCreate,
+		EntityType: "reading",
+		EntityID:   progress.ID,
+		After:      ToResponse(progress),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit progress creation")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return Response{}, errors.New("something went wrong")
+	}
+
+	return *ToResponse(progress), nil
+}
+
+func (usecase *UsecaseImpl) Update(ctx context.Context, request UpdateRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "reading.Usecase.Update")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	progress, err := usecase.findByBook(ctx, tx, request.BookId)
+	if err != nil {
+		return nil, err
+	}
+
+	original := progress
+	if request.Version != 0 {
+		progress.Version = request.Version
+	}
+	progress.CurrentPage = request.CurrentPage
+	progress.StartedAt = parseDate(request.StartedAt)
+	progress.FinishedAt = parseDate(request.FinishedAt)
+
+	validation = checkPage("current_page", progress.CurrentPage, &progress.Book)
+	if progress.StartedAt != nil && progress.FinishedAt != nil && progress.FinishedAt.Before(*progress.StartedAt) {
+		validation = append(validation, ivalidator.ValidationError{
+			Field:   "finished_at",
+			Message: "finished_at cannot be before started_at",
+		})
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	return usecase.write(ctx, tx, &original, &progress)
+}
+
+// SaveSession logs reading from the current page up to the requested one and
+// moves the progress along. Reaching the last page finishes the book.
+func (usecase *UsecaseImpl) SaveSession(ctx context.Context, request SessionRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "reading.Usecase.SaveSession")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	progress, err := usecase.findByBook(ctx, tx, request.BookId)
+	if err != nil {
+		return nil, err
+	}
+
+	validation = checkPage("end_page", request.EndPage, &progress.Book)
+	if request.EndPage <= progress.CurrentPage {
+		validation = append(validation, ivalidator.ValidationError{
+			Field:   "end_page",
+			Message: fmt.Sprintf("end_page must be past the current page %d", progress.CurrentPage),
+		})
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	readOn := today()
+	if date := parseDate(request.ReadOn); date != nil {
+		readOn = *date
+	}
+
+	err = usecase.ReadingRepository.SaveSession(tx, &Session{
+		ProgressID: progress.ID,
+		StartPage:  progress.CurrentPage,
+		EndPage:    request.EndPage,
+		Pages:      request.EndPage - progress.CurrentPage,
+		Minutes:    request.Minutes,
+		ReadOn:     readOn,
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save reading session")
+		return nil, errors.New("something went wrong")
+	}
+
+	original := progress
+	progress.CurrentPage = request.EndPage
+	if progress.StartedAt == nil {
+		progress.StartedAt = &readOn
+	}
+	if progress.FinishedAt == nil && request.EndPage == progress.Book.PageCount {
+		progress.FinishedAt = &readOn
+	}
+
+	return usecase.write(ctx, tx, &original, &progress)
+}
+
+// write stores the changes made to progress, audits them and commits. The
+// response carries the sessions as they are after the write.
+func (usecase *UsecaseImpl) write(ctx context.Context, tx *gorm.DB, original *Progress, progress *Progress) (*Response, error) {
+	err := usecase.ReadingRepository.Update(tx, progress)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update progress")
+		return nil, errors.New("something went wrong")
+	}
+
+	current, err := usecase.findByBook(ctx, tx, int(progress.BookID))
+	if err != nil {
+		return nil, err
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "reading",
+		EntityID:   progress.ID,
+		Before:     ToResponse(original),
+		After:      ToResponse(&current),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit progress update")
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToResponse(&current), nil
+}
+
+// Delete stops tracking a book, its sessions go with it.
+func (usecase *UsecaseImpl) Delete(ctx context.Context, bookID int, version uint) error {
+	ctx, span := tracer.Start(ctx, "reading.Usecase.Delete")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	progress, err := usecase.findByBook(ctx, tx, bookID)
+	if err != nil {
+		return err
+	}
+
+	err = usecase.ReadingRepository.Delete(tx, progress.ID, version)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to delete progress")
+		return errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionDelete,
+		EntityType: "reading",
+		EntityID:   progress.ID,
+		Before:     ToResponse(&progress),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit progress deletion")
+		return errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return errors.New("something went wrong")
+	}
+	return nil
+}
+
+func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Request, filter *filter.ReadingFilter) (pagination.Page[Response], error) {
+	ctx, span := tracer.Start(ctx, "reading.Usecase.FindAll")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	// What was read last comes first unless asked otherwise.
+	if request.Sort == "" && request.OrderBy == "" && request.SortBy == "" {
+		request.Sort = "-updated_at"
+	}
+	pagination.NewPagination(request)
+
+	validation := usecase.Validator.ValidateStruct(request)
+	validation = append(validation, usecase.Validator.ValidateStruct(filter)...)
+	validation = append(validation, request.ParseSort(SortableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return pagination.Page[Response]{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	progresses, meta, err := usecase.ReadingRepository.FindAll(tx, audit.ActorFrom(ctx).UserID, *request, *filter)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return pagination.Page[Response]{}, pagination.CursorError()
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to fetch reading progress")
+		return pagination.Page[Response]{}, errors.New("something went wrong")
+	}
+
+	var response []Response
+	for _, progress := range progresses {
+		response = append(response, *ToResponse(&progress))
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return pagination.Page[Response]{}, errors.New("something went wrong")
+	}
+
+	return *pagination.NewPage[Response](*request, meta, response), nil
+}
+
+func (usecase *UsecaseImpl) FindByBook(ctx context.Context, bookID int) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "reading.Usecase.FindByBook")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	progress, err := usecase.findByBook(ctx, tx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToResponse(&progress), nil
+}
+
+// Stats sums up the caller's reading in year, the current one when zero.
+func (usecase *UsecaseImpl) Stats(ctx context.Context, year int) (*StatsResponse, error) {
+	ctx, span := tracer.Start(ctx, "reading.Usecase.Stats")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	if year == 0 {
+		year = time.Now().Year()
+	}
+	if validation := checkYear(year); validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	userID := audit.ActorFrom(ctx).UserID
+	response := &StatsResponse{
+		Year:                year,
+		PagesPerWeek:        []WeekResponse{},
+		BooksPerYear:        []YearResponse{},
+		FavouriteCategories: []CategoryResponse{},
+	}
+
+	pages, err := usecase.ReadingRepository.PagesRead(tx, userID, year)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to sum pages read")
+		return nil, errors.New("something went wrong")
+	}
+	response.PagesRead = pages
+
+	weeks, err := usecase.ReadingRepository.PagesPerWeek(tx, userID, year)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to sum pages per week")
+		return nil, errors.New("something went wrong")
+	}
+	for _, week := range weeks {
+		response.PagesPerWeek = append(response.PagesPerWeek, WeekResponse{
+			Week:  week.Week.Format("2006-01-02"),
+			Pages: week.Pages,
+		})
+	}
+
+	years, err := usecase.ReadingRepository.BooksPerYear(tx, userID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to count books per year")
+		return nil, errors.New("something went wrong")
+	}
+	for _, y := range years {
+		response.BooksPerYear = append(response.BooksPerYear, YearResponse{Year: y.Year, Books: y.Books})
+		if y.Year == year {
+			response.BooksFinished = y.Books
+		}
+	}
+
+	categories, err := usecase.ReadingRepository.FavouriteCategories(tx, userID, favouriteCategories)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to rank categories")
+		return nil, errors.New("something went wrong")
+	}
+	for _, category := range categories {
+		response.FavouriteCategories = append(response.FavouriteCategories, CategoryResponse{
+			Id:    int(category.ID),
+			Name:  category.Name,
+			Books: category.Books,
+		})
+	}
+
+	goal, err := usecase.ReadingRepository.FindGoal(tx, userID, year)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find goal of %d", year)
+		return nil, errors.New("something went wrong")
+	}
+	if err == nil {
+		response.Goal = &GoalResponse{
+			Year:          goal.Year,
+			Books:         goal.Books,
+			Pages:         goal.Pages,
+			BooksFinished: response.BooksFinished,
+			PagesRead:     response.PagesRead,
+		}
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return response, nil
+}
+
+func (usecase *UsecaseImpl) FindGoals(ctx context.Context) ([]GoalResponse, error) {
+	ctx, span := tracer.Start(ctx, "reading.Usecase.FindGoals")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	userID := audit.ActorFrom(ctx).UserID
+	goals, err := usecase.ReadingRepository.FindGoals(tx, userID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to fetch goals")
+		return nil, errors.New("something went wrong")
+	}
+
+	response := make([]GoalResponse, 0, len(goals))
+	for _, goal := range goals {
+		progress, err := usecase.goalResponse(ctx, tx, &goal)
+		if err != nil {
+			return nil, err
+		}
+		response = append(response, *progress)
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return response, nil
+}
+
+// SaveGoal sets the goal of a year, replacing the one there was.
+func (usecase *UsecaseImpl) SaveGoal(ctx context.Context, request GoalRequest) (*GoalResponse, error) {
+	ctx, span := tracer.Start(ctx, "reading.Usecase.SaveGoal")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	userID := audit.ActorFrom(ctx).UserID
+	before, err := usecase.ReadingRepository.FindGoal(tx, userID, request.Year)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find goal of %d", request.Year)
+		return nil, errors.New("something went wrong")
+	}
+	change := audit.Change{Action: audit.ActionUpdate, EntityType: "reading_goal", Before: ToGoalResponse(&before)}
+	if err != nil {
+		change = audit.Change{Action: audit.ActionCreate, EntityType: "reading_goal"}
+	}
+
+	goal := &Goal{
+		UserID: userID,
+		Year:   request.Year,
+		Books:  request.Books,
+		Pages:  request.Pages,
+	}
+	err = usecase.ReadingRepository.SaveGoal(tx, goal)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save goal")
+		return nil, errors.New("something went wrong")
+	}
+
+	change.EntityID = goal.ID
+	change.After = ToGoalResponse(goal)
+	if err = usecase.Audit.Record(ctx, tx, change); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit goal")
+		return nil, errors.New("something went wrong")
+	}
+
+	response, err := usecase.goalResponse(ctx, tx, goal)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return response, nil
+}
+
+func (usecase *UsecaseImpl) DeleteGoal(ctx context.Context, year int) error {
+	ctx, span := tracer.Start(ctx, "reading.Usecase.DeleteGoal")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	userID := audit.ActorFrom(ctx).UserID
+	goal, err := usecase.ReadingRepository.FindGoal(tx, userID, year)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrGoalNotFound
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find goal of %d", year)
+		return errors.New("something went wrong")
+	}
+
+	if err = usecase.ReadingRepository.DeleteGoal(tx, userID, year); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to delete goal")
+		return errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionDelete,
+		EntityType: "reading_goal",
+		EntityID:   goal.ID,
+		Before:     ToGoalResponse(&goal),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit goal deletion")
+		return errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return errors.New("something went wrong")
+	}
+	return nil
+}
+
+// goalResponse puts goal next to the pages and books read in its year.
+func (usecase *UsecaseImpl) goalResponse(ctx context.Context, tx *gorm.DB, goal *Goal) (*GoalResponse, error) {
+	pages, err := usecase.ReadingRepository.PagesRead(tx, goal.UserID, goal.Year)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to sum pages read")
+		return nil, errors.New("something went wrong")
+	}
+	years, err := usecase.ReadingRepository.BooksPerYear(tx, goal.UserID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to count books per year")
+		return nil, errors.New("something went wrong")
+	}
+
+	response := ToGoalResponse(goal)
+	response.PagesRead = pages
+	for _, year := range years {
+		if year.Year == goal.Year {
+			response.BooksFinished = year.Books
+		}
+	}
+	return response, nil
+}
+
+func (usecase *UsecaseImpl) findByBook(ctx context.Context, tx *gorm.DB, bookID int) (Progress, error) {
+	progress, err := usecase.ReadingRepository.FindByBook(tx, audit.ActorFrom(ctx).UserID, bookID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return progress, ErrNotFound
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find progress of book %d", bookID)
+		return progress, errors.New("something went wrong")
+	}
+	return progress, nil
+}
+
+func (usecase *UsecaseImpl) findBook(ctx context.Context, tx *gorm.DB, id int) (book.Book, error) {
+	book, err := usecase.ReadingRepository.FindBook(tx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return book, ErrBookNotFound
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find book by id: %d", id)
+		return book, errors.New("something went wrong")
+	}
+	return book, nil
+}
+
+// checkPage keeps page within the book, books without a page count take any.
+func checkPage(field string, page int, book *book.Book) []ivalidator.ValidationError {
+	if book.PageCount == 0 || page <= book.PageCount {
+		return nil
+	}
+	return []ivalidator.ValidationError{{
+		Field:   field,
+		Message: fmt.Sprintf("%s cannot be past the last page %d", field, book.PageCount),
+	}}
+}
+
+func checkYear(year int) []ivalidator.ValidationError {
+	if year >= 1900 && year <= 9999 {
+		return nil
+	}
+	return []ivalidator.ValidationError{{
+		Field:   "year",
+		Message: "year must be between 1900 and 9999",
+	}}
+}
+
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,63 @@
+package recommendation
+
+import (
+	"starter/internal/core/book"
+	"time"
+)
+
+// DefaultLimit is how many books the endpoints return without ?limit=.
+const DefaultLimit = 10
+
+// Weights of the similarity signals. Categories are compared with the Jaccard
+// index, readers with the cosine of the books' owner sets, so every signal is
+// between 0 and 1 before weighting.
+type Weights struct {
+	Categories float64
+	Author     float64
+	Readers    float64
+}
+
+var DefaultWeights = Weights{
+	Categories: 0.4,
+	Author:     0.2,
+	Readers:    0.4,
+}
+
+type Request struct {
+	Limit int `json:"limit" validate:"omitempty,min=1,max=50"`
+}
+
+// Neighbour is a row of a book's neighbourhood, Matches is how many of the
+// caller's books led to it when recommending.
+type Neighbour struct {
+	BookID           uint
+	Score            float64
+	SharedCategories int
+	SameAuthor       bool
+	CoReaders        int
+	Matches          int
+}
+
+// SimilarResponse is a book close to the one asked about, with the signals
+// that made it so.
+type SimilarResponse struct {
+	book.Response
+	Score            float64 `json:"score"`
+	SharedCategories int     `json:"shared_categories"`
+	SameAuthor       bool    `json:"same_author"`
+	CoReaders        int     `json:"co_readers"`
+}
+
+// RecommendationResponse is a book the caller doesn't have yet. Matches is how
+// many of their books it is similar to, zero for the popular books users
+// without any books get.
+type RecommendationResponse struct {
+	book.Response
+	Score   float64 `json:"score"`
+	Matches int     `json:"matches"`
+}
+
+type RefreshResponse struct {
+	Pairs      int64     `json:"pairs"`
+	ComputedAt time.Time `json:"computed_at"`
+}
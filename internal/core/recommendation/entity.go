@@ -0,0 +1,25 @@
+package recommendation
+
+import (
+	"starter/internal/core/book"
+	"time"
+)
+
+// Similarity is how close Similar is to Book. The table is rebuilt from
+// scratch by the refresh job, only the closest neighbours of every book are
+// kept.
+type Similarity struct {
+	BookID           uint      `gorm:"primaryKey"`
+	Book             book.Book `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	SimilarID        uint      `gorm:"primaryKey;index"`
+	Similar          book.Book `gorm:"foreignKey:SimilarID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Score            float64   `gorm:"not null"`
+	SharedCategories int       `gorm:"not null;default:0"`
+	SameAuthor       bool      `gorm:"not null;default:false"`
+	CoReaders        int       `gorm:"not null;default:0"`
+	ComputedAt       time.Time `gorm:"not null"`
+}
+
+func (Similarity) TableName() string {
+	return "book_similarities"
+}
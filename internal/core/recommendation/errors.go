@@ -0,0 +1,10 @@
+package recommendation
+
+import "errors"
+
+var (
+	ErrBookNotFound = errors.New("book not found")
+	// ErrRefreshing is returned when another instance is already rebuilding
+	// the similarity table.
+	ErrRefreshing = errors.New("similarities are already being refreshed")
+)
@@ -0,0 +1,29 @@
+package recommendation
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"starter/internal/core/book"
+	"time"
+)
+
+type Repository interface {
+	// Refresh replaces every similarity with freshly computed ones, keeping
+	// the closest neighbours of each book. It reports false without touching
+	// anything when another transaction holds the refresh lock.
+	Refresh(db *gorm.DB, weights Weights, neighbours int, now time.Time) (int64, bool, error)
+	BookExists(db *gorm.DB, id int) (bool, error)
+	FindSimilar(db *gorm.DB, bookID int, userID uint, limit int) ([]Neighbour, error)
+	FindRecommended(db *gorm.DB, userID uint, limit int) ([]Neighbour, error)
+	FindPopular(db *gorm.DB, userID uint, limit int) ([]Neighbour, error)
+	FindBooks(db *gorm.DB, ids []uint) ([]book.Book, error)
+}
+
+type Usecase interface {
+	Refresh(ctx context.Context) (*RefreshResponse, error)
+	// Run refreshes the similarities right away and then every Interval
+	// until ctx is done. It returns at once when Interval is not positive.
+	Run(ctx context.Context)
+	FindSimilar(ctx context.Context, bookID int, request Request) ([]SimilarResponse, error)
+	FindRecommended(ctx context.Context, request Request) ([]RecommendationResponse, error)
+}
@@ -0,0 +1,244 @@
+package recommendation
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+	"starter/internal/core/audit"
+	"starter/internal/core/book"
+	"starter/internal/core/storage"
+	ivalidator "starter/internal/core/validator"
+	"time"
+)
+
+var tracer = otel.Tracer("starter/internal/core/recommendation")
+
+type UsecaseDependency struct {
+	DB        *gorm.DB
+	Validator ivalidator.Validator
+	Storage   storage.Storage
+	// Interval between two refreshes of the background job, Neighbours is
+	// how many similar books are kept for every book.
+	Interval                 time.Duration
+	Neighbours               int
+	Weights                  Weights
+	RecommendationRepository Repository
+}
+
+type UsecaseImpl struct {
+	UsecaseDependency
+}
+
+func NewUsecase(deps UsecaseDependency) Usecase {
+	return &UsecaseImpl{
+		deps,
+	}
+}
+
+func (usecase *UsecaseImpl) Refresh(ctx context.Context) (*RefreshResponse, error) {
+	ctx, span := tracer.Start(ctx, "recommendation.Usecase.Refresh")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	now := time.Now()
+	pairs, locked, err := usecase.RecommendationRepository.Refresh(tx, usecase.Weights, usecase.Neighbours, now)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to refresh similarities")
+		return nil, errors.New("something went wrong")
+	}
+	if !locked {
+		return nil, ErrRefreshing
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+
+	return &RefreshResponse{
+		Pairs:      pairs,
+		ComputedAt: now,
+	}, nil
+}
+
+func (usecase *UsecaseImpl) Run(ctx context.Context) {
+	if usecase.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(usecase.Interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		response, err := usecase.Refresh(ctx)
+		switch {
+		case errors.Is(err, ErrRefreshing):
+			log.Ctx(ctx).Info().Msg("similarities are being refreshed elsewhere, skipping")
+		case err != nil && ctx.Err() == nil:
+			log.Ctx(ctx).Error().Err(err).Msg("similarity refresh failed")
+		case err == nil:
+			log.Ctx(ctx).Info().
+				Int64("pairs", response.Pairs).
+				Dur("duration", time.Since(start)).
+				Msg("similarities refreshed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (usecase *UsecaseImpl) FindSimilar(ctx context.Context, bookID int, request Request) ([]SimilarResponse, error) {
+	ctx, span := tracer.Start(ctx, "recommendation.Usecase.FindSimilar")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	if validation := usecase.Validator.ValidateStruct(request); validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	exists, err := usecase.RecommendationRepository.BookExists(tx, bookID)
+	if err != nil {
+		return nil, errors.New("something went wrong")
+	}
+	if !exists {
+		return nil, ErrBookNotFound
+	}
+
+	neighbours, err := usecase.RecommendationRepository.FindSimilar(tx, bookID, audit.ActorFrom(ctx).UserID, limit(request))
+	if err != nil {
+		return nil, errors.New("something went wrong")
+	}
+	books, err := usecase.books(ctx, tx, neighbours)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+
+	response := make([]SimilarResponse, 0, len(neighbours))
+	for _, neighbour := range neighbours {
+		book, ok := books[neighbour.BookID]
+		if !ok {
+			continue
+		}
+		response = append(response, SimilarResponse{
+			Response:         book,
+			Score:            neighbour.Score,
+			SharedCategories: neighbour.SharedCategories,
+			SameAuthor:       neighbour.SameAuthor,
+			CoReaders:        neighbour.CoReaders,
+		})
+	}
+
+	return response, nil
+}
+
+// FindRecommended ranks the neighbours of the caller's books, anything on one
+// of their shelves or in their reading progress is left out. Users without
+// any books get the books most readers have instead.
+func (usecase *UsecaseImpl) FindRecommended(ctx context.Context, request Request) ([]RecommendationResponse, error) {
+	ctx, span := tracer.Start(ctx, "recommendation.Usecase.FindRecommended")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	if validation := usecase.Validator.ValidateStruct(request); validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	userID := audit.ActorFrom(ctx).UserID
+	neighbours, err := usecase.RecommendationRepository.FindRecommended(tx, userID, limit(request))
+	if err != nil {
+		return nil, errors.New("something went wrong")
+	}
+	if len(neighbours) == 0 {
+		neighbours, err = usecase.RecommendationRepository.FindPopular(tx, userID, limit(request))
+		if err != nil {
+			return nil, errors.New("something went wrong")
+		}
+	}
+	books, err := usecase.books(ctx, tx, neighbours)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+
+	response := make([]RecommendationResponse, 0, len(neighbours))
+	for _, neighbour := range neighbours {
+		book, ok := books[neighbour.BookID]
+		if !ok {
+			continue
+		}
+		response = append(response, RecommendationResponse{
+			Response: book,
+			Score:    neighbour.Score,
+			Matches:  neighbour.Matches,
+		})
+	}
+
+	return response, nil
+}
+
+// books loads the books of neighbours with their cover links, by id.
+func (usecase *UsecaseImpl) books(ctx context.Context, tx *gorm.DB, neighbours []Neighbour) (map[uint]book.Response, error) {
+	if len(neighbours) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(neighbours))
+	for _, neighbour := range neighbours {
+		ids = append(ids, neighbour.BookID)
+	}
+	books, err := usecase.RecommendationRepository.FindBooks(tx, ids)
+	if err != nil {
+		return nil, errors.New("something went wrong")
+	}
+
+	response := make(map[uint]book.Response, len(books))
+	for _, entity := range books {
+		cover := usecase.Storage.Download(ctx, storage.DownloadRequest{
+			Bucket: "bucket",
+			Name:   entity.Cover,
+		})
+
+		entity.Cover = ""
+		if cover != nil {
+			entity.Cover = cover.Link
+		}
+		response[entity.ID] = *book.ToResponse(&entity)
+	}
+
+	return response, nil
+}
+
+func limit(request Request) int {
+	if request.Limit == 0 {
+		return DefaultLimit
+	}
+	return request.Limit
+}
@@ -0,0 +1,83 @@
+package review
+
+import (
+	"starter/internal/core/pagination"
+	"time"
+)
+
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// SortableFields lists what FindAll accepts in the sort parameter.
+var SortableFields = pagination.Sortable{
+	"id":            "reviews.id",
+	"rating":        "reviews.rating",
+	"helpful_count": "reviews.helpful_count",
+	"created_at":    "reviews.created_at",
+	"updated_at":    "reviews.updated_at",
+}
+
+type CreateRequest struct {
+	BookId int    `json:"-"`
+	Rating int    `json:"rating" validate:"required,min=1,max=5"`
+	Body   string `json:"body" validate:"max=5000"`
+}
+
+type UpdateRequest struct {
+	Id      int    `json:"-"`
+	Rating  int    `json:"rating" validate:"required,min=1,max=5"`
+	Body    string `json:"body" validate:"max=5000"`
+	Version uint   `json:"-"`
+}
+
+type ModerateRequest struct {
+	Id      int    `json:"-"`
+	Status  string `json:"status" validate:"required,oneof=pending approved rejected"`
+	Version uint   `json:"-"`
+}
+
+type Response struct {
+	Id           int       `json:"id"`
+	BookId       int       `json:"book_id"`
+	UserId       int       `json:"user_id"`
+	Rating       int       `json:"rating"`
+	Body         string    `json:"body"`
+	Status       string    `json:"status"`
+	HelpfulCount int       `json:"helpful_count"`
+	Version      uint      `json:"version"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (dto *CreateRequest) ToEntity(userID uint, status string) *Review {
+	return &Review{
+		BookID: uint(dto.BookId),
+		UserID: userID,
+		Rating: dto.Rating,
+		Body:   dto.Body,
+		Status: status,
+	}
+}
+
+// Counted reports whether the review takes part in the rating of its book.
+func (entity *Review) Counted() bool {
+	return entity.Status == StatusApproved
+}
+
+func ToResponse(entity *Review) *Response {
+	return &Response{
+		Id:           int(entity.ID),
+		BookId:       int(entity.BookID),
+		UserId:       int(entity.UserID),
+		Rating:       entity.Rating,
+		Body:         entity.Body,
+		Status:       entity.Status,
+		HelpfulCount: entity.HelpfulCount,
+		Version:      entity.Version,
+		CreatedAt:    entity.CreatedAt,
+		UpdatedAt:    entity.UpdatedAt,
+	}
+}
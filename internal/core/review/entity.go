@@ -0,0 +1,37 @@
+package review
+
+import (
+	"gorm.io/gorm"
+	"starter/internal/core/book"
+	"starter/internal/core/user"
+	"time"
+)
+
+// Review is the rating a user gives a book, at most one live review per user
+// and book. HelpfulCount is kept by the vote queries, review writes never
+// touch it.
+type Review struct {
+	BookID       uint      `gorm:"not null;uniqueIndex:idx_reviews_book_user,where:deleted_at IS NULL"`
+	Book         book.Book `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_reviews_book_user,where:deleted_at IS NULL"`
+	User         user.User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Rating       int       `gorm:"not null"`
+	Body         string    `gorm:"type:text"`
+	Status       string    `gorm:"size:16;not null;index"`
+	HelpfulCount int       `gorm:"<-:false;not null;default:0"`
+	Votes        []Vote    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Version      uint      `gorm:"not null;default:1"`
+	gorm.Model
+}
+
+// Vote marks a review as helpful to the user who cast it.
+type Vote struct {
+	ReviewID  uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"primaryKey"`
+	User      user.User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	CreatedAt time.Time
+}
+
+func (Vote) TableName() string {
+	return "review_votes"
+}
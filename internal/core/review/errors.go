@@ -0,0 +1,12 @@
+package review
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("review not found")
+	ErrBookNotFound    = errors.New("book not found")
+	ErrAlreadyReviewed = errors.New("book already reviewed by this user")
+	ErrNotOwner        = errors.New("review belongs to another user")
+	ErrOwnReview       = errors.New("users cannot vote on their own review")
+	ErrNotApproved     = errors.New("only approved reviews can be voted on")
+)
@@ -0,0 +1,31 @@
+package review
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"starter/internal/core/filter"
+	"starter/internal/core/pagination"
+)
+
+type Repository interface {
+	Save(db *gorm.DB, review *Review) error
+	Update(db *gorm.DB, review *Review) error
+	Delete(db *gorm.DB, id int, version uint) error
+	FindAll(db *gorm.DB, bookID int, params pagination.Request, filter filter.ReviewFilter) ([]Review, pagination.Meta, error)
+	FindByID(db *gorm.DB, id int) (Review, error)
+	FindByBookAndUser(db *gorm.DB, bookID int, userID uint) (Review, error)
+	BookExists(db *gorm.DB, id int) (bool, error)
+	AdjustRating(db *gorm.DB, bookID uint, count int, sum int) error
+	AddVote(db *gorm.DB, vote *Vote) error
+	RemoveVote(db *gorm.DB, reviewID uint, userID uint) error
+}
+
+type Usecase interface {
+	Save(ctx context.Context, request CreateRequest) (Response, error)
+	Update(ctx context.Context, request UpdateRequest) (*Response, error)
+	Moderate(ctx context.Context, request ModerateRequest) (*Response, error)
+	Delete(ctx context.Context, id int, version uint, moderator bool) error
+	FindAll(ctx context.Context, bookID int, request *pagination.Request, filter *filter.ReviewFilter) (pagination.Page[Response], error)
+	Vote(ctx context.Context, id int) (*Response, error)
+	Unvote(ctx context.Context, id int) (*Response, error)
+}
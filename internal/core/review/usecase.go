@@ -0,0 +1,406 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+	"starter/internal/core/audit"
+	"starter/internal/core/concurrency"
+	"starter/internal/core/filter"
+	"starter/internal/core/pagination"
+	ivalidator "starter/internal/core/validator"
+)
+
+var tracer = otel.Tracer("starter/internal/core/review")
+
+type UsecaseDependency struct {
+	DB               *gorm.DB
+	Validator        ivalidator.Validator
+	Audit            audit.Recorder
+	ReviewRepository Repository
+	// InitialStatus is what new and edited reviews start out as, pending
+	// keeps them out of listings and ratings until a moderator approves.
+	InitialStatus string
+}
+
+type UsecaseImpl struct {
+	UsecaseDependency
+}
+
+func NewUsecase(deps UsecaseDependency) Usecase {
+	return &UsecaseImpl{
+		deps,
+	}
+}
+
+func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Response, error) {
+	ctx, span := tracer.Start(ctx, "review.Usecase.Save")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return Response{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	exists, err := usecase.ReviewRepository.BookExists(tx, request.BookId)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find book by id: %d", request.BookId)
+		return Response{}, errors.New("something went wrong")
+	}
+	if !exists {
+		return Response{}, ErrBookNotFound
+	}
+
+	userID := audit.ActorFrom(ctx).UserID
+	_, err = usecase.ReviewRepository.FindByBookAndUser(tx, request.BookId, userID)
+	if err == nil {
+		return Response{}, ErrAlreadyReviewed
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find review of book %d", request.BookId)
+		return Response{}, errors.New("something went wrong")
+	}
+
+	review := request.ToEntity(userID, usecase.InitialStatus)
+	err = usecase.ReviewRepository.Save(tx, review)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save review")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	err = usecase.adjustRating(tx, nil, review)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update rating of book %d", review.BookID)
+		return Response{}, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionCreate,
+		EntityType: "review",
+		EntityID:   review.ID,
+		After:      ToResponse(review),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit review creation")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return Response{}, errors.New("something went wrong")
+	}
+
+	return *ToResponse(review), nil
+}
+
+// Update lets the author of a review change it. The edited review goes back to
+// the initial status, so a moderator sees it again when that is pending.
+func (usecase *UsecaseImpl) Update(ctx context.Context, request UpdateRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "review.Usecase.Update")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	review, err := usecase.findByID(ctx, tx, request.Id)
+	if err != nil {
+		return nil, err
+	}
+	if review.UserID != audit.ActorFrom(ctx).UserID {
+		return nil, ErrNotOwner
+	}
+
+	original := review
+	if request.Version != 0 {
+		review.Version = request.Version
+	}
+	review.Rating = request.Rating
+	review.Body = request.Body
+	review.Status = usecase.InitialStatus
+
+	return usecase.write(ctx, tx, &original, &review)
+}
+
+func (usecase *UsecaseImpl) Moderate(ctx context.Context, request ModerateRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "review.Usecase.Moderate")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	review, err := usecase.findByID(ctx, tx, request.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	original := review
+	if request.Version != 0 {
+		review.Version = request.Version
+	}
+	review.Status = request.Status
+
+	return usecase.write(ctx, tx, &original, &review)
+}
+
+// write stores the changes Update and Moderate made to review, moves its
+// rating in or out of the book aggregate and commits.
+func (usecase *UsecaseImpl) write(ctx context.Context, tx *gorm.DB, original *Review, review *Review) (*Response, error) {
+	err := usecase.ReviewRepository.Update(tx, review)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update review")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.adjustRating(tx, original, review)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update rating of book %d", review.BookID)
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "review",
+		EntityID:   review.ID,
+		Before:     ToResponse(original),
+		After:      ToResponse(review),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit review update")
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToResponse(review), nil
+}
+
+// Delete removes a review on behalf of its author, moderators may remove any.
+func (usecase *UsecaseImpl) Delete(ctx context.Context, id int, version uint, moderator bool) error {
+	ctx, span := tracer.Start(ctx, "review.Usecase.Delete")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	review, err := usecase.findByID(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if !moderator && review.UserID != audit.ActorFrom(ctx).UserID {
+		return ErrNotOwner
+	}
+
+	// The delete is always checked against the version read above, so that
+	// of two concurrent deletes only one takes the review off the rating.
+	expected := version
+	if expected == 0 {
+		expected = review.Version
+	}
+	err = usecase.ReviewRepository.Delete(tx, id, expected)
+	if errors.Is(err, concurrency.ErrVersionMismatch) && version == 0 {
+		return ErrNotFound
+	}
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to delete review")
+		return errors.New("something went wrong")
+	}
+
+	err = usecase.adjustRating(tx, &review, nil)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update rating of book %d", review.BookID)
+		return errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionDelete,
+		EntityType: "review",
+		EntityID:   review.ID,
+		Before:     ToResponse(&review),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit review deletion")
+		return errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return errors.New("something went wrong")
+	}
+	return nil
+}
+
+func (usecase *UsecaseImpl) FindAll(ctx context.Context, bookID int, request *pagination.Request, filter *filter.ReviewFilter) (pagination.Page[Response], error) {
+	ctx, span := tracer.Start(ctx, "review.Usecase.FindAll")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	// The newest reviews come first unless asked otherwise.
+	if request.Sort == "" && request.OrderBy == "" && request.SortBy == "" {
+		request.Sort = "-created_at"
+	}
+	pagination.NewPagination(request)
+
+	validation := usecase.Validator.ValidateStruct(request)
+	validation = append(validation, usecase.Validator.ValidateStruct(filter)...)
+	validation = append(validation, request.ParseSort(SortableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return pagination.Page[Response]{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	reviews, meta, err := usecase.ReviewRepository.FindAll(tx, bookID, *request, *filter)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return pagination.Page[Response]{}, pagination.CursorError()
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to fetch reviews of book %d", bookID)
+		return pagination.Page[Response]{}, errors.New("something went wrong")
+	}
+
+	var response []Response
+	for _, review := range reviews {
+		response = append(response, *ToResponse(&review))
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return pagination.Page[Response]{}, errors.New("something went wrong")
+	}
+
+	return *pagination.NewPage[Response](*request, meta, response), nil
+}
+
+// Vote marks an approved review as helpful to the caller. Voting twice is a
+// no-op.
+func (usecase *UsecaseImpl) Vote(ctx context.Context, id int) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "review.Usecase.Vote")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	review, err := usecase.findByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := audit.ActorFrom(ctx).UserID
+	if review.UserID == userID {
+		return nil, ErrOwnReview
+	}
+	if review.Status != StatusApproved {
+		return nil, ErrNotApproved
+	}
+
+	err = usecase.ReviewRepository.AddVote(tx, &Vote{ReviewID: review.ID, UserID: userID})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to vote on review %d", id)
+		return nil, errors.New("something went wrong")
+	}
+
+	return usecase.reload(ctx, tx, id)
+}
+
+// Unvote takes the caller's helpful vote back, if there was one.
+func (usecase *UsecaseImpl) Unvote(ctx context.Context, id int) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "review.Usecase.Unvote")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	review, err := usecase.findByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = usecase.ReviewRepository.RemoveVote(tx, review.ID, audit.ActorFrom(ctx).UserID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to remove vote on review %d", id)
+		return nil, errors.New("something went wrong")
+	}
+
+	return usecase.reload(ctx, tx, id)
+}
+
+// reload reads review id back with its current helpful count and commits.
+func (usecase *UsecaseImpl) reload(ctx context.Context, tx *gorm.DB, id int) (*Response, error) {
+	review, err := usecase.findByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToResponse(&review), nil
+}
+
+func (usecase *UsecaseImpl) findByID(ctx context.Context, tx *gorm.DB, id int) (Review, error) {
+	review, err := usecase.ReviewRepository.FindByID(tx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return review, ErrNotFound
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find review by id: %d", id)
+		return review, errors.New("something went wrong")
+	}
+	return review, nil
+}
+
+// adjustRating moves the book aggregate from what before contributed to what
+// after contributes, either may be nil. Only approved reviews count.
+func (usecase *UsecaseImpl) adjustRating(tx *gorm.DB, before *Review, after *Review) error {
+	count, sum := 0, 0
+	bookID := uint(0)
+	if before != nil && before.Counted() {
+		count, sum = count-1, sum-before.Rating
+		bookID = before.BookID
+	}
+	if after != nil && after.Counted() {
+		count, sum = count+1, sum+after.Rating
+		bookID = after.BookID
+	}
+	if count == 0 && sum == 0 {
+		return nil
+	}
+
+	return usecase.ReviewRepository.AdjustRating(tx, bookID, count, sum)
+}
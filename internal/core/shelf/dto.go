@@ -0,0 +1,128 @@
+package shelf
+
+import (
+	"starter/internal/core/pagination"
+	"time"
+)
+
+const (
+	KindWantToRead = "want_to_read"
+	KindReading    = "reading"
+	KindRead       = "read"
+	KindCustom     = "custom"
+)
+
+const (
+	VisibilityPrivate = "private"
+	VisibilityLink    = "link"
+	VisibilityPublic  = "public"
+)
+
+// Defaults are the shelves every user starts with, they are created the
+// first time the user lists their shelves.
+var Defaults = []Shelf{
+	{Kind: KindWantToRead, Name: "Want to read"},
+	{Kind: KindReading, Name: "Reading"},
+	{Kind: KindRead, Name: "Read"},
+}
+
+// SortableFields lists what the shelf lists accept in the sort parameter.
+var SortableFields = pagination.Sortable{
+	"id":         "shelves.id",
+	"name":       "shelves.name",
+	"created_at": "shelves.created_at",
+	"updated_at": "shelves.updated_at",
+}
+
+type CreateRequest struct {
+	Name       string `json:"name" validate:"required,max=100"`
+	Visibility string `json:"visibility" validate:"omitempty,oneof=private link public"`
+}
+
+type UpdateRequest struct {
+	Id         int    `json:"-"`
+	Name       string `json:"name" validate:"required,max=100"`
+	Visibility string `json:"visibility" validate:"required,oneof=private link public"`
+	Version    uint   `json:"-"`
+}
+
+// ItemRequest adds a book to a shelf or changes its entry. A zero Position
+// appends the book, or leaves it where it is when it is on the shelf already.
+type ItemRequest struct {
+	ShelfId  int    `json:"-"`
+	BookId   int    `json:"book_id" validate:"required,min=1"`
+	Position int    `json:"position" validate:"min=0"`
+	Note     string `json:"note" validate:"max=1000"`
+}
+
+type ItemResponse struct {
+	BookId   int       `json:"book_id"`
+	Title    string    `json:"title"`
+	Cover    string    `json:"cover"`
+	Position int       `json:"position"`
+	Note     string    `json:"note"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// Response is a shelf as its owner sees it, ToPublicResponse leaves the share
+// token out for everyone else.
+type Response struct {
+	Id         int            `json:"id"`
+	UserId     int            `json:"user_id"`
+	Name       string         `json:"name"`
+	Kind       string         `json:"kind"`
+	Visibility string         `json:"visibility"`
+	ShareToken string         `json:"share_token,omitempty"`
+	Items      []ItemResponse `json:"items,omitempty"`
+	Version    uint           `json:"version"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+func (dto *CreateRequest) ToEntity(userID uint, token string) *Shelf {
+	visibility := dto.Visibility
+	if visibility == "" {
+		visibility = VisibilityPrivate
+	}
+
+	return &Shelf{
+		UserID:     userID,
+		Name:       dto.Name,
+		Kind:       KindCustom,
+		Visibility: visibility,
+		ShareToken: token,
+	}
+}
+
+func ToResponse(entity *Shelf) *Response {
+	var items []ItemResponse
+	for _, item := range entity.Items {
+		items = append(items, ItemResponse{
+			BookId:   int(item.BookID),
+			Title:    item.Book.Title,
+			Cover:    item.Book.Cover,
+			Position: item.Position,
+			Note:     item.Note,
+			AddedAt:  item.CreatedAt,
+		})
+	}
+
+	return &Response{
+		Id:         int(entity.ID),
+		UserId:     int(entity.UserID),
+		Name:       entity.Name,
+		Kind:       entity.Kind,
+		Visibility: entity.Visibility,
+		ShareToken: entity.ShareToken,
+		Items:      items,
+		Version:    entity.Version,
+		CreatedAt:  entity.CreatedAt,
+		UpdatedAt:  entity.UpdatedAt,
+	}
+}
+
+func ToPublicResponse(entity *Shelf) *Response {
+	response := ToResponse(entity)
+	response.ShareToken = ""
+	return response
+}
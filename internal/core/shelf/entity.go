@@ -0,0 +1,39 @@
+package shelf
+
+import (
+	"gorm.io/gorm"
+	"starter/internal/core/book"
+	"starter/internal/core/user"
+	"time"
+)
+
+// Shelf is a named list of books owned by a user. Every user has one shelf of
+// each default kind, the rest are custom. ShareToken is what link visibility
+// hands out, it is generated once per shelf.
+type Shelf struct {
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_shelves_user_name,where:deleted_at IS NULL;uniqueIndex:idx_shelves_user_kind,where:kind <> 'custom' AND deleted_at IS NULL"`
+	User       user.User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Name       string    `gorm:"size:100;not null;uniqueIndex:idx_shelves_user_name,where:deleted_at IS NULL"`
+	Kind       string    `gorm:"size:16;not null;uniqueIndex:idx_shelves_user_kind,where:kind <> 'custom' AND deleted_at IS NULL"`
+	Visibility string    `gorm:"size:16;not null;index"`
+	ShareToken string    `gorm:"size:64;not null;uniqueIndex"`
+	Items      []Item    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Version    uint      `gorm:"not null;default:1"`
+	gorm.Model
+}
+
+// Item is a book on a shelf. Positions run from 1 without gaps.
+type Item struct {
+	ID        uint      `gorm:"primaryKey"`
+	ShelfID   uint      `gorm:"not null;uniqueIndex:idx_shelf_items_shelf_book"`
+	BookID    uint      `gorm:"not null;uniqueIndex:idx_shelf_items_shelf_book;index"`
+	Book      book.Book `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Position  int       `gorm:"not null"`
+	Note      string    `gorm:"size:1000"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (Item) TableName() string {
+	return "shelf_items"
+}
@@ -0,0 +1,11 @@
+package shelf
+
+import "errors"
+
+var (
+	ErrNotFound     = errors.New("shelf not found")
+	ErrBookNotFound = errors.New("book not found")
+	ErrItemNotFound = errors.New("book is not on the shelf")
+	ErrAlreadyOnIt  = errors.New("book is already on the shelf")
+	ErrDefaultShelf = errors.New("default shelves cannot be deleted")
+)
@@ -0,0 +1,38 @@
+package shelf
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"starter/internal/core/filter"
+	"starter/internal/core/pagination"
+)
+
+type Repository interface {
+	Save(db *gorm.DB, shelf *Shelf) error
+	Update(db *gorm.DB, shelf *Shelf) error
+	Delete(db *gorm.DB, id int, version uint) error
+	EnsureDefaults(db *gorm.DB, userID uint, shelves []Shelf) error
+	FindAll(db *gorm.DB, params pagination.Request, filter filter.ShelfFilter) ([]Shelf, pagination.Meta, error)
+	FindByID(db *gorm.DB, id int) (Shelf, error)
+	FindByToken(db *gorm.DB, token string) (Shelf, error)
+	NameTaken(db *gorm.DB, userID uint, name string, except uint) (bool, error)
+	BookExists(db *gorm.DB, id int) (bool, error)
+	FindItem(db *gorm.DB, shelfID uint, bookID uint) (Item, error)
+	SaveItem(db *gorm.DB, item *Item) error
+	UpdateItem(db *gorm.DB, item *Item, position int) error
+	DeleteItem(db *gorm.DB, item *Item) error
+}
+
+type Usecase interface {
+	Save(ctx context.Context, request CreateRequest) (Response, error)
+	Update(ctx context.Context, request UpdateRequest) (*Response, error)
+	Delete(ctx context.Context, id int, version uint) error
+	FindAll(ctx context.Context, request *pagination.Request) (pagination.Page[Response], error)
+	FindById(ctx context.Context, id int) (*Response, error)
+	SaveItem(ctx context.Context, request ItemRequest) (*Response, error)
+	UpdateItem(ctx context.Context, request ItemRequest) (*Response, error)
+	DeleteItem(ctx context.Context, shelfID int, bookID int) (*Response, error)
+	FindPublic(ctx context.Context, request *pagination.Request, filter *filter.ShelfFilter) (pagination.Page[Response], error)
+	FindPublicById(ctx context.Context, id int) (*Response, error)
+	FindShared(ctx context.Context, token string) (*Response, error)
+}
@@ -0,0 +1,580 @@
+package shelf
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+	"starter/internal/core/audit"
+	"starter/internal/core/concurrency"
+	"starter/internal/core/filter"
+	"starter/internal/core/pagination"
+	"starter/internal/core/storage"
+	ivalidator "starter/internal/core/validator"
+)
+
+var tracer = otel.Tracer("starter/internal/core/shelf")
+
+type UsecaseDependency struct {
+	DB              *gorm.DB
+	Validator       ivalidator.Validator
+	Storage         storage.Storage
+	Audit           audit.Recorder
+	ShelfRepository Repository
+}
+
+type UsecaseImpl struct {
+	UsecaseDependency
+}
+
+func NewUsecase(deps UsecaseDependency) Usecase {
+	return &UsecaseImpl{
+		deps,
+	}
+}
+
+func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Response, error) {
+	ctx, span := tracer.Start(ctx, "shelf.Usecase.Save")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	var err error
+	userID := audit.ActorFrom(ctx).UserID
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation == nil {
+		validation, err = usecase.validateName(ctx, tx, userID, request.Name, 0)
+		if err != nil {
+			return Response{}, err
+		}
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return Response{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	// The defaults go in first so that a custom shelf can't take their names.
+	if err = usecase.ensureDefaults(ctx, tx, userID); err != nil {
+		return Response{}, err
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to generate share token")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	shelf := request.ToEntity(userID, token)
+	err = usecase.ShelfRepository.Save(tx, shelf)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save shelf")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionCreate,
+		EntityType: "shelf",
+		EntityID:   shelf.ID,
+		After:      ToResponse(shelf),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit shelf creation")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return Response{}, errors.New("something went wrong")
+	}
+
+	return *ToResponse(shelf), nil
+}
+
+func (usecase *UsecaseImpl) Update(ctx context.Context, request UpdateRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "shelf.Usecase.Update")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	shelf, err := usecase.owned(ctx, tx, request.Id)
+	if err != nil {
+		return nil, err
+	}
+	validation, err = usecase.validateName(ctx, tx, shelf.UserID, request.Name, shelf.ID)
+	if err != nil {
+		return nil, err
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	before := ToResponse(&shelf)
+	if request.Version != 0 {
+		shelf.Version = request.Version
+	}
+	shelf.Name = request.Name
+	shelf.Visibility = request.Visibility
+
+	err = usecase.ShelfRepository.Update(tx, &shelf)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update shelf")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "shelf",
+		EntityID:   shelf.ID,
+		Before:     before,
+		After:      ToResponse(&shelf),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit shelf update")
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	usecase.covers(ctx, &shelf)
+	return ToResponse(&shelf), nil
+}
+
+func (usecase *UsecaseImpl) Delete(ctx context.Context, id int, version uint) error {
+	ctx, span := tracer.Start(ctx, "shelf.Usecase.Delete")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	shelf, err := usecase.owned(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if shelf.Kind != KindCustom {
+		return ErrDefaultShelf
+	}
+
+	err = usecase.ShelfRepository.Delete(tx, id, version)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to delete shelf")
+		return errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionDelete,
+		EntityType: "shelf",
+		EntityID:   shelf.ID,
+		Before:     ToResponse(&shelf),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit shelf deletion")
+		return errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return errors.New("something went wrong")
+	}
+	return nil
+}
+
+// FindAll lists the caller's own shelves, creating the default ones on the
+// first call.
+func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Request) (pagination.Page[Response], error) {
+	ctx, span := tracer.Start(ctx, "shelf.Usecase.FindAll")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	userID := audit.ActorFrom(ctx).UserID
+	if err := usecase.ensureDefaults(ctx, tx, userID); err != nil {
+		return pagination.Page[Response]{}, err
+	}
+
+	return usecase.findAll(ctx, tx, request, &filter.ShelfFilter{UserID: userID}, ToResponse)
+}
+
+// FindPublic lists the public shelves of every user, or of one user when the
+// filter names them.
+func (usecase *UsecaseImpl) FindPublic(ctx context.Context, request *pagination.Request, query *filter.ShelfFilter) (pagination.Page[Response], error) {
+	ctx, span := tracer.Start(ctx, "shelf.Usecase.FindPublic")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	query.Visibility = VisibilityPublic
+	return usecase.findAll(ctx, tx, request, query, ToPublicResponse)
+}
+
+func (usecase *UsecaseImpl) findAll(ctx context.Context, tx *gorm.DB, request *pagination.Request, query *filter.ShelfFilter, respond func(*Shelf) *Response) (pagination.Page[Response], error) {
+	pagination.NewPagination(request)
+
+	validation := usecase.Validator.ValidateStruct(request)
+	validation = append(validation, usecase.Validator.ValidateStruct(query)...)
+	validation = append(validation, request.ParseSort(SortableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return pagination.Page[Response]{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	shelves, meta, err := usecase.ShelfRepository.FindAll(tx, *request, *query)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return pagination.Page[Response]{}, pagination.CursorError()
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to fetch shelves")
+		return pagination.Page[Response]{}, errors.New("something went wrong")
+	}
+
+	var response []Response
+	for _, shelf := range shelves {
+		response = append(response, *respond(&shelf))
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return pagination.Page[Response]{}, errors.New("something went wrong")
+	}
+
+	return *pagination.NewPage[Response](*request, meta, response), nil
+}
+
+func (usecase *UsecaseImpl) FindById(ctx context.Context, id int) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "shelf.Usecase.FindById")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	shelf, err := usecase.owned(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	usecase.covers(ctx, &shelf)
+	return ToResponse(&shelf), nil
+}
+
+func (usecase *UsecaseImpl) FindPublicById(ctx context.Context, id int) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "shelf.Usecase.FindPublicById")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	shelf, err := usecase.findByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if shelf.Visibility != VisibilityPublic {
+		return nil, ErrNotFound
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	usecase.covers(ctx, &shelf)
+	return ToPublicResponse(&shelf), nil
+}
+
+// FindShared opens a shelf through its share link. Making a shelf private
+// again turns the link off.
+func (usecase *UsecaseImpl) FindShared(ctx context.Context, token string) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "shelf.Usecase.FindShared")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	shelf, err := usecase.ShelfRepository.FindByToken(tx, token)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find shared shelf")
+		return nil, errors.New("something went wrong")
+	}
+	if shelf.Visibility == VisibilityPrivate {
+		return nil, ErrNotFound
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	usecase.covers(ctx, &shelf)
+	return ToPublicResponse(&shelf), nil
+}
+
+func (usecase *UsecaseImpl) SaveItem(ctx context.Context, request ItemRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "shelf.Usecase.SaveItem")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	shelf, err := usecase.owned(ctx, tx, request.ShelfId)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := usecase.ShelfRepository.BookExists(tx, request.BookId)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find book by id: %d", request.BookId)
+		return nil, errors.New("something went wrong")
+	}
+	if !exists {
+		return nil, ErrBookNotFound
+	}
+
+	_, err = usecase.ShelfRepository.FindItem(tx, shelf.ID, uint(request.BookId))
+	if err == nil {
+		return nil, ErrAlreadyOnIt
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find shelf item")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.ShelfRepository.SaveItem(tx, &Item{
+		ShelfID:  shelf.ID,
+		BookID:   uint(request.BookId),
+		Position: request.Position,
+		Note:     request.Note,
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to add book to shelf")
+		return nil, errors.New("something went wrong")
+	}
+
+	return usecase.itemsChanged(ctx, tx, &shelf)
+}
+
+func (usecase *UsecaseImpl) UpdateItem(ctx context.Context, request ItemRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "shelf.Usecase.UpdateItem")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	shelf, err := usecase.owned(ctx, tx, request.ShelfId)
+	if err != nil {
+		return nil, err
+	}
+	item, err := usecase.findItem(ctx, tx, shelf.ID, request.BookId)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Note = request.Note
+	err = usecase.ShelfRepository.UpdateItem(tx, &item, request.Position)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update shelf item")
+		return nil, errors.New("something went wrong")
+	}
+
+	return usecase.itemsChanged(ctx, tx, &shelf)
+}
+
+func (usecase *UsecaseImpl) DeleteItem(ctx context.Context, shelfID int, bookID int) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "shelf.Usecase.DeleteItem")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	shelf, err := usecase.owned(ctx, tx, shelfID)
+	if err != nil {
+		return nil, err
+	}
+	item, err := usecase.findItem(ctx, tx, shelf.ID, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = usecase.ShelfRepository.DeleteItem(tx, &item)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to remove book from shelf")
+		return nil, errors.New("something went wrong")
+	}
+
+	return usecase.itemsChanged(ctx, tx, &shelf)
+}
+
+// itemsChanged audits an item write as an update of shelf, whose items are
+// the state before it, and commits.
+func (usecase *UsecaseImpl) itemsChanged(ctx context.Context, tx *gorm.DB, shelf *Shelf) (*Response, error) {
+	current, err := usecase.findByID(ctx, tx, int(shelf.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "shelf",
+		EntityID:   shelf.ID,
+		Before:     ToResponse(shelf),
+		After:      ToResponse(&current),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit shelf items")
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	usecase.covers(ctx, &current)
+	return ToResponse(&current), nil
+}
+
+func (usecase *UsecaseImpl) findByID(ctx context.Context, tx *gorm.DB, id int) (Shelf, error) {
+	shelf, err := usecase.ShelfRepository.FindByID(tx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return shelf, ErrNotFound
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find shelf by id: %d", id)
+		return shelf, errors.New("something went wrong")
+	}
+	return shelf, nil
+}
+
+// owned loads shelf id for its owner. Shelves of other users are reported as
+// missing, whatever their visibility.
+func (usecase *UsecaseImpl) owned(ctx context.Context, tx *gorm.DB, id int) (Shelf, error) {
+	shelf, err := usecase.findByID(ctx, tx, id)
+	if err != nil {
+		return shelf, err
+	}
+	if shelf.UserID != audit.ActorFrom(ctx).UserID {
+		return Shelf{}, ErrNotFound
+	}
+	return shelf, nil
+}
+
+func (usecase *UsecaseImpl) findItem(ctx context.Context, tx *gorm.DB, shelfID uint, bookID int) (Item, error) {
+	item, err := usecase.ShelfRepository.FindItem(tx, shelfID, uint(bookID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return item, ErrItemNotFound
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find shelf item")
+		return item, errors.New("something went wrong")
+	}
+	return item, nil
+}
+
+// validateName checks that no other live shelf of the user, except, is called
+// name already.
+func (usecase *UsecaseImpl) validateName(ctx context.Context, tx *gorm.DB, userID uint, name string, except uint) ([]ivalidator.ValidationError, error) {
+	taken, err := usecase.ShelfRepository.NameTaken(tx, userID, name, except)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to check shelf name")
+		return nil, errors.New("something went wrong")
+	}
+	if !taken {
+		return nil, nil
+	}
+
+	return []ivalidator.ValidationError{{
+		Field:   "name",
+		Message: "name is already used by another shelf",
+	}}, nil
+}
+
+func (usecase *UsecaseImpl) ensureDefaults(ctx context.Context, tx *gorm.DB, userID uint) error {
+	shelves := make([]Shelf, 0, len(Defaults))
+	for _, shelf := range Defaults {
+		token, err := newShareToken()
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to generate share token")
+			return errors.New("something went wrong")
+		}
+		shelf.UserID = userID
+		shelf.Visibility = VisibilityPrivate
+		shelf.ShareToken = token
+		shelves = append(shelves, shelf)
+	}
+
+	if err := usecase.ShelfRepository.EnsureDefaults(tx, userID, shelves); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to create default shelves")
+		return errors.New("something went wrong")
+	}
+	return nil
+}
+
+// covers swaps the stored cover names of the books on shelf for links.
+func (usecase *UsecaseImpl) covers(ctx context.Context, shelf *Shelf) {
+	for i, item := range shelf.Items {
+		cover := usecase.Storage.Download(ctx, storage.DownloadRequest{
+			Bucket: "bucket",
+			Name:   item.Book.Cover,
+		})
+
+		shelf.Items[i].Book.Cover = ""
+		if cover != nil {
+			shelf.Items[i].Book.Cover = cover.Link
+		}
+	}
+}
+
+func newShareToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
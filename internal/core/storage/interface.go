@@ -6,4 +6,5 @@ type Storage interface {
 	Upload(ctx context.Context, file UploadRequest) *Response
 	Download(ctx context.Context, file DownloadRequest) *Response
 	Delete(ctx context.Context, bucket string, name string) *Response
+	Ping(ctx context.Context) error
 }
@@ -0,0 +1,68 @@
+package tag
+
+const (
+	DefaultLimit      = 10
+	DefaultCloudLimit = 50
+)
+
+type CreateRequest struct {
+	Name string `json:"name" validate:"required,max=200"`
+	Kind string `json:"kind" validate:"omitempty,oneof=tag subject"`
+}
+
+// SearchRequest completes Prefix against the slugs of the tags, the most
+// used ones first.
+type SearchRequest struct {
+	Prefix string `json:"prefix" validate:"max=200"`
+	Kind   string `json:"kind" validate:"omitempty,oneof=tag subject"`
+	Limit  int    `json:"limit" validate:"omitempty,min=1,max=50"`
+}
+
+// CloudRequest asks for the most used tags of a kind with their counts.
+type CloudRequest struct {
+	Kind  string `json:"kind" validate:"omitempty,oneof=tag subject"`
+	Limit int    `json:"limit" validate:"omitempty,min=1,max=200"`
+}
+
+type Response struct {
+	Id    uint   `json:"id"`
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Kind  string `json:"kind"`
+	Books int64  `json:"books"`
+}
+
+// Count is a tag along with the number of live books carrying it.
+type Count struct {
+	Tag
+	Books int64
+}
+
+func (dto *CreateRequest) ToEntity() *Tag {
+	kind := dto.Kind
+	if kind == "" {
+		kind = KindTag
+	}
+	name := Normalize(kind, dto.Name)
+
+	return &Tag{
+		Name: name,
+		Slug: Slug(name),
+		Kind: kind,
+	}
+}
+
+func ToResponse(entity *Tag) *Response {
+	return &Response{
+		Id:   entity.ID,
+		Name: entity.Name,
+		Slug: entity.Slug,
+		Kind: entity.Kind,
+	}
+}
+
+func ToCountResponse(entity *Count) *Response {
+	response := ToResponse(&entity.Tag)
+	response.Books = entity.Books
+	return response
+}
@@ -0,0 +1,19 @@
+package tag
+
+import "time"
+
+const (
+	KindTag     = "tag"
+	KindSubject = "subject"
+)
+
+// Tag is either a free-form label anyone can put on a book or a subject
+// heading from the controlled vocabulary admins maintain. Slug is the
+// normalised name, unique within a kind.
+type Tag struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"size:200;not null"`
+	Slug      string `gorm:"size:200;not null;uniqueIndex:idx_tags_kind_slug,priority:2"`
+	Kind      string `gorm:"size:16;not null;uniqueIndex:idx_tags_kind_slug,priority:1"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,5 @@
+package tag
+
+import "errors"
+
+var ErrNotFound = errors.New("tag not found")
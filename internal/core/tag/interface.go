@@ -0,0 +1,22 @@
+package tag
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Save(db *gorm.DB, tag *Tag) error
+	Delete(db *gorm.DB, id uint) error
+	FindByID(db *gorm.DB, id uint) (Tag, error)
+	FindBySlug(db *gorm.DB, kind string, slug string) (Tag, error)
+	Search(db *gorm.DB, kind string, prefix string, limit int) ([]Count, error)
+	Cloud(db *gorm.DB, kind string, limit int) ([]Count, error)
+}
+
+type Usecase interface {
+	Save(ctx context.Context, request CreateRequest) (Response, error)
+	Delete(ctx context.Context, id uint) error
+	Search(ctx context.Context, request SearchRequest) ([]Response, error)
+	Cloud(ctx context.Context, request CloudRequest) ([]Response, error)
+}
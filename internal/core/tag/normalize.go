@@ -0,0 +1,60 @@
+package tag
+
+import (
+	"starter/pkg/helper"
+	"strings"
+	"unicode"
+)
+
+// Normalize tidies name up the way it is stored: surrounding and repeated
+// whitespace goes, and the subdivisions of a subject heading are separated
+// by " -- " whether they came in as "--" or as an em dash.
+func Normalize(kind string, name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if kind != KindSubject {
+		return name
+	}
+
+	var parts []string
+	for _, part := range strings.Split(strings.ReplaceAll(name, "—", "--"), "--") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " -- ")
+}
+
+// Slug is the form names are matched by: lowercase, without accents, and
+// with every run of anything but letters and digits turned into a hyphen.
+func Slug(name string) string {
+	var slug strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(helper.StripAccents(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		hyphen = true
+	}
+	return slug.String()
+}
+
+// Slugs turns names into their slugs, dropping the ones left empty and the
+// repeated ones.
+func Slugs(names []string) []string {
+	var slugs []string
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		slug := Slug(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		slugs = append(slugs, slug)
+	}
+	return slugs
+}
@@ -0,0 +1,208 @@
+package tag
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+	"starter/internal/core/audit"
+	ivalidator "starter/internal/core/validator"
+)
+
+var tracer = otel.Tracer("starter/internal/core/tag")
+
+type UsecaseDependency struct {
+	DB            *gorm.DB
+	Validator     ivalidator.Validator
+	Audit         audit.Recorder
+	TagRepository Repository
+}
+
+type UsecaseImpl struct {
+	UsecaseDependency
+}
+
+func NewUsecase(deps UsecaseDependency) Usecase {
+	return &UsecaseImpl{
+		deps,
+	}
+}
+
+// Save adds a tag ahead of its first use, which is how subject headings get
+// into the vocabulary. Free-form tags are also created on the fly by books.
+func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Response, error) {
+	ctx, span := tracer.Start(ctx, "tag.Usecase.Save")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	tag := request.ToEntity()
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation == nil && tag.Slug == "" {
+		validation = append(validation, ivalidator.ValidationError{
+			Field:   "name",
+			Message: "name must contain letters or digits",
+		})
+	}
+	if validation == nil {
+		_, err := usecase.TagRepository.FindBySlug(tx, tag.Kind, tag.Slug)
+		if err == nil {
+			validation = append(validation, ivalidator.ValidationError{
+				Field:   "name",
+				Message: "name is already taken",
+			})
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to look up tag %s", tag.Slug)
+			return Response{}, errors.New("something went wrong")
+		}
+	}
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return Response{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	err := usecase.TagRepository.Save(tx, tag)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save tag")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionCreate,
+		EntityType: "tag",
+		EntityID:   tag.ID,
+		After:      ToResponse(tag),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit tag creation")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return Response{}, errors.New("something went wrong")
+	}
+
+	return *ToResponse(tag), nil
+}
+
+// Delete removes the tag from the vocabulary and from every book carrying it.
+func (usecase *UsecaseImpl) Delete(ctx context.Context, id uint) error {
+	ctx, span := tracer.Start(ctx, "tag.Usecase.Delete")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	tag, err := usecase.TagRepository.FindByID(tx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find tag by id: %d", id)
+		return errors.New("something went wrong")
+	}
+
+	err = usecase.TagRepository.Delete(tx, id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to delete tag")
+		return errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionDelete,
+		EntityType: "tag",
+		EntityID:   tag.ID,
+		Before:     ToResponse(&tag),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit tag deletion")
+		return errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return errors.New("something went wrong")
+	}
+
+	return nil
+}
+
+func (usecase *UsecaseImpl) Search(ctx context.Context, request SearchRequest) ([]Response, error) {
+	ctx, span := tracer.Start(ctx, "tag.Usecase.Search")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	if request.Limit == 0 {
+		request.Limit = DefaultLimit
+	}
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	counts, err := usecase.TagRepository.Search(tx, request.Kind, Slug(request.Prefix), request.Limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to search tags")
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return toCountResponses(counts), nil
+}
+
+// Cloud lists the most used tags of a kind, free-form tags unless asked
+// otherwise, with the number of books carrying each.
+func (usecase *UsecaseImpl) Cloud(ctx context.Context, request CloudRequest) ([]Response, error) {
+	ctx, span := tracer.Start(ctx, "tag.Usecase.Cloud")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	if request.Kind == "" {
+		request.Kind = KindTag
+	}
+	if request.Limit == 0 {
+		request.Limit = DefaultCloudLimit
+	}
+	validation := usecase.Validator.ValidateStruct(request)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	counts, err := usecase.TagRepository.Cloud(tx, request.Kind, request.Limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to count tags")
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return toCountResponses(counts), nil
+}
+
+func toCountResponses(counts []Count) []Response {
+	response := make([]Response, 0, len(counts))
+	for _, count := range counts {
+		response = append(response, *ToCountResponse(&count))
+	}
+	return response
+}
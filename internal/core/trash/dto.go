@@ -0,0 +1,101 @@
+package trash
+
+import (
+	"starter/internal/core/pagination"
+	"time"
+)
+
+// Reference is a column of Table pointing at another table by id.
+type Reference struct {
+	Table  string
+	Column string
+}
+
+// Kind describes a trashable table. References are rows elsewhere that point
+// at it and block a purge, Parents are the columns of this table pointing at
+// rows that must be active for a restore, Owned rows are purged along with it.
+type Kind struct {
+	EntityType string
+	Table      string
+	Label      string
+	AdminOnly  bool
+	References []Reference
+	Parents    []Reference
+	Owned      []Reference
+}
+
+var Kinds = map[string]Kind{
+	"books": {
+		EntityType: "book",
+		Table:      "books",
+		Label:      "books.title",
+		Parents: []Reference{
+			{Table: "authors", Column: "author_id"},
+			{Table: "publishers", Column: "publisher_id"},
+		},
+		Owned: []Reference{
+			{Table: "book_category", Column: "book_id"},
+			{Table: "book_tag", Column: "book_id"},
+			{Table: "book_subject", Column: "book_id"},
+			{Table: "book_localizations", Column: "book_id"},
+			{Table: "book_revisions", Column: "book_id"},
+		},
+	},
+	"authors": {
+		EntityType: "author",
+		Table:      "authors",
+		Label:      "concat_ws(' ', authors.first_name, authors.last_name)",
+		References: []Reference{{Table: "books", Column: "author_id"}},
+	},
+	"publishers": {
+		EntityType: "publisher",
+		Table:      "publishers",
+		Label:      "publishers.name",
+		References: []Reference{{Table: "books", Column: "publisher_id"}},
+	},
+	"categories": {
+		EntityType: "category",
+		Table:      "categories",
+		Label:      "categories.name",
+		References: []Reference{
+			{Table: "book_category", Column: "category_id"},
+			{Table: "categories", Column: "parent_id"},
+		},
+		Parents: []Reference{{Table: "categories", Column: "parent_id"}},
+	},
+	"users": {
+		EntityType: "user",
+		Table:      "users",
+		Label:      "users.email",
+		AdminOnly:  true,
+		References: []Reference{
+			{Table: "reviews", Column: "user_id"},
+			{Table: "review_votes", Column: "user_id"},
+			{Table: "shelves", Column: "user_id"},
+		},
+	},
+}
+
+func (kind Kind) SortableFields() pagination.Sortable {
+	return pagination.Sortable{
+		"id":         kind.Table + ".id",
+		"created_at": kind.Table + ".created_at",
+		"deleted_at": kind.Table + ".deleted_at",
+	}
+}
+
+type Response struct {
+	Id        int        `json:"id"`
+	Label     string     `json:"label"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+func ToResponse(entity *Item) *Response {
+	return &Response{
+		Id:        int(entity.ID),
+		Label:     entity.Label,
+		CreatedAt: entity.CreatedAt,
+		DeletedAt: entity.DeletedAt,
+	}
+}
@@ -0,0 +1,12 @@
+package trash
+
+import "time"
+
+// Item is a soft-deleted row of any trashable table. Label is whatever
+// identifies the row to a human, the title of a book or the name of an author.
+type Item struct {
+	ID        uint
+	Label     string
+	CreatedAt time.Time
+	DeletedAt *time.Time
+}
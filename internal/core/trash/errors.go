@@ -0,0 +1,32 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownEntity = errors.New("unknown trash entity")
+	ErrNotFound      = errors.New("no such item in the trash")
+)
+
+// ReferencedError refuses a purge while other rows still point at the item,
+// whether those rows are active or in the trash themselves.
+type ReferencedError struct {
+	Table string
+	Count int64
+}
+
+func (err ReferencedError) Error() string {
+	return fmt.Sprintf("still referenced by %d rows of %s", err.Count, err.Table)
+}
+
+// TrashedParentError refuses a restore while a row the item points at is in
+// the trash, the parent has to be restored first.
+type TrashedParentError struct {
+	Table string
+}
+
+func (err TrashedParentError) Error() string {
+	return fmt.Sprintf("the referenced row in %s is in the trash", err.Table)
+}
@@ -0,0 +1,22 @@
+package trash
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"starter/internal/core/pagination"
+)
+
+type Repository interface {
+	FindAll(db *gorm.DB, kind Kind, params pagination.Request) ([]Item, pagination.Meta, error)
+	FindByID(db *gorm.DB, kind Kind, id int) (Item, error)
+	CountReferences(db *gorm.DB, reference Reference, id int) (int64, error)
+	ParentTrashed(db *gorm.DB, kind Kind, parent Reference, id int) (bool, error)
+	Restore(db *gorm.DB, kind Kind, id int) error
+	Purge(db *gorm.DB, kind Kind, id int) error
+}
+
+type Usecase interface {
+	FindAll(ctx context.Context, entity string, request *pagination.Request) (pagination.Page[Response], error)
+	Restore(ctx context.Context, entity string, id int) (*Response, error)
+	Purge(ctx context.Context, entity string, id int) error
+}
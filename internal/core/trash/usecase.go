@@ -0,0 +1,205 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+	"starter/internal/core/audit"
+	"starter/internal/core/pagination"
+	ivalidator "starter/internal/core/validator"
+)
+
+var tracer = otel.Tracer("starter/internal/core/trash")
+
+type UsecaseDependency struct {
+	DB              *gorm.DB
+	Validator       ivalidator.Validator
+	Audit           audit.Recorder
+	TrashRepository Repository
+}
+
+type UsecaseImpl struct {
+	UsecaseDependency
+}
+
+func NewUsecase(deps UsecaseDependency) Usecase {
+	return &UsecaseImpl{
+		deps,
+	}
+}
+
+func (usecase *UsecaseImpl) FindAll(ctx context.Context, entity string, request *pagination.Request) (pagination.Page[Response], error) {
+	ctx, span := tracer.Start(ctx, "trash.Usecase.FindAll")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	kind, ok := Kinds[entity]
+	if !ok {
+		return pagination.Page[Response]{}, ErrUnknownEntity
+	}
+
+	// The most recently deleted rows come first unless asked otherwise.
+	if request.Sort == "" && request.OrderBy == "" && request.SortBy == "" {
+		request.Sort = "-deleted_at"
+	}
+	pagination.NewPagination(request)
+
+	validation := usecase.Validator.ValidateStruct(request)
+	validation = append(validation, request.ParseSort(kind.SortableFields())...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return pagination.Page[Response]{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	items, meta, err := usecase.TrashRepository.FindAll(tx, kind, *request)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return pagination.Page[Response]{}, pagination.CursorError()
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to fetch trashed %s", entity)
+		return pagination.Page[Response]{}, errors.New("something went wrong")
+	}
+
+	var response []Response
+	for _, item := range items {
+		response = append(response, *ToResponse(&item))
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return pagination.Page[Response]{}, errors.New("something went wrong")
+	}
+
+	return *pagination.NewPage[Response](*request, meta, response), nil
+}
+
+func (usecase *UsecaseImpl) Restore(ctx context.Context, entity string, id int) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "trash.Usecase.Restore")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	kind, ok := Kinds[entity]
+	if !ok {
+		return nil, ErrUnknownEntity
+	}
+
+	item, err := usecase.TrashRepository.FindByID(tx, kind, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find trashed %s with id: %d", entity, id)
+		return nil, errors.New("something went wrong")
+	}
+
+	for _, parent := range kind.Parents {
+		trashed, err := usecase.TrashRepository.ParentTrashed(tx, kind, parent, id)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to check %s of %s %d", parent.Table, entity, id)
+			return nil, errors.New("something went wrong")
+		}
+		if trashed {
+			return nil, TrashedParentError{Table: parent.Table}
+		}
+	}
+
+	err = usecase.TrashRepository.Restore(tx, kind, id)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Another live row took the name while this one was in the trash.
+		return nil, ivalidator.ValidationErrors{Errors: []ivalidator.ValidationError{{
+			Field:   "name",
+			Message: "name is already taken",
+		}}}
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to restore %s %d", entity, id)
+		return nil, errors.New("something went wrong")
+	}
+
+	restored := item
+	restored.DeletedAt = nil
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionRestore,
+		EntityType: kind.EntityType,
+		EntityID:   item.ID,
+		Before:     ToResponse(&item),
+		After:      ToResponse(&restored),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit %s restore", kind.EntityType)
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToResponse(&restored), nil
+}
+
+// Purge only removes rows that are already in the trash, anything still
+// referencing the row has to be purged or repointed first.
+func (usecase *UsecaseImpl) Purge(ctx context.Context, entity string, id int) error {
+	ctx, span := tracer.Start(ctx, "trash.Usecase.Purge")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	kind, ok := Kinds[entity]
+	if !ok {
+		return ErrUnknownEntity
+	}
+
+	item, err := usecase.TrashRepository.FindByID(tx, kind, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find trashed %s with id: %d", entity, id)
+		return errors.New("something went wrong")
+	}
+
+	for _, reference := range kind.References {
+		count, err := usecase.TrashRepository.CountReferences(tx, reference, id)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to count %s referencing %s %d", reference.Table, entity, id)
+			return errors.New("something went wrong")
+		}
+		if count > 0 {
+			return ReferencedError{Table: reference.Table, Count: count}
+		}
+	}
+
+	err = usecase.TrashRepository.Purge(tx, kind, id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to purge %s %d", entity, id)
+		return errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionPurge,
+		EntityType: kind.EntityType,
+		EntityID:   item.ID,
+		Before:     ToResponse(&item),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit %s purge", kind.EntityType)
+		return errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return errors.New("something went wrong")
+	}
+
+	return nil
+}
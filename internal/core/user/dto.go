@@ -1,6 +1,36 @@
 package user
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
+)
+
+// SortableFields lists what FindAll accepts in the sort parameter.
+var SortableFields = pagination.Sortable{
+	"id":         "users.id",
+	"name":       "users.name",
+	"email":      "users.email",
+	"created_at": "users.created_at",
+	"updated_at": "users.updated_at",
+}
+
+// ProjectableFields lists what read endpoints accept in fields and include.
+var ProjectableFields = projection.Projectable{
+	ID:      "users.id",
+	Version: "users.version",
+	Fields: map[string]string{
+		"name":  "users.name",
+		"email": "users.email",
+	},
+}
+
+// PatchableFields lists the keys PATCH accepts and the columns they write.
+var PatchableFields = patch.Fields{
+	"name":  "name",
+	"email": "email",
+}
 
 type CreateRequest struct {
 	Name     string `json:"name" validate:"required,max=100"`
@@ -9,14 +39,23 @@ type CreateRequest struct {
 }
 
 type UpdateRequest struct {
-	Id    int    `json:"id" validate:"required"`
-	Name  string `json:"name" validate:"max=100"`
-	Email string `json:"email" validate:"omitempty,email"`
+	Id      int    `json:"id" validate:"required"`
+	Name    string `json:"name" validate:"max=100"`
+	Email   string `json:"email" validate:"omitempty,email"`
+	Version uint   `json:"-"`
+}
+
+// PatchDocument is the user a merge patch is applied to. Its rules are the
+// ones a stored user has to satisfy, so anything optional can be cleared.
+type PatchDocument struct {
+	Name  string `json:"name" validate:"required,max=100"`
+	Email string `json:"email" validate:"required,email"`
 }
 
 type Response struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Version uint   `json:"version"`
 }
 
 func (dto *CreateRequest) ToEntity() *User {
@@ -35,9 +74,22 @@ func (dto *UpdateRequest) ToEntity() *User {
 	}
 }
 
-func ToResponse(entity *User) *Response {
-	return &Response{
+func ToPatchDocument(entity *User) *PatchDocument {
+	return &PatchDocument{
 		Name:  entity.Name,
 		Email: entity.Email,
 	}
 }
+
+func (dto *PatchDocument) Apply(entity *User) {
+	entity.Name = dto.Name
+	entity.Email = dto.Email
+}
+
+func ToResponse(entity *User) *Response {
+	return &Response{
+		Name:    entity.Name,
+		Email:   entity.Email,
+		Version: entity.Version,
+	}
+}
@@ -7,9 +7,10 @@ import (
 
 type User struct {
 	Name     string
-	Role     role.Role
-	Email    string `gorm:"unique"`
-	Password string `gorm:"min:8"`
+	Role     role.Role `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Email    string    `gorm:"unique"`
+	Password string    `gorm:"min:8"`
 	RoleID   uint
+	Version  uint `gorm:"not null;default:1"`
 	gorm.Model
 }
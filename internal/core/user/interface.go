@@ -4,22 +4,26 @@ import (
 	"context"
 	"gorm.io/gorm"
 	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
 )
 
 type Repository interface {
 	Save(db *gorm.DB, user *User) error
 	Update(db *gorm.DB, user *User) error
-	Delete(db *gorm.DB, id int) error
-	FindAll(db *gorm.DB, params pagination.Request) ([]User, int64, error)
-	FindByID(db *gorm.DB, id int) (User, error)
-	FindByEmail(db *gorm.DB, email string) (User, error)
+	Patch(db *gorm.DB, user *User, columns []string) error
+	Delete(db *gorm.DB, id int, version uint) error
+	FindAll(db *gorm.DB, params pagination.Request, view projection.Request) ([]User, pagination.Meta, error)
+	FindByID(db *gorm.DB, id int, view projection.Request) (User, error)
+	FindByEmail(db *gorm.DB, email string, view projection.Request) (User, error)
 }
 
 type Usecase interface {
 	Save(ctx context.Context, request CreateRequest) (Response, error)
 	Update(ctx context.Context, request UpdateRequest) (*Response, error)
-	Delete(ctx context.Context, id int) error
-	FindAll(ctx context.Context, request *pagination.Request) (pagination.Page[Response], error)
-	FindById(ctx context.Context, id int) (*Response, error)
-	FindByEmail(ctx context.Context, email string) (*Response, error)
+	Patch(ctx context.Context, request patch.Request) (*Response, error)
+	Delete(ctx context.Context, id int, version uint) error
+	FindAll(ctx context.Context, request *pagination.Request, view *projection.Request) (pagination.Page[Response], error)
+	FindById(ctx context.Context, id int, view *projection.Request) (*Response, error)
+	FindByEmail(ctx context.Context, email string, view *projection.Request) (*Response, error)
 }
@@ -4,18 +4,26 @@ import (
 	"context"
 	"errors"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
 	"gorm.io/gorm"
+	"starter/internal/core/audit"
+	"starter/internal/core/concurrency"
 	"starter/internal/core/pagination"
+	"starter/internal/core/patch"
+	"starter/internal/core/projection"
 	"starter/internal/core/role"
 	ivalidator "starter/internal/core/validator"
 	"starter/pkg/hasher"
 	"starter/pkg/helper"
 )
 
+var tracer = otel.Tracer("starter/internal/core/user")
+
 type UsecaseDependency struct {
 	DB             *gorm.DB
 	Hasher         hasher.Hasher
 	Validator      ivalidator.Validator
+	Audit          audit.Recorder
 	UserRepository Repository
 }
 
@@ -30,12 +38,15 @@ func NewUsecase(deps UsecaseDependency) Usecase {
 }
 
 func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Response, error) {
+	ctx, span := tracer.Start(ctx, "user.Usecase.Save")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	validation := usecase.Validator.ValidateStruct(request)
 	if validation != nil {
-		log.Error().Msgf("Validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
 		return Response{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
@@ -44,7 +55,7 @@ func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Re
 	user := request.ToEntity()
 	password, err := usecase.Hasher.Hash(user.Password)
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to secure password")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to secure password")
 		return Response{}, errors.New("something went wrong")
 	}
 	user.Password = password
@@ -53,8 +64,24 @@ func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Re
 	}
 
 	err = usecase.UserRepository.Save(tx, user)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to save user")
+		return Response{}, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionCreate,
+		EntityType: "user",
+		EntityID:   user.ID,
+		After:      ToResponse(user),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit user creation")
+		return Response{}, errors.New("something went wrong")
+	}
+
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return Response{}, errors.New("something went wrong")
 	}
 
@@ -62,72 +89,196 @@ func (usecase *UsecaseImpl) Save(ctx context.Context, request CreateRequest) (Re
 }
 
 func (usecase *UsecaseImpl) Update(ctx context.Context, request UpdateRequest) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "user.Usecase.Update")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	validation := usecase.Validator.ValidateStruct(request)
 	if validation != nil {
-		log.Error().Msgf("Validation error: %s", validation)
+		log.Ctx(ctx).Error().Msgf("Validation error: %s", validation)
 		return &Response{}, ivalidator.ValidationErrors{
 			Errors: validation,
 		}
 	}
 
-	user, err := usecase.UserRepository.FindByID(tx, request.Id)
+	user, err := usecase.UserRepository.FindByID(tx, request.Id, projection.Request{})
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to find user by id: %+v", request.Id)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find user by id: %+v", request.Id)
 		return nil, errors.New("user not found")
 	}
 	updated := helper.Differ(user, *request.ToEntity()).(User)
+	if request.Version != 0 {
+		updated.Version = request.Version
+	}
 
 	if updated.Password != "" {
 		password, err := usecase.Hasher.Hash(user.Password)
 		if err != nil {
-			log.Error().Err(err).Msgf("failed to secure password")
+			log.Ctx(ctx).Error().Err(err).Msgf("failed to secure password")
 			return &Response{}, errors.New("something went wrong")
 		}
 		user.Password = password
 	}
 
 	err = usecase.UserRepository.Update(tx, &updated)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update user")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "user",
+		EntityID:   updated.ID,
+		Before:     ToResponse(&user),
+		After:      ToResponse(&updated),
+	})
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to update user")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit user update")
 		return nil, errors.New("something went wrong")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return &Response{}, errors.New("something went wrong")
 	}
 	return ToResponse(&updated), nil
 }
 
-func (usecase *UsecaseImpl) Delete(ctx context.Context, id int) error {
+func (usecase *UsecaseImpl) Patch(ctx context.Context, request patch.Request) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "user.Usecase.Patch")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
-	err := usecase.UserRepository.Delete(tx, id)
+	user, err := usecase.UserRepository.FindByID(tx, request.Id, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find user by id: %+v", request.Id)
+		return nil, errors.New("user not found")
+	}
+	before := ToResponse(&user)
+	if request.Version != 0 {
+		user.Version = request.Version
+	}
+
+	document := ToPatchDocument(&user)
+	keys, err := patch.Apply(document, request.Patch)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to apply patch to user")
+		return nil, err
+	}
+
+	columns, validation := PatchableFields.Columns(keys)
+	validation = append(validation, usecase.Validator.ValidateStruct(document)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+	document.Apply(&user)
+
+	err = usecase.UserRepository.Patch(tx, &user, columns)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to patch user")
+		return nil, errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionUpdate,
+		EntityType: "user",
+		EntityID:   user.ID,
+		Before:     before,
+		After:      ToResponse(&user),
+	})
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to update user")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit user patch")
+		return nil, errors.New("something went wrong")
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		return nil, errors.New("something went wrong")
+	}
+	return ToResponse(&user), nil
+}
+
+func (usecase *UsecaseImpl) Delete(ctx context.Context, id int, version uint) error {
+	ctx, span := tracer.Start(ctx, "user.Usecase.Delete")
+	defer span.End()
+
+	tx := usecase.DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+
+	user, err := usecase.UserRepository.FindByID(tx, id, projection.Request{})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find user by id: %+v", id)
+		return errors.New("user not found")
+	}
+
+	err = usecase.UserRepository.Delete(tx, id, version)
+	if errors.Is(err, concurrency.ErrVersionMismatch) {
+		return err
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to update user")
+		return errors.New("something went wrong")
+	}
+
+	err = usecase.Audit.Record(ctx, tx, audit.Change{
+		Action:     audit.ActionDelete,
+		EntityType: "user",
+		EntityID:   user.ID,
+		Before:     ToResponse(&user),
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to audit user deletion")
 		return errors.New("something went wrong")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return errors.New("something went wrong")
 	}
 
 	return nil
 }
 
-func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Request) (pagination.Page[Response], error) {
+func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Request, view *projection.Request) (pagination.Page[Response], error) {
+	ctx, span := tracer.Start(ctx, "user.Usecase.FindAll")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
 	pagination.NewPagination(request)
-	users, count, err := usecase.UserRepository.FindAll(tx, *request)
+
+	validation := usecase.Validator.ValidateStruct(request)
+	validation = append(validation, request.ParseSort(SortableFields)...)
+	validation = append(validation, usecase.Validator.ValidateStruct(view)...)
+	validation = append(validation, view.Parse(ProjectableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return pagination.Page[Response]{}, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	users, meta, err := usecase.UserRepository.FindAll(tx, *request, *view)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return pagination.Page[Response]{}, pagination.CursorError()
+	}
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to fetch users")
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to fetch users")
 		return pagination.Page[Response]{}, errors.New("something went wrong")
 	}
 
@@ -137,42 +288,66 @@ func (usecase *UsecaseImpl) FindAll(ctx context.Context, request *pagination.Req
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return pagination.Page[Response]{}, errors.New("something went wrong")
 	}
 
-	return *pagination.NewPage[Response](*request, count, response), nil
+	return *pagination.NewPage[Response](*request, meta, response), nil
 }
 
-func (usecase *UsecaseImpl) FindById(ctx context.Context, id int) (*Response, error) {
+func (usecase *UsecaseImpl) FindById(ctx context.Context, id int, view *projection.Request) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "user.Usecase.FindById")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
-	user, err := usecase.UserRepository.FindByID(tx, id)
+	validation := usecase.Validator.ValidateStruct(view)
+	validation = append(validation, view.Parse(ProjectableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	user, err := usecase.UserRepository.FindByID(tx, id, *view)
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to find user with id: %d", id)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find user with id: %d", id)
 		return nil, errors.New("user not found")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return nil, errors.New("something went wrong")
 	}
 	return ToResponse(&user), nil
 }
 
-func (usecase *UsecaseImpl) FindByEmail(ctx context.Context, email string) (*Response, error) {
+func (usecase *UsecaseImpl) FindByEmail(ctx context.Context, email string, view *projection.Request) (*Response, error) {
+	ctx, span := tracer.Start(ctx, "user.Usecase.FindByEmail")
+	defer span.End()
+
 	tx := usecase.DB.WithContext(ctx).Begin()
 	defer tx.Rollback()
 
-	user, err := usecase.UserRepository.FindByEmail(tx, email)
+	validation := usecase.Validator.ValidateStruct(view)
+	validation = append(validation, view.Parse(ProjectableFields)...)
+	if validation != nil {
+		log.Ctx(ctx).Error().Msgf("validation error: %s", validation)
+		return nil, ivalidator.ValidationErrors{
+			Errors: validation,
+		}
+	}
+
+	user, err := usecase.UserRepository.FindByEmail(tx, email, *view)
 	if err != nil {
-		log.Error().Err(err).Msgf("failed to find user with email: %s", email)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to find user with email: %s", email)
 		return nil, errors.New("user not found")
 	}
 
 	if err = tx.Commit().Error; err != nil {
-		log.Error().Err(err).Msgf("failed to commit transaction: %+v", err)
+		log.Ctx(ctx).Error().Err(err).Msgf("failed to commit transaction: %+v", err)
 		return nil, errors.New("something went wrong")
 	}
 	return ToResponse(&user), nil
@@ -76,3 +76,14 @@ func ParseUintSlice(s string) []uint {
 	}
 	return result
 }
+
+func ParseStringSlice(s string) []string {
+	var result []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package helper
+
+import (
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var apostrophes = strings.NewReplacer("’", "'", "‘", "'", "ʼ", "'")
+
+// StripAccents drops the combining marks off s, "Société" becomes "Societe".
+func StripAccents(s string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	if err != nil {
+		return s
+	}
+	return stripped
+}
+
+// Fold is the search key of s: Unicode case folded, without accents, with
+// plain apostrophes and single spaces. "O’Reilly" and "o'reilly" share it.
+func Fold(s string) string {
+	s = cases.Fold().String(StripAccents(s))
+	return strings.Join(strings.Fields(apostrophes.Replace(s)), " ")
+}
+
+// minorWords stay lowercase inside a title.
+var minorWords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "but": true, "or": true,
+	"nor": true, "for": true, "so": true, "yet": true, "as": true, "at": true,
+	"by": true, "in": true, "of": true, "off": true, "on": true, "per": true,
+	"to": true, "up": true, "via": true, "vs": true,
+}
+
+var romanNumeral = regexp.MustCompile(`^(X{0,3})(IX|IV|V?I{0,3})$`)
+
+// TitleCase rewrites s in title case when it is written in capitals only,
+// which is how names and titles used to be stored: "THE C PROGRAMMING
+// LANGUAGE" becomes "The C Programming Language". With title set, minor
+// words stay lowercase unless they open the title or follow a colon. Roman
+// numerals stay in capitals and Mc and O’ prefixes are honoured. Anything
+// holding a lowercase letter was cased on purpose and is returned as is.
+func TitleCase(s string, title bool) string {
+	if strings.ToUpper(s) != s || strings.ToLower(s) == s {
+		return s
+	}
+
+	words := strings.Split(s, " ")
+	capitalize := true
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		switch {
+		case word == "":
+			continue
+		case title && !capitalize && i < len(words)-1 && minorWords[lower]:
+			words[i] = lower
+		case len(word) > 1 && romanNumeral.MatchString(strings.Trim(word, ".,:;!?()")):
+		default:
+			parts := strings.Split(lower, "-")
+			for j, part := range parts {
+				parts[j] = capitalizeWord(part)
+			}
+			words[i] = strings.Join(parts, "-")
+		}
+		capitalize = strings.HasSuffix(word, ":")
+	}
+	return strings.Join(words, " ")
+}
+
+// capitalizeWord uppercases the first letter of a lowercase word, and the
+// one after an O’ or Mc prefix.
+func capitalizeWord(word string) string {
+	letters := []rune(word)
+	first := -1
+	for i, r := range letters {
+		if unicode.IsLetter(r) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return word
+	}
+	letters[first] = unicode.ToTitle(letters[first])
+
+	rest := letters[first+1:]
+	switch {
+	// O’Reilly and D'Artagnan, but not I'm or I'll.
+	case len(rest) > 3 && (rest[0] == '\'' || rest[0] == '’') && unicode.IsLetter(rest[1]):
+		rest[1] = unicode.ToTitle(rest[1])
+	case len(rest) > 2 && letters[first] == 'M' && rest[0] == 'c':
+		rest[1] = unicode.ToTitle(rest[1])
+	}
+	return string(letters)
+}
@@ -0,0 +1,50 @@
+package helper
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"O’Reilly Media", "o'reilly media"},
+		{"o'reilly   media", "o'reilly media"},
+		{"  Société Générale ", "societe generale"},
+		{"Straße", "strasse"},
+		{"ÉMILE ZOLA", "emile zola"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := Fold(test.s); got != test.want {
+			t.Errorf("Fold(%q) = %q, want %q", test.s, got, test.want)
+		}
+	}
+}
+
+func TestTitleCase(t *testing.T) {
+	tests := []struct {
+		s     string
+		title bool
+		want  string
+	}{
+		{"THE C PROGRAMMING LANGUAGE", true, "The C Programming Language"},
+		{"OF MICE AND MEN", true, "Of Mice and Men"},
+		{"WORLD WAR II: THE ART OF WAR", true, "World War II: The Art of War"},
+		{"WHAT IT IS FOR", true, "What It Is For"},
+		{"OF MICE AND MEN", false, "Of Mice And Men"},
+		{"JOHN MCCARTHY", false, "John McCarthy"},
+		{"O’REILLY MEDIA", false, "O’Reilly Media"},
+		{"I'M OK", false, "I'm Ok"},
+		{"JEAN-PAUL SARTRE", false, "Jean-Paul Sartre"},
+		{"Addison-Wesley", false, "Addison-Wesley"},
+		{"mcdonald", false, "mcdonald"},
+		{"1984", true, "1984"},
+	}
+
+	for _, test := range tests {
+		if got := TitleCase(test.s, test.title); got != test.want {
+			t.Errorf("TitleCase(%q, %v) = %q, want %q", test.s, test.title, got, test.want)
+		}
+	}
+}
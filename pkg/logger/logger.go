@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"fmt"
+	"github.com/rs/zerolog"
+	"io"
+	"os"
+	"time"
+)
+
+// New builds a logger writing to stderr. format is "json" or "console", level
+// any zerolog level name such as "debug" or "info".
+func New(level string, format string) (zerolog.Logger, error) {
+	parsed, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return zerolog.Logger{}, err
+	}
+
+	var writer io.Writer
+	switch format {
+	case "json":
+		writer = os.Stderr
+	case "", "console":
+		writer = zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}
+	default:
+		return zerolog.Logger{}, fmt.Errorf("unknown log format %q", format)
+	}
+
+	return zerolog.New(writer).
+		Level(parsed).
+		With().
+		Timestamp().
+		Caller().
+		Logger(), nil
+}